@@ -0,0 +1,50 @@
+// Command keygen writes a new Ed25519 JWT signing key to the key directory.
+//
+//	go run ./cmd/keygen -dir keys -kid 2025-06
+//
+// Point JWT_KEYS_DIR at the directory and set JWT_ACTIVE_KEY_ID to the new
+// key ID to start signing with it. Keep the previous key in the directory
+// until the tokens it signed have expired.
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func main() {
+	dir := flag.String("dir", "keys", "directory to write the key to")
+	kid := flag.String("kid", time.Now().UTC().Format("2006-01-02"), "key ID, used as the file name")
+	flag.Parse()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatal("Failed to generate key:", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		log.Fatal("Failed to encode key:", err)
+	}
+
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		log.Fatal("Failed to create key directory:", err)
+	}
+	path := filepath.Join(*dir, *kid+".pem")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		log.Fatal("Failed to create key file:", err)
+	}
+	defer file.Close()
+
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		log.Fatal("Failed to write key:", err)
+	}
+	log.Printf("Wrote signing key %s to %s", *kid, path)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/scheduler"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+)
+
+// newScheduler registers the maintenance jobs. Schedules are in UTC. Every
+// server registers the same jobs; the coordinator picks which one runs each.
+func newScheduler(cfg *config.Config, coordinator scheduler.Coordinator) (*scheduler.Scheduler, error) {
+	authService := services.NewAuthService(cfg)
+	accountService := services.NewAccountService(cfg)
+	deviceAuthService := services.NewDeviceAuthService(cfg)
+	tokenService := services.NewPersonalAccessTokenService()
+	oidcService := services.NewOIDCService(cfg)
+	deletionService := services.NewAccountDeletionService(cfg)
+	trashService := services.NewNoteTrashService(cfg)
+	notesService := services.NewNotesService()
+	summarizer := services.NewSummarizerService()
+
+	jobs := scheduler.New(coordinator)
+	for _, job := range []scheduler.Job{
+		{
+			Name:     "cleanup-refresh-tokens",
+			Schedule: "15 * * * *",
+			Jitter:   time.Minute,
+			Run:      authService.CleanupExpiredRefreshTokens,
+		},
+		{
+			Name:     "cleanup-action-tokens",
+			Schedule: "20 * * * *",
+			Jitter:   time.Minute,
+			Run:      accountService.CleanupExpiredActionTokens,
+		},
+		{
+			Name:     "cleanup-device-codes",
+			Schedule: "*/10 * * * *",
+			Jitter:   30 * time.Second,
+			Timeout:  time.Minute,
+			Run:      deviceAuthService.CleanupExpiredDeviceCodes,
+		},
+		{
+			Name:     "cleanup-oidc-states",
+			Schedule: "*/10 * * * *",
+			Jitter:   30 * time.Second,
+			Timeout:  time.Minute,
+			Run:      oidcService.CleanupExpiredOIDCStates,
+		},
+		{
+			Name:     "cleanup-personal-access-tokens",
+			Schedule: "30 3 * * *",
+			Jitter:   5 * time.Minute,
+			Run:      tokenService.CleanupExpiredPersonalAccessTokens,
+		},
+		{
+			Name:     "purge-deleted-accounts",
+			Schedule: "0 * * * *",
+			Jitter:   time.Minute,
+			Timeout:  30 * time.Minute,
+			Run: func(ctx context.Context) error {
+				purged, err := deletionService.PurgeDueAccounts(ctx)
+				if purged > 0 {
+					log.Printf("Purged %d deleted accounts", purged)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "purge-trashed-notes",
+			Schedule: "45 2 * * *",
+			Jitter:   5 * time.Minute,
+			Timeout:  30 * time.Minute,
+			Run: func(ctx context.Context) error {
+				purged, err := trashService.PurgeExpired(ctx)
+				if purged > 0 {
+					log.Printf("Purged %d notes from the trash", purged)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "summarize-queued-notes",
+			Schedule: "*/5 * * * *",
+			Jitter:   30 * time.Second,
+			Timeout:  4 * time.Minute,
+			Run: func(ctx context.Context) error {
+				summarized, err := notesService.SummarizeQueued(ctx, summarizer)
+				if summarized > 0 {
+					log.Printf("Summarized %d queued notes", summarized)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "fingerprint-notes",
+			Schedule: "40 * * * *",
+			Jitter:   time.Minute,
+			Timeout:  30 * time.Minute,
+			Run: func(ctx context.Context) error {
+				fingerprinted, err := notesService.FingerprintMissing(ctx)
+				if fingerprinted > 0 {
+					log.Printf("Fingerprinted %d notes", fingerprinted)
+				}
+				return err
+			},
+		},
+	} {
+		if err := jobs.Add(job); err != nil {
+			return nil, err
+		}
+	}
+	return jobs, nil
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -13,6 +15,10 @@ import (
 	"github.com/pratts/tts-study-assistant/backend/internal/database"
 	"github.com/pratts/tts-study-assistant/backend/internal/handlers"
 	"github.com/pratts/tts-study-assistant/backend/internal/middleware"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"github.com/pratts/tts-study-assistant/backend/internal/scheduler"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+	"github.com/pratts/tts-study-assistant/backend/internal/signing"
 )
 
 func main() {
@@ -24,6 +30,34 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// Give the admin role to the users in ADMIN_EMAILS
+	if err := services.NewAdminService(cfg).PromoteConfiguredAdmins(); err != nil {
+		log.Printf("Failed to promote admins: %v", err)
+	}
+
+	// Load JWT signing keys
+	if err := signing.Load(cfg); err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+
+	// Background jobs, claimed through Postgres so that each run happens on
+	// only one server
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		log.Fatal("Failed to get database handle:", err)
+	}
+	coordinator, err := scheduler.NewPostgresCoordinator(sqlDB)
+	if err != nil {
+		log.Fatal("Failed to set up job scheduler:", err)
+	}
+	jobs, err := newScheduler(cfg, coordinator)
+	if err != nil {
+		log.Fatal("Failed to register background jobs:", err)
+	}
+	if cfg.SchedulerEnabled {
+		jobs.Start(context.Background())
+	}
+
 	// Create fiber app
 	app := fiber.New(fiber.Config{
 		ErrorHandler: customErrorHandler,
@@ -40,7 +74,7 @@ func main() {
 	}))
 
 	// Routes
-	setupRoutes(app, cfg)
+	setupRoutes(app, cfg, jobs)
 
 	// Start server
 	log.Printf("Server starting on port %s", cfg.Port)
@@ -49,7 +83,7 @@ func main() {
 	}
 }
 
-func setupRoutes(app *fiber.App, cfg *config.Config) {
+func setupRoutes(app *fiber.App, cfg *config.Config, jobs *scheduler.Scheduler) {
 	// Health check
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -59,9 +93,23 @@ func setupRoutes(app *fiber.App, cfg *config.Config) {
 	})
 
 	// Initialize handlers
+	jwksHandler := handlers.NewJWKSHandler()
 	authHandler := handlers.NewAuthHandler(cfg)
+	deviceAuthHandler := handlers.NewDeviceAuthHandler(cfg)
+	accountHandler := handlers.NewAccountHandler(cfg)
+	oidcHandler := handlers.NewOIDCHandler(cfg)
 	notesHandler := handlers.NewNotesHandler()
-	userHandler := handlers.NewUserHandler()
+	tagHandler := handlers.NewTagHandler()
+	userHandler := handlers.NewUserHandler(cfg)
+	sessionHandler := handlers.NewSessionHandler()
+	twoFactorHandler := handlers.NewTwoFactorHandler(cfg)
+	tokenHandler := handlers.NewPersonalAccessTokenHandler()
+	auditHandler := handlers.NewAuditHandler()
+	adminHandler := handlers.NewAdminHandler(cfg)
+	jobsHandler := handlers.NewJobsHandler(jobs)
+
+	// Public keys for verifying access tokens
+	app.Get("/.well-known/jwks.json", jwksHandler.GetJWKS)
 
 	// API routes
 	api := app.Group("/api/v1")
@@ -70,27 +118,121 @@ func setupRoutes(app *fiber.App, cfg *config.Config) {
 	auth := api.Group("/auth")
 	auth.Post("/register", authHandler.Register)
 	auth.Post("/login", authHandler.Login)
+	auth.Post("/login/2fa", middleware.RateLimitByIP(10, time.Minute), authHandler.LoginTwoFactor)
 	auth.Post("/refresh", authHandler.Refresh)
 	auth.Post("/logout", authHandler.Logout)
+	auth.Get("/verify", authHandler.Verify)
+
+	// Emailed account links (public)
+	auth.Post("/verify-email", middleware.RateLimitByIP(20, time.Minute), accountHandler.VerifyEmail)
+	auth.Post("/verify-email/resend", middleware.RateLimitByIP(5, time.Minute), accountHandler.ResendVerification)
+	auth.Post("/forgot-password", middleware.RateLimitByIP(5, time.Minute), accountHandler.ForgotPassword)
+	auth.Post("/reset-password", middleware.RateLimitByIP(20, time.Minute), accountHandler.ResetPassword)
+	auth.Post("/confirm-email-change", middleware.RateLimitByIP(20, time.Minute), accountHandler.ConfirmEmailChange)
+
+	// Single sign-on with the OpenID Connect provider (public)
+	auth.Get("/oidc", oidcHandler.GetInfo)
+	auth.Post("/oidc/authorize", middleware.RateLimitByIP(20, time.Minute), oidcHandler.Authorize)
+	auth.Post("/oidc/callback", middleware.RateLimitByIP(20, time.Minute), oidcHandler.Callback)
+
+	// Device authorization for the extension (public)
+	auth.Post("/device/code", middleware.RateLimitByIP(10, time.Minute), deviceAuthHandler.StartAuthorization)
+	auth.Post("/device/token", middleware.RateLimitByIP(60, time.Minute), deviceAuthHandler.PollToken)
 
 	// Protected routes
 	protected := api.Group("", middleware.AuthMiddleware(cfg))
 
-	// Notes routes (protected)
+	// Extension token sync from a web session (protected)
+	protected.Post("/auth/extension-sync", middleware.RequireSession(), authHandler.ExtensionSync)
+
+	// Device approval from the web app (protected)
+	device := protected.Group("/device", middleware.RequireSession(), middleware.RateLimitByUser(20, time.Minute))
+	device.Get("/", deviceAuthHandler.GetPendingDevice)
+	device.Post("/approve", deviceAuthHandler.ApproveDevice)
+
+	// Notes routes (protected, also open to personal access tokens)
+	notesRead := middleware.RequireScope(models.ScopeNotesRead)
+	notesWrite := middleware.RequireScope(models.ScopeNotesWrite)
 	notes := protected.Group("/notes")
-	notes.Get("/", notesHandler.GetNotes)
-	notes.Post("/", notesHandler.CreateNote)
-	notes.Get("/stats", notesHandler.GetNotesStats)
-	notes.Get("/:id", notesHandler.GetNote)
-	notes.Put("/:id", notesHandler.UpdateNote)
-	notes.Delete("/:id", notesHandler.DeleteNote)
-	notes.Post("/:id/summarize", notesHandler.SummarizeNote)
+	notes.Get("/", notesRead, notesHandler.GetNotes)
+	notes.Post("/", notesWrite, notesHandler.CreateNote)
+	notes.Get("/stats", notesRead, notesHandler.GetNotesStats)
+	notes.Get("/search", notesRead, notesHandler.SearchNotes)
+	notes.Post("/bulk", notesWrite, notesHandler.BulkNotes)
+	notes.Get("/duplicates", notesRead, notesHandler.GetDuplicates)
+	notes.Post("/merge", notesWrite, notesHandler.MergeNotes)
+
+	trashHandler := handlers.NewTrashHandler(cfg)
+	notes.Get("/trash", notesRead, trashHandler.GetTrash)
+	notes.Delete("/trash", notesWrite, trashHandler.EmptyTrash)
+	notes.Post("/trash/:id/restore", notesWrite, trashHandler.RestoreNote)
+	notes.Delete("/trash/:id", notesWrite, trashHandler.DeleteNote)
+
+	notes.Get("/:id", notesRead, notesHandler.GetNote)
+	notes.Put("/:id", notesWrite, notesHandler.UpdateNote)
+	notes.Delete("/:id", notesWrite, notesHandler.DeleteNote)
+	notes.Post("/:id/summarize", middleware.RequireScope(models.ScopeSummariesWrite), notesHandler.SummarizeNote)
+	notes.Get("/:id/revisions", notesRead, notesHandler.GetRevisions)
+	notes.Get("/:id/revisions/diff", notesRead, notesHandler.DiffRevisions)
+	notes.Get("/:id/revisions/:number", notesRead, notesHandler.GetRevision)
+	notes.Post("/:id/revisions/:number/revert", notesWrite, notesHandler.RevertNote)
+
+	// Tag routes (protected, also open to personal access tokens)
+	tags := protected.Group("/tags")
+	tags.Get("/", notesRead, tagHandler.GetTags)
+	tags.Post("/", notesWrite, tagHandler.CreateTag)
+	tags.Post("/merge", notesWrite, tagHandler.MergeTags)
+	tags.Put("/:id", notesWrite, tagHandler.UpdateTag)
+	tags.Delete("/:id", notesWrite, tagHandler.DeleteTag)
+
+	collectionHandler := handlers.NewCollectionHandler()
+	collections := protected.Group("/collections")
+	collections.Get("/", notesRead, collectionHandler.GetCollections)
+	collections.Post("/", notesWrite, collectionHandler.CreateCollection)
+	collections.Get("/:id", notesRead, collectionHandler.GetCollection)
+	collections.Put("/:id", notesWrite, collectionHandler.UpdateCollection)
+	collections.Delete("/:id", notesWrite, collectionHandler.DeleteCollection)
+	collections.Post("/:id/move", notesWrite, collectionHandler.MoveCollection)
+	collections.Post("/:id/notes", notesWrite, collectionHandler.AddNotes)
+	collections.Put("/:id/notes/order", notesWrite, collectionHandler.ReorderNotes)
+	collections.Delete("/:id/notes/:noteId", notesWrite, collectionHandler.RemoveNote)
 
 	// User routes (protected)
-	user := protected.Group("/user")
+	user := protected.Group("/user", middleware.RequireSession())
 	user.Get("/profile", userHandler.GetProfile)
 	user.Put("/profile", userHandler.UpdateProfile)
 	user.Put("/password", userHandler.UpdatePassword)
+	user.Delete("/", middleware.RateLimitByUser(5, time.Minute), userHandler.DeleteAccount)
+	user.Get("/sessions", sessionHandler.GetSessions)
+	user.Delete("/sessions", sessionHandler.RevokeSessions)
+	user.Put("/sessions/:id", sessionHandler.UpdateSession)
+	user.Delete("/sessions/:id", sessionHandler.RevokeSession)
+	user.Get("/tokens", tokenHandler.GetTokens)
+	user.Post("/tokens", tokenHandler.CreateToken)
+	user.Delete("/tokens/:id", tokenHandler.RevokeToken)
+	user.Get("/security-events", auditHandler.GetSecurityEvents)
+
+	// Two-factor authentication (protected)
+	twoFactor := user.Group("/2fa", middleware.RateLimitByUser(10, time.Minute))
+	twoFactor.Get("/", twoFactorHandler.GetStatus)
+	twoFactor.Post("/setup", twoFactorHandler.Setup)
+	twoFactor.Post("/confirm", twoFactorHandler.Confirm)
+	twoFactor.Post("/disable", twoFactorHandler.Disable)
+	twoFactor.Post("/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+
+	// Admin routes (protected). Support staff can read, only admins can
+	// change anything.
+	adminOnly := middleware.RequireRole(models.RoleAdmin)
+	admin := protected.Group("/admin", middleware.RequireSession(), middleware.RequireRole(models.RoleAdmin, models.RoleSupport))
+	admin.Get("/stats", adminHandler.GetStats)
+	admin.Get("/users", adminHandler.GetUsers)
+	admin.Get("/users/:id", adminHandler.GetUser)
+	admin.Put("/users/:id/role", adminOnly, adminHandler.UpdateRole)
+	admin.Post("/users/:id/disable", adminOnly, adminHandler.DisableUser)
+	admin.Post("/users/:id/enable", adminOnly, adminHandler.EnableUser)
+	admin.Post("/users/:id/logout", adminOnly, adminHandler.ForceLogout)
+	admin.Get("/audit-events", auditHandler.GetAuditEvents)
+	admin.Get("/jobs", jobsHandler.GetJobs)
 }
 
 func customErrorHandler(c *fiber.Ctx, err error) error {
@@ -2,10 +2,16 @@ package main
 
 import (
 	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/middleware"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"github.com/pratts/tts-study-assistant/backend/internal/scheduler"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,19 +22,31 @@ func TestSetupRoutes(t *testing.T) {
 		CORSOrigins: []string{"http://localhost:3000"},
 	}
 
-	setupRoutes(app, cfg)
+	setupRoutes(app, cfg, scheduler.New(scheduler.NewLocalCoordinator()))
 
 	// Test that health endpoint exists
 	req := httptest.NewRequest("GET", "/health", nil)
 	resp, _ := app.Test(req)
 	assert.Equal(t, 200, resp.StatusCode)
 
+	// Test that the JWKS endpoint exists
+	req = httptest.NewRequest("GET", "/.well-known/jwks.json", nil)
+	resp, _ = app.Test(req)
+	assert.Equal(t, 200, resp.StatusCode)
+
 	// Test that auth routes exist
 	authRoutes := []string{
 		"/api/v1/auth/register",
 		"/api/v1/auth/login",
+		"/api/v1/auth/login/2fa",
+		"/api/v1/auth/oidc/callback",
 		"/api/v1/auth/refresh",
 		"/api/v1/auth/logout",
+		"/api/v1/auth/device/token",
+		"/api/v1/auth/verify-email",
+		"/api/v1/auth/forgot-password",
+		"/api/v1/auth/reset-password",
+		"/api/v1/auth/confirm-email-change",
 	}
 
 	for _, route := range authRoutes {
@@ -38,3 +56,192 @@ func TestSetupRoutes(t *testing.T) {
 		assert.NotEqual(t, 404, resp.StatusCode)
 	}
 }
+
+func TestProtectedRoutesRequireAuth(t *testing.T) {
+	app := fiber.New()
+	cfg := &config.Config{
+		JWTSecret:   "test-secret",
+		CORSOrigins: []string{"http://localhost:3000"},
+	}
+
+	setupRoutes(app, cfg, scheduler.New(scheduler.NewLocalCoordinator()))
+
+	protectedRoutes := []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/api/v1/notes/search"},
+		{"POST", "/api/v1/notes/bulk"},
+		{"GET", "/api/v1/notes/duplicates"},
+		{"POST", "/api/v1/notes/merge"},
+		{"GET", "/api/v1/notes/trash"},
+		{"DELETE", "/api/v1/notes/trash"},
+		{"POST", "/api/v1/notes/trash/123/restore"},
+		{"DELETE", "/api/v1/notes/trash/123"},
+		{"GET", "/api/v1/notes/123/revisions"},
+		{"GET", "/api/v1/notes/123/revisions/diff"},
+		{"GET", "/api/v1/notes/123/revisions/2"},
+		{"POST", "/api/v1/notes/123/revisions/2/revert"},
+		{"GET", "/api/v1/tags"},
+		{"POST", "/api/v1/tags/merge"},
+		{"PUT", "/api/v1/tags/123"},
+		{"GET", "/api/v1/collections"},
+		{"POST", "/api/v1/collections/123/move"},
+		{"PUT", "/api/v1/collections/123/notes/order"},
+		{"DELETE", "/api/v1/collections/123/notes/456"},
+		{"GET", "/api/v1/user/sessions"},
+		{"DELETE", "/api/v1/user/sessions"},
+		{"PUT", "/api/v1/user/sessions/123"},
+		{"DELETE", "/api/v1/user/sessions/123"},
+		{"POST", "/api/v1/auth/extension-sync"},
+		{"GET", "/api/v1/device"},
+		{"POST", "/api/v1/device/approve"},
+		{"GET", "/api/v1/user/2fa"},
+		{"GET", "/api/v1/user/tokens"},
+		{"POST", "/api/v1/user/tokens"},
+		{"DELETE", "/api/v1/user/tokens/123"},
+		{"POST", "/api/v1/user/2fa/setup"},
+		{"POST", "/api/v1/user/2fa/confirm"},
+		{"POST", "/api/v1/user/2fa/disable"},
+		{"POST", "/api/v1/user/2fa/recovery-codes"},
+		{"GET", "/api/v1/user/security-events"},
+		{"DELETE", "/api/v1/user"},
+		{"GET", "/api/v1/admin/audit-events"},
+		{"GET", "/api/v1/admin/stats"},
+		{"GET", "/api/v1/admin/users"},
+		{"PUT", "/api/v1/admin/users/123/role"},
+		{"POST", "/api/v1/admin/users/123/disable"},
+		{"POST", "/api/v1/admin/users/123/logout"},
+		{"GET", "/api/v1/admin/jobs"},
+	}
+
+	for _, route := range protectedRoutes {
+		req := httptest.NewRequest(route.method, route.path, nil)
+		resp, _ := app.Test(req)
+		assert.Equal(t, 401, resp.StatusCode, route.method+" "+route.path)
+	}
+}
+
+// Personal access tokens pass AuthMiddleware on every protected route, so
+// each of them must check the token's scopes or turn tokens away
+func TestProtectedRoutesCheckTokenScopes(t *testing.T) {
+	app := fiber.New()
+	cfg := &config.Config{
+		JWTSecret:   "test-secret",
+		CORSOrigins: []string{"http://localhost:3000"},
+	}
+
+	setupRoutes(app, cfg, scheduler.New(scheduler.NewLocalCoordinator()))
+
+	// Group middleware is registered as prefix routes, which run before the
+	// routes registered after them
+	guarded := func(names []string, guard string) bool {
+		for _, name := range names {
+			if strings.Contains(name, guard) {
+				return true
+			}
+		}
+		return false
+	}
+	checked := 0
+	var middleware []fiber.Route
+	for _, route := range app.GetRoutes() {
+		if isPrefixRoute(route) {
+			middleware = append(middleware, route)
+			continue
+		}
+		var names []string
+		for _, use := range middleware {
+			if use.Method == route.Method &&
+				(use.Path == "/" || route.Path == use.Path || strings.HasPrefix(route.Path, use.Path+"/")) {
+				names = append(names, handlerNames(use.Handlers)...)
+			}
+		}
+		names = append(names, handlerNames(route.Handlers)...)
+
+		if !guarded(names, "middleware.AuthMiddleware") {
+			continue
+		}
+		checked++
+		if !guarded(names, "middleware.RequireScope") && !guarded(names, "middleware.RequireSession") {
+			t.Errorf("%s %s needs RequireScope or RequireSession", route.Method, route.Path)
+		}
+	}
+	assert.NotZero(t, checked)
+}
+
+// isPrefixRoute reports whether a route was registered with Use
+func isPrefixRoute(route fiber.Route) bool {
+	return reflect.ValueOf(route).FieldByName("use").Bool()
+}
+
+func handlerNames(handlers []fiber.Handler) []string {
+	names := make([]string, len(handlers))
+	for i, handler := range handlers {
+		names[i] = runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	}
+	return names
+}
+
+func TestPersonalAccessTokenScopes(t *testing.T) {
+	app := fiber.New()
+	// Stand in for AuthMiddleware: a personal access token with one scope
+	app.Use(func(c *fiber.Ctx) error {
+		if c.Get("X-Test-Token") == "pat" {
+			c.Locals("scopes", []string{models.ScopeNotesRead})
+		}
+		return c.Next()
+	})
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(200) }
+	app.Get("/notes", middleware.RequireScope(models.ScopeNotesRead), ok)
+	app.Post("/notes", middleware.RequireScope(models.ScopeNotesWrite), ok)
+	app.Get("/user/profile", middleware.RequireSession(), ok)
+
+	tests := []struct {
+		method, path, token string
+		want                int
+	}{
+		{"GET", "/notes", "pat", 200},
+		{"POST", "/notes", "pat", 403},
+		{"GET", "/user/profile", "pat", 403},
+		{"POST", "/notes", "", 200},
+		{"GET", "/user/profile", "", 200},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("X-Test-Token", tt.token)
+		resp, _ := app.Test(req)
+		assert.Equal(t, tt.want, resp.StatusCode, tt.method+" "+tt.path+" "+tt.token)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	app := fiber.New()
+	// Stand in for AuthMiddleware
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("role", c.Get("X-Test-Role"))
+		return c.Next()
+	})
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(200) }
+	admin := app.Group("/admin", middleware.RequireRole(models.RoleAdmin, models.RoleSupport))
+	admin.Get("/users", ok)
+	admin.Post("/users/1/disable", middleware.RequireRole(models.RoleAdmin), ok)
+
+	tests := []struct {
+		method, path, role string
+		want               int
+	}{
+		{"GET", "/admin/users", models.RoleAdmin, 200},
+		{"GET", "/admin/users", models.RoleSupport, 200},
+		{"GET", "/admin/users", models.RoleUser, 403},
+		{"GET", "/admin/users", "", 403},
+		{"POST", "/admin/users/1/disable", models.RoleAdmin, 200},
+		{"POST", "/admin/users/1/disable", models.RoleSupport, 403},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("X-Test-Role", tt.role)
+		resp, _ := app.Test(req)
+		assert.Equal(t, tt.want, resp.StatusCode, tt.method+" "+tt.path+" "+tt.role)
+	}
+}
@@ -3,7 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,8 +14,51 @@ type Config struct {
 	DatabaseURL      string
 	JWTSecret        string
 	JWTRefreshSecret string
+	JWTKeysDir       string
+	JWTActiveKeyID   string
+	JWTIssuer        string
 	Port             string
 	CORSOrigins      []string
+	AppURL           string
+
+	// Email
+	MailDriver               string
+	MailFrom                 string
+	MailOutboxDir            string
+	SMTPHost                 string
+	SMTPPort                 string
+	SMTPUsername             string
+	SMTPPassword             string
+	ActionTokenSecret        string
+	RequireEmailVerification bool
+
+	// Two-factor authentication
+	TOTPEncryptionKey        string
+	TwoFactorChallengeSecret string
+
+	// Single sign-on with an OpenID Connect provider. Disabled when
+	// OIDCIssuerURL is empty.
+	OIDCIssuerURL     string
+	OIDCClientID      string
+	OIDCClientSecret  string
+	OIDCRedirectURL   string
+	OIDCScopes        []string
+	OIDCProviderName  string
+	OIDCAutoProvision bool
+
+	// Users with these verified email addresses are given the admin role at
+	// startup. Access is granted by the role, not by the list.
+	AdminEmails []string
+
+	// How long a deleted account can still be restored by logging in
+	AccountDeletionGracePeriod time.Duration
+
+	// How long deleted notes stay in the trash before they are purged
+	NoteTrashRetention time.Duration
+
+	// Whether this server runs background jobs. Several servers can run
+	// them safely; turning it off keeps a server to serving requests.
+	SchedulerEnabled bool
 }
 
 func Load() *Config {
@@ -22,12 +67,46 @@ func Load() *Config {
 		log.Println("No .env file found")
 	}
 
+	appURL := strings.TrimSuffix(getEnv("APP_URL", "http://localhost:5173"), "/")
+
 	return &Config{
 		DatabaseURL:      getEnv("DATABASE_URL", ""),
 		JWTSecret:        getEnv("JWT_SECRET", ""),
 		JWTRefreshSecret: getEnv("JWT_REFRESH_SECRET", ""),
+		JWTKeysDir:       getEnv("JWT_KEYS_DIR", ""),
+		JWTActiveKeyID:   getEnv("JWT_ACTIVE_KEY_ID", ""),
+		JWTIssuer:        getEnv("JWT_ISSUER", "tts-study-assistant"),
 		Port:             getEnv("PORT", "3000"),
 		CORSOrigins:      strings.Split(getEnv("CORS_ORIGINS", "http://localhost:3000"), ","),
+		AppURL:           appURL,
+
+		MailDriver:               getEnv("MAIL_DRIVER", "file"),
+		MailFrom:                 getEnv("MAIL_FROM", "TTS Study Assistant <no-reply@localhost>"),
+		MailOutboxDir:            getEnv("MAIL_OUTBOX_DIR", "outbox"),
+		SMTPHost:                 getEnv("SMTP_HOST", ""),
+		SMTPPort:                 getEnv("SMTP_PORT", "587"),
+		SMTPUsername:             getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:             getEnv("SMTP_PASSWORD", ""),
+		ActionTokenSecret:        getEnv("ACTION_TOKEN_SECRET", getEnv("JWT_REFRESH_SECRET", "")),
+		RequireEmailVerification: getEnvBool("REQUIRE_EMAIL_VERIFICATION", false),
+
+		TOTPEncryptionKey:        getEnv("TOTP_ENCRYPTION_KEY", getEnv("JWT_REFRESH_SECRET", "")),
+		TwoFactorChallengeSecret: getEnv("TWO_FACTOR_CHALLENGE_SECRET", getEnv("JWT_REFRESH_SECRET", "")),
+
+		OIDCIssuerURL:     getEnv("OIDC_ISSUER_URL", ""),
+		OIDCClientID:      getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:  getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:   getEnv("OIDC_REDIRECT_URL", appURL+"/sso/callback"),
+		OIDCScopes:        strings.Fields(getEnv("OIDC_SCOPES", "openid email profile")),
+		OIDCProviderName:  getEnv("OIDC_PROVIDER_NAME", "SSO"),
+		OIDCAutoProvision: getEnvBool("OIDC_AUTO_PROVISION", true),
+
+		AdminEmails: getEnvList("ADMIN_EMAILS"),
+
+		AccountDeletionGracePeriod: time.Duration(getEnvInt("ACCOUNT_DELETION_GRACE_DAYS", 30)) * 24 * time.Hour,
+		NoteTrashRetention:         time.Duration(getEnvInt("NOTE_TRASH_RETENTION_DAYS", 30)) * 24 * time.Hour,
+
+		SchedulerEnabled: getEnvBool("SCHEDULER_ENABLED", true),
 	}
 }
 
@@ -37,3 +116,38 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList reads a comma-separated list, dropping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			log.Printf("Invalid integer for %s: %q, using default", key, value)
+			return defaultValue
+		}
+		return parsed
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Printf("Invalid boolean for %s: %q, using default", key, value)
+			return defaultValue
+		}
+		return parsed
+	}
+	return defaultValue
+}
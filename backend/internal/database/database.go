@@ -10,6 +10,28 @@ import (
 
 var DB *gorm.DB
 
+// Models lists every table the schema is migrated from. Account purges
+// must cover those with a user_id column.
+var Models = []any{
+	&models.User{},
+	&models.Note{},
+	&models.RefreshToken{},
+	&models.AuditEvent{},
+	&models.DeviceCode{},
+	&models.ActionToken{},
+	&models.RecoveryCode{},
+	&models.PersonalAccessToken{},
+	&models.UserIdentity{},
+	&models.OIDCLoginState{},
+	&models.DeletionReceipt{},
+	&models.Tag{},
+	&models.NoteTag{},
+	&models.Collection{},
+	&models.CollectionNote{},
+	&models.NoteRevision{},
+	&models.NoteFingerprintBand{},
+}
+
 func Connect(databaseURL string) error {
 	var err error
 
@@ -20,16 +42,105 @@ func Connect(databaseURL string) error {
 
 	log.Println("Database connected successfully")
 
+	if err := migrateRefreshTokenHashes(DB); err != nil {
+		return err
+	}
+
+	if err := DB.SetupJoinTable(&models.Note{}, "Tags", &models.NoteTag{}); err != nil {
+		return err
+	}
+	if err := DB.SetupJoinTable(&models.Note{}, "Collections", &models.CollectionNote{}); err != nil {
+		return err
+	}
+
 	// Auto migrate the schema
-	err = DB.AutoMigrate(
-		&models.User{},
-		&models.Note{},
-		&models.RefreshToken{},
-	)
+	err = DB.AutoMigrate(Models...)
 	if err != nil {
 		return err
 	}
 
+	// Refresh tokens issued before token families existed become their own family
+	if err := DB.Exec("UPDATE refresh_tokens SET family_id = id WHERE family_id IS NULL").Error; err != nil {
+		return err
+	}
+
+	if err := migrateNoteSearch(DB); err != nil {
+		return err
+	}
+
+	// Keyset pages of the notes list walk this index
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_notes_user_created ON notes (user_id, created_at, id)").Error; err != nil {
+		return err
+	}
+
+	// Metadata filters compile to containment (@>), which jsonb_path_ops serves
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_notes_metadata ON notes USING GIN (metadata jsonb_path_ops)").Error; err != nil {
+		return err
+	}
+
+	// Exact duplicates are found by content hash within a user's notes; the
+	// partial index finds notes still to be fingerprinted
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_notes_user_content_hash ON notes (user_id, content_hash)").Error; err != nil {
+		return err
+	}
+	if err := DB.Exec("DROP INDEX IF EXISTS idx_notes_unfingerprinted").Error; err != nil {
+		return err
+	}
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_notes_not_fingerprinted ON notes (id) WHERE fingerprinted_at IS NULL").Error; err != nil {
+		return err
+	}
+
+	// Tag names are unique per user regardless of case
+	if err := DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags (user_id, lower(name))").Error; err != nil {
+		return err
+	}
+
 	log.Println("Database migrated successfully")
 	return nil
 }
+
+// migrateNoteSearch adds the full-text search column to notes. Postgres
+// keeps the generated column up to date, so existing notes are indexed when
+// it is added and no write path has to maintain it. Titles rank above
+// summaries, which rank above the content.
+func migrateNoteSearch(db *gorm.DB) error {
+	statements := []string{
+		`ALTER TABLE notes ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('english', coalesce(source_title, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(summary, '')), 'B') ||
+			setweight(to_tsvector('english', coalesce(content, '')), 'C')
+		) STORED`,
+		"CREATE INDEX IF NOT EXISTS idx_notes_search_vector ON notes USING GIN (search_vector)",
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateRefreshTokenHashes replaces the raw refresh token column with the
+// token's SHA-256 digest. Clients keep the tokens they hold: a legacy token
+// is looked up by the digest of its raw value like any other.
+func migrateRefreshTokenHashes(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable("refresh_tokens") || !migrator.HasColumn(&models.RefreshToken{}, "token") {
+		return nil
+	}
+
+	log.Println("Hashing stored refresh tokens")
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			"ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS token_hash text",
+			"UPDATE refresh_tokens SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex') WHERE token_hash IS NULL",
+			"ALTER TABLE refresh_tokens DROP COLUMN token",
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+type AccountHandler struct {
+	accountService *services.AccountService
+}
+
+func NewAccountHandler(cfg *config.Config) *AccountHandler {
+	return &AccountHandler{
+		accountService: services.NewAccountService(cfg),
+	}
+}
+
+// VerifyEmail handles confirming an email address from the emailed link
+func (h *AccountHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req services.TokenRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Token == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Token is required")
+	}
+
+	if err := h.accountService.VerifyEmail(req.Token); err != nil {
+		if err.Error() == "invalid or expired token" {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired link", "INVALID_TOKEN")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to verify email")
+	}
+
+	return utils.SendSuccess(c, "Email verified successfully")
+}
+
+// ResendVerification handles sending a new verification link
+func (h *AccountHandler) ResendVerification(c *fiber.Ctx) error {
+	var req services.EmailRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Email == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Email is required")
+	}
+
+	if err := h.accountService.ResendVerificationEmail(req.Email); err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to send verification email")
+	}
+
+	return utils.SendSuccess(c, "If the account exists and is unverified, a verification email has been sent")
+}
+
+// ForgotPassword handles requesting a password reset link
+func (h *AccountHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req services.EmailRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Email == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Email is required")
+	}
+
+	if err := h.accountService.ForgotPassword(req.Email); err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to send password reset email")
+	}
+
+	return utils.SendSuccess(c, "If the account exists, a password reset email has been sent")
+}
+
+// ResetPassword handles setting a new password from the emailed link
+func (h *AccountHandler) ResetPassword(c *fiber.Ctx) error {
+	var req services.ResetPasswordRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Token == "" || req.Password == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Token and password are required")
+	}
+
+	if err := h.accountService.ResetPassword(&req, clientInfo(c)); err != nil {
+		if err.Error() == "invalid or expired token" {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired link", "INVALID_TOKEN")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to reset password")
+	}
+
+	return utils.SendSuccess(c, "Password reset successfully")
+}
+
+// ConfirmEmailChange handles confirming a new email address
+func (h *AccountHandler) ConfirmEmailChange(c *fiber.Ctx) error {
+	var req services.TokenRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Token == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Token is required")
+	}
+
+	if err := h.accountService.ConfirmEmailChange(req.Token, clientInfo(c)); err != nil {
+		if err.Error() == "invalid or expired token" {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired link", "INVALID_TOKEN")
+		}
+		if err.Error() == "email already taken" {
+			return utils.SendError(c, fiber.StatusConflict, "Email already taken")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to change email")
+	}
+
+	return utils.SendSuccess(c, "Email changed successfully")
+}
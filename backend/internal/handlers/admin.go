@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+type AdminHandler struct {
+	adminService *services.AdminService
+}
+
+func NewAdminHandler(cfg *config.Config) *AdminHandler {
+	return &AdminHandler{
+		adminService: services.NewAdminService(cfg),
+	}
+}
+
+// GetStats handles reporting system-wide stats
+func (h *AdminHandler) GetStats(c *fiber.Ctx) error {
+	stats, err := h.adminService.GetStats()
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to fetch stats")
+	}
+
+	return utils.SendSuccess(c, "Stats fetched successfully", stats)
+}
+
+// GetUsers handles listing and searching users
+func (h *AdminHandler) GetUsers(c *fiber.Ctx) error {
+	query := services.AdminUserQuery{
+		Search:   c.Query("q"),
+		Role:     c.Query("role"),
+		Status:   c.Query("status"),
+		Page:     c.QueryInt("page", 1),
+		PageSize: c.QueryInt("page_size", 20),
+	}
+
+	users, err := h.adminService.ListUsers(&query)
+	if err != nil {
+		if err.Error() == "invalid status" {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid status, expected active, disabled or pending_deletion")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to fetch users")
+	}
+
+	return utils.SendSuccess(c, "Users fetched successfully", users)
+}
+
+// GetUser handles fetching a single user
+func (h *AdminHandler) GetUser(c *fiber.Ctx) error {
+	user, err := h.adminService.GetUser(c.Params("id"))
+	if err != nil {
+		return h.sendError(c, err, "Failed to fetch user")
+	}
+
+	return utils.SendSuccess(c, "User fetched successfully", user)
+}
+
+// UpdateRole handles changing a user's role
+func (h *AdminHandler) UpdateRole(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+	var req services.UpdateRoleRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	user, err := h.adminService.SetRole(actorID, c.Params("id"), req.Role, clientInfo(c))
+	if err != nil {
+		return h.sendError(c, err, "Failed to update role")
+	}
+
+	return utils.SendSuccess(c, "Role updated successfully", user)
+}
+
+// DisableUser handles disabling a user's account
+func (h *AdminHandler) DisableUser(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	user, err := h.adminService.DisableUser(actorID, c.Params("id"), clientInfo(c))
+	if err != nil {
+		return h.sendError(c, err, "Failed to disable user")
+	}
+
+	return utils.SendSuccess(c, "User disabled successfully", user)
+}
+
+// EnableUser handles re-enabling a disabled account
+func (h *AdminHandler) EnableUser(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	user, err := h.adminService.EnableUser(actorID, c.Params("id"), clientInfo(c))
+	if err != nil {
+		return h.sendError(c, err, "Failed to enable user")
+	}
+
+	return utils.SendSuccess(c, "User enabled successfully", user)
+}
+
+// ForceLogout handles signing a user out of every session
+func (h *AdminHandler) ForceLogout(c *fiber.Ctx) error {
+	actorID := c.Locals("user_id").(string)
+
+	if err := h.adminService.ForceLogout(actorID, c.Params("id"), clientInfo(c)); err != nil {
+		return h.sendError(c, err, "Failed to sign out user")
+	}
+
+	return utils.SendSuccess(c, "User signed out of all sessions")
+}
+
+func (h *AdminHandler) sendError(c *fiber.Ctx, err error, fallback string) error {
+	switch err.Error() {
+	case "user not found":
+		return utils.SendError(c, fiber.StatusNotFound, "User not found")
+	case "invalid role":
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid role, expected user, support or admin")
+	case "cannot change own account":
+		return utils.SendError(c, fiber.StatusBadRequest, "You cannot change your own role or disable your own account")
+	}
+	return utils.SendError(c, fiber.StatusInternalServerError, fallback)
+}
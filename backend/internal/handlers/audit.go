@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+func NewAuditHandler() *AuditHandler {
+	return &AuditHandler{
+		auditService: services.NewAuditService(),
+	}
+}
+
+// GetSecurityEvents handles listing the user's own security events
+func (h *AuditHandler) GetSecurityEvents(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	query, err := auditQuery(c)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	page, err := h.auditService.ListUserEvents(userID, query)
+	if err != nil {
+		if err.Error() == "invalid cursor" {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid cursor")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to fetch security events")
+	}
+
+	return utils.SendSuccess(c, "Security events fetched successfully", page)
+}
+
+// GetAuditEvents handles querying the audit log across users (admin only)
+func (h *AuditHandler) GetAuditEvents(c *fiber.Ctx) error {
+	query, err := auditQuery(c)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
+	query.UserID = c.Query("user_id")
+	query.IPAddress = c.Query("ip_address")
+
+	page, err := h.auditService.ListEvents(query)
+	if err != nil {
+		if err.Error() == "invalid cursor" {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid cursor")
+		}
+		if err.Error() == "invalid user ID" {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to fetch audit events")
+	}
+
+	return utils.SendSuccess(c, "Audit events fetched successfully", page)
+}
+
+// auditQuery reads the filters shared by both audit endpoints
+func auditQuery(c *fiber.Ctx) (*services.AuditQuery, error) {
+	query := &services.AuditQuery{
+		Event:   c.Query("event"),
+		Outcome: c.Query("outcome"),
+		Cursor:  c.Query("cursor"),
+		Limit:   c.QueryInt("limit", 0),
+	}
+	for name, target := range map[string]**time.Time{"since": &query.Since, "until": &query.Until} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid "+name+" parameter, expected an RFC 3339 timestamp")
+		}
+		*target = &t
+	}
+	return query, nil
+}
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/lockout"
 	"github.com/pratts/tts-study-assistant/backend/internal/services"
 	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
 )
@@ -32,7 +37,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Email, password, and name are required")
 	}
 
-	response, err := h.authService.Register(&req)
+	response, err := h.authService.Register(&req, clientInfo(c))
 	if err != nil {
 		if err.Error() == "user already exists" {
 			return utils.SendError(c, fiber.StatusConflict, "User already exists")
@@ -56,11 +61,57 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Email and password are required")
 	}
 
-	response, err := h.authService.Login(&req)
+	response, challenge, err := h.authService.Login(&req, clientInfo(c))
 	if err != nil {
+		var locked *lockout.LockedError
+		if errors.As(err, &locked) {
+			return sendLocked(c, locked)
+		}
 		if err.Error() == "invalid credentials" {
 			return utils.SendError(c, fiber.StatusUnauthorized, "Invalid credentials")
 		}
+		if err.Error() == "email not verified" {
+			return utils.SendError(c, fiber.StatusForbidden, "Please verify your email address before logging in", "EMAIL_NOT_VERIFIED")
+		}
+		if err.Error() == "account disabled" {
+			return utils.SendError(c, fiber.StatusForbidden, "This account has been disabled", "ACCOUNT_DISABLED")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to login")
+	}
+	if challenge != nil {
+		return utils.SendSuccess(c, "Two-factor authentication required", challenge)
+	}
+
+	return utils.SendSuccess(c, "Login successful", response)
+}
+
+// LoginTwoFactor handles the second login step for users with 2FA enabled
+func (h *AuthHandler) LoginTwoFactor(c *fiber.Ctx) error {
+	var req services.TwoFactorLoginRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.ChallengeToken == "" || req.Code == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Challenge token and code are required")
+	}
+
+	response, err := h.authService.LoginWithTwoFactor(&req, clientInfo(c))
+	if err != nil {
+		var locked *lockout.LockedError
+		if errors.As(err, &locked) {
+			return sendLocked(c, locked)
+		}
+		if err.Error() == "invalid challenge" {
+			return utils.SendError(c, fiber.StatusUnauthorized, "Login expired, please sign in again", "INVALID_CHALLENGE")
+		}
+		if err.Error() == "invalid two-factor code" {
+			return utils.SendError(c, fiber.StatusUnauthorized, "Invalid two-factor code", "INVALID_2FA_CODE")
+		}
+		if err.Error() == "account disabled" {
+			return utils.SendError(c, fiber.StatusForbidden, "This account has been disabled", "ACCOUNT_DISABLED")
+		}
 		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to login")
 	}
 
@@ -79,7 +130,7 @@ func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Refresh token is required")
 	}
 
-	response, err := h.authService.Refresh(&req)
+	response, err := h.authService.Refresh(&req, clientInfo(c))
 	if err != nil {
 		if err.Error() == "invalid refresh token" {
 			return utils.SendError(c, fiber.StatusUnauthorized, "Invalid refresh token")
@@ -104,13 +155,32 @@ func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Refresh token is required")
 	}
 
-	if err := h.authService.Logout(req.RefreshToken); err != nil {
+	if err := h.authService.Logout(req.RefreshToken, clientInfo(c)); err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to logout")
 	}
 
 	return utils.SendSuccess(c, "Logout successful")
 }
 
+// sendLocked reports a login lockout with a Retry-After header
+func sendLocked(c *fiber.Ctx, locked *lockout.LockedError) error {
+	seconds := int(math.Ceil(locked.RetryAfter.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return utils.SendError(c, fiber.StatusTooManyRequests,
+		fmt.Sprintf("Too many failed login attempts, try again in %d seconds", seconds), "ACCOUNT_LOCKED")
+}
+
+// clientInfo extracts the caller's IP, user agent and, on protected routes,
+// the token's source for auditing
+func clientInfo(c *fiber.Ctx) services.ClientInfo {
+	source, _ := c.Locals("source").(string)
+	return services.ClientInfo{
+		IP:        c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		Source:    source,
+	}
+}
+
 // Verify handles token verification
 func (h *AuthHandler) Verify(c *fiber.Ctx) error {
 	// Get Authorization header
@@ -146,13 +216,13 @@ func (h *AuthHandler) ExtensionSync(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusUnauthorized, "User not found")
 	}
 	// Generate extension tokens
-	accessToken, err := h.authService.GenerateAccessTokenForSource(user.ID.String(), user.Email, "extension")
+	refreshToken, sessionID, err := h.authService.GenerateRefreshTokenForSource(user.ID.String(), "extension", "", clientInfo(c))
 	if err != nil {
-		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to generate access token")
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to generate refresh token")
 	}
-	refreshToken, err := h.authService.GenerateRefreshTokenForSource(user.ID.String(), "extension", "")
+	accessToken, err := h.authService.GenerateAccessTokenForSource(user, "extension", sessionID)
 	if err != nil {
-		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to generate refresh token")
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to generate access token")
 	}
 	return utils.SendSuccess(c, "Extension tokens generated", fiber.Map{
 		"access_token":  accessToken,
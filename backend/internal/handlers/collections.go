@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+type CollectionHandler struct {
+	collectionService *services.CollectionService
+}
+
+func NewCollectionHandler() *CollectionHandler {
+	return &CollectionHandler{
+		collectionService: services.NewCollectionService(),
+	}
+}
+
+// GetCollections handles listing the user's collections as a tree
+func (h *CollectionHandler) GetCollections(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	collections, err := h.collectionService.ListCollections(userID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to fetch collections")
+	}
+
+	return utils.SendSuccess(c, "Collections fetched successfully", collections)
+}
+
+// GetCollection handles getting a collection with its subcollections
+func (h *CollectionHandler) GetCollection(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	collection, err := h.collectionService.GetCollection(userID, c.Params("id"))
+	if err != nil {
+		return sendCollectionError(c, err, "Failed to fetch collection")
+	}
+
+	return utils.SendSuccess(c, "Collection fetched successfully", collection)
+}
+
+// CreateCollection handles creating a collection
+func (h *CollectionHandler) CreateCollection(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.CreateCollectionRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	collection, err := h.collectionService.CreateCollection(userID, &req)
+	if err != nil {
+		return sendCollectionError(c, err, "Failed to create collection")
+	}
+
+	return utils.SendSuccess(c, "Collection created successfully", collection)
+}
+
+// UpdateCollection handles renaming a collection or changing its description
+func (h *CollectionHandler) UpdateCollection(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.UpdateCollectionRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	collection, err := h.collectionService.UpdateCollection(userID, c.Params("id"), &req)
+	if err != nil {
+		return sendCollectionError(c, err, "Failed to update collection")
+	}
+
+	return utils.SendSuccess(c, "Collection updated successfully", collection)
+}
+
+// MoveCollection handles moving a collection to another parent or position
+func (h *CollectionHandler) MoveCollection(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.MoveCollectionRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	collection, err := h.collectionService.MoveCollection(userID, c.Params("id"), &req)
+	if err != nil {
+		return sendCollectionError(c, err, "Failed to move collection")
+	}
+
+	return utils.SendSuccess(c, "Collection moved successfully", collection)
+}
+
+// DeleteCollection handles deleting a collection; the mode query parameter
+// picks what happens to its subcollections and notes
+func (h *CollectionHandler) DeleteCollection(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	result, err := h.collectionService.DeleteCollection(userID, c.Params("id"), c.Query("mode"))
+	if err != nil {
+		return sendCollectionError(c, err, "Failed to delete collection")
+	}
+
+	return utils.SendSuccess(c, "Collection deleted successfully", result)
+}
+
+// AddNotes handles adding notes to a collection
+func (h *CollectionHandler) AddNotes(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.CollectionNotesRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.collectionService.AddNotes(userID, c.Params("id"), &req); err != nil {
+		return sendCollectionError(c, err, "Failed to add notes to collection")
+	}
+
+	return utils.SendSuccess(c, "Notes added to collection successfully")
+}
+
+// RemoveNote handles taking a note out of a collection
+func (h *CollectionHandler) RemoveNote(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.collectionService.RemoveNote(userID, c.Params("id"), c.Params("noteId")); err != nil {
+		return sendCollectionError(c, err, "Failed to remove note from collection")
+	}
+
+	return utils.SendSuccess(c, "Note removed from collection successfully")
+}
+
+// ReorderNotes handles changing the order of the notes in a collection
+func (h *CollectionHandler) ReorderNotes(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.CollectionNotesRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.collectionService.ReorderNotes(userID, c.Params("id"), &req); err != nil {
+		return sendCollectionError(c, err, "Failed to reorder notes")
+	}
+
+	return utils.SendSuccess(c, "Notes reordered successfully")
+}
+
+// sendCollectionError maps collection errors, which the notes list can
+// return too, to responses
+func sendCollectionError(c *fiber.Ctx, err error, fallback string) error {
+	switch err.Error() {
+	case "collection not found":
+		return utils.SendError(c, fiber.StatusNotFound, "Collection not found")
+	case "parent collection not found":
+		return utils.SendError(c, fiber.StatusBadRequest, "Parent collection not found")
+	case "collection name is required":
+		return utils.SendError(c, fiber.StatusBadRequest, "Collection name is required")
+	case "collection name too long":
+		return utils.SendError(c, fiber.StatusBadRequest, "Collection names can be at most 100 characters")
+	case "collection too deep":
+		return utils.SendError(c, fiber.StatusBadRequest, "Collections can be nested at most 8 levels deep")
+	case "cannot move collection into itself":
+		return utils.SendError(c, fiber.StatusBadRequest, "A collection cannot be moved into itself or one of its subcollections")
+	case "invalid delete mode":
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid mode, expected keep_children, cascade or cascade_notes")
+	case "note not found":
+		return utils.SendError(c, fiber.StatusNotFound, "Note not found")
+	case "note not in collection":
+		return utils.SendError(c, fiber.StatusNotFound, "Note is not in this collection")
+	case "no notes given":
+		return utils.SendError(c, fiber.StatusBadRequest, "Pick at least one note")
+	case "too many notes":
+		return utils.SendError(c, fiber.StatusBadRequest, "At most 100 notes can be changed at once")
+	}
+	return utils.SendError(c, fiber.StatusInternalServerError, fallback)
+}
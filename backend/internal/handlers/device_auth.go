@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+type DeviceAuthHandler struct {
+	deviceAuthService *services.DeviceAuthService
+}
+
+func NewDeviceAuthHandler(cfg *config.Config) *DeviceAuthHandler {
+	return &DeviceAuthHandler{
+		deviceAuthService: services.NewDeviceAuthService(cfg),
+	}
+}
+
+// StartAuthorization handles the extension's request for a device code
+func (h *DeviceAuthHandler) StartAuthorization(c *fiber.Ctx) error {
+	var req services.DeviceAuthorizationRequest
+
+	// The body is optional
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	response, err := h.deviceAuthService.StartAuthorization(&req, clientInfo(c))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to start device authorization")
+	}
+
+	return utils.SendSuccess(c, "Device authorization started", response)
+}
+
+// PollToken handles the extension polling for its tokens
+func (h *DeviceAuthHandler) PollToken(c *fiber.Ctx) error {
+	var req services.DeviceTokenRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.DeviceCode == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Device code is required")
+	}
+
+	response, err := h.deviceAuthService.PollToken(&req, clientInfo(c))
+	if err != nil {
+		switch err.Error() {
+		case "authorization pending":
+			return utils.SendError(c, fiber.StatusBadRequest, "Authorization pending", "AUTHORIZATION_PENDING")
+		case "slow down":
+			return utils.SendError(c, fiber.StatusBadRequest, "Polling too fast", "SLOW_DOWN")
+		case "expired token":
+			return utils.SendError(c, fiber.StatusBadRequest, "Device code expired", "EXPIRED_TOKEN")
+		case "access denied":
+			return utils.SendError(c, fiber.StatusBadRequest, "Authorization denied", "ACCESS_DENIED")
+		case "invalid device code":
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid device code", "INVALID_GRANT")
+		case "account disabled":
+			return utils.SendError(c, fiber.StatusForbidden, "This account has been disabled", "ACCOUNT_DISABLED")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to issue tokens")
+	}
+
+	return utils.SendSuccess(c, "Device authorized", response)
+}
+
+// GetPendingDevice handles looking up a pending device by user code
+func (h *DeviceAuthHandler) GetPendingDevice(c *fiber.Ctx) error {
+	userCode := c.Query("user_code")
+	if userCode == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "User code is required")
+	}
+
+	device, err := h.deviceAuthService.GetPendingDevice(userCode)
+	if err != nil {
+		if err.Error() == "device code not found" {
+			return utils.SendError(c, fiber.StatusNotFound, "Code not found or expired")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to fetch device")
+	}
+
+	return utils.SendSuccess(c, "Device fetched successfully", device)
+}
+
+// ApproveDevice handles the signed-in user approving or denying a device
+func (h *DeviceAuthHandler) ApproveDevice(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.DeviceApprovalRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.UserCode == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "User code is required")
+	}
+
+	if err := h.deviceAuthService.ApproveDevice(userID, &req); err != nil {
+		if err.Error() == "device code not found" {
+			return utils.SendError(c, fiber.StatusNotFound, "Code not found or expired")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to update device authorization")
+	}
+
+	if !req.Approve {
+		return utils.SendSuccess(c, "Device denied")
+	}
+	return utils.SendSuccess(c, "Device approved")
+}
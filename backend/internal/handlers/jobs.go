@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/scheduler"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+type JobsHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+type JobStatusResponse struct {
+	Name           string `json:"name"`
+	Schedule       string `json:"schedule"`
+	Running        bool   `json:"running"`
+	NextRunAt      string `json:"next_run_at,omitempty"`
+	LastRunAt      string `json:"last_run_at,omitempty"`
+	LastDurationMs int64  `json:"last_duration_ms"`
+	LastError      string `json:"last_error,omitempty"`
+	LastSuccessAt  string `json:"last_success_at,omitempty"`
+	Runs           int    `json:"runs"`
+	Failures       int    `json:"failures"`
+	Skipped        int    `json:"skipped"`
+}
+
+func NewJobsHandler(jobs *scheduler.Scheduler) *JobsHandler {
+	return &JobsHandler{
+		scheduler: jobs,
+	}
+}
+
+// GetJobs handles reporting the background jobs as seen by this server
+func (h *JobsHandler) GetJobs(c *fiber.Ctx) error {
+	statuses := h.scheduler.Status()
+	jobs := make([]JobStatusResponse, len(statuses))
+	for i, status := range statuses {
+		jobs[i] = JobStatusResponse{
+			Name:           status.Name,
+			Schedule:       status.Schedule,
+			Running:        status.Running,
+			NextRunAt:      formatTime(status.NextRun),
+			LastRunAt:      formatTime(status.LastRun),
+			LastDurationMs: status.LastDuration.Milliseconds(),
+			LastError:      status.LastError,
+			LastSuccessAt:  formatTime(status.LastSuccess),
+			Runs:           status.Runs,
+			Failures:       status.Failures,
+			Skipped:        status.Skipped,
+		}
+	}
+
+	return utils.SendSuccess(c, "Jobs fetched successfully", jobs)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02T15:04:05Z07:00")
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/signing"
+)
+
+type JWKSHandler struct{}
+
+func NewJWKSHandler() *JWKSHandler {
+	return &JWKSHandler{}
+}
+
+// GetJWKS publishes the public keys that verify access tokens so other
+// services can check them without sharing a secret
+func (h *JWKSHandler) GetJWKS(c *fiber.Ctx) error {
+	if signing.Keys == nil {
+		return c.JSON(signing.JWKS{Keys: []signing.JWK{}})
+	}
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(signing.Keys.JWKS())
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+// GetDuplicates handles listing groups of near-duplicate notes
+func (h *NotesHandler) GetDuplicates(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	threshold := c.QueryFloat("threshold", services.DefaultDuplicateThreshold)
+
+	clusters, err := h.notesService.FindDuplicates(c.UserContext(), userID, threshold, c.QueryInt("limit"))
+	if err != nil {
+		if err.Error() == "invalid threshold" {
+			return utils.SendError(c, fiber.StatusBadRequest, "Threshold must be between 0.5 and 1")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to find duplicates")
+	}
+
+	return utils.SendSuccess(c, "Duplicates fetched successfully", clusters)
+}
+
+// MergeNotes handles folding notes into one and moving the rest to the trash
+func (h *NotesHandler) MergeNotes(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.MergeNotesRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	note, err := h.notesService.MergeNotes(userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "note not found":
+			return utils.SendError(c, fiber.StatusNotFound, "Note not found")
+		case "no notes given":
+			return utils.SendError(c, fiber.StatusBadRequest, "Give the notes to merge in note_ids")
+		case "too many notes":
+			return utils.SendError(c, fiber.StatusBadRequest, "At most 100 notes can be merged at once")
+		case "cannot merge note into itself":
+			return utils.SendError(c, fiber.StatusBadRequest, "The target cannot be one of the merged notes")
+		}
+		return sendTagError(c, err, "Failed to merge notes")
+	}
+
+	return utils.SendSuccess(c, "Notes merged successfully", note)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+// GetRevisions handles listing the kept revisions of a note
+func (h *NotesHandler) GetRevisions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	revisions, err := h.notesService.ListRevisions(c.Params("id"), userID)
+	if err != nil {
+		return sendRevisionError(c, err, "Failed to fetch revisions")
+	}
+
+	return utils.SendSuccess(c, "Revisions fetched successfully", revisions)
+}
+
+// GetRevision handles getting one revision of a note
+func (h *NotesHandler) GetRevision(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	number, err := strconv.Atoi(c.Params("number"))
+	if err != nil || number < 1 {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid revision number")
+	}
+
+	revision, err := h.notesService.GetRevision(c.Params("id"), userID, number)
+	if err != nil {
+		return sendRevisionError(c, err, "Failed to fetch revision")
+	}
+
+	return utils.SendSuccess(c, "Revision fetched successfully", revision)
+}
+
+// DiffRevisions handles comparing two revisions of a note. Without from and
+// to, the latest revision is compared with the one before it.
+func (h *NotesHandler) DiffRevisions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	from, to := c.QueryInt("from"), c.QueryInt("to")
+	if from < 0 || to < 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid revision number")
+	}
+
+	diff, err := h.notesService.DiffRevisions(c.Params("id"), userID, from, to)
+	if err != nil {
+		return sendRevisionError(c, err, "Failed to compare revisions")
+	}
+
+	return utils.SendSuccess(c, "Revisions compared successfully", diff)
+}
+
+// RevertNote handles putting a note back as it was at a revision
+func (h *NotesHandler) RevertNote(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	number, err := strconv.Atoi(c.Params("number"))
+	if err != nil || number < 1 {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid revision number")
+	}
+
+	note, err := h.notesService.RevertNote(c.Params("id"), userID, number)
+	if err != nil {
+		if err.Error() == "revision not found" || err.Error() == "note not found" {
+			return sendRevisionError(c, err, "Failed to revert note")
+		}
+		return sendTagError(c, err, "Failed to revert note")
+	}
+
+	return utils.SendSuccess(c, "Note reverted successfully", note)
+}
+
+// sendRevisionError maps revision service errors to responses
+func sendRevisionError(c *fiber.Ctx, err error, fallback string) error {
+	switch err.Error() {
+	case "note not found":
+		return utils.SendError(c, fiber.StatusNotFound, "Note not found")
+	case "revision not found":
+		return utils.SendError(c, fiber.StatusNotFound, "Revision not found")
+	}
+	return utils.SendError(c, fiber.StatusInternalServerError, fallback)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+type TrashHandler struct {
+	trashService *services.NoteTrashService
+}
+
+func NewTrashHandler(cfg *config.Config) *TrashHandler {
+	return &TrashHandler{
+		trashService: services.NewNoteTrashService(cfg),
+	}
+}
+
+// GetTrash handles listing the user's deleted notes
+func (h *TrashHandler) GetTrash(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	page, err := h.trashService.ListTrash(userID, c.QueryInt("page", 1), c.QueryInt("page_size", 10))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to fetch trash")
+	}
+
+	return utils.SendSuccessWithMeta(c, "Trash fetched successfully", page.Notes, page.Info)
+}
+
+// RestoreNote handles taking a note out of the trash
+func (h *TrashHandler) RestoreNote(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	note, err := h.trashService.RestoreNote(userID, c.Params("id"))
+	if err != nil {
+		if err.Error() == "note not found" {
+			return utils.SendError(c, fiber.StatusNotFound, "Note not found in trash")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to restore note")
+	}
+
+	return utils.SendSuccess(c, "Note restored successfully", note)
+}
+
+// DeleteNote handles permanently deleting a note in the trash
+func (h *TrashHandler) DeleteNote(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.trashService.DeleteNote(userID, c.Params("id")); err != nil {
+		if err.Error() == "note not found" {
+			return utils.SendError(c, fiber.StatusNotFound, "Note not found in trash")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to delete note")
+	}
+
+	return utils.SendSuccess(c, "Note deleted permanently")
+}
+
+// EmptyTrash handles permanently deleting every note in the trash
+func (h *TrashHandler) EmptyTrash(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	deleted, err := h.trashService.EmptyTrash(userID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to empty trash")
+	}
+
+	return utils.SendSuccess(c, "Trash emptied successfully", fiber.Map{"deleted": deleted})
+}
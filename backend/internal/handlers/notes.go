@@ -2,8 +2,13 @@ package handlers
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/middleware"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
 	"github.com/pratts/tts-study-assistant/backend/internal/services"
 	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
 )
@@ -22,18 +27,117 @@ func NewNotesHandler() *NotesHandler {
 func (h *NotesHandler) GetNotes(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 
-	// Parse pagination and filter params
-	page := c.QueryInt("page", 1)
-	pageSize := c.QueryInt("page_size", 10)
-	sourceURL := c.Query("source_url", "")
-	domain := c.Query("domain", "")
+	query, err := notesQuery(c)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
 
-	notes, err := h.notesService.GetNotes(userID, page, pageSize, sourceURL, domain)
+	page, err := h.notesService.GetNotes(userID, query)
 	if err != nil {
+		switch err.Error() {
+		case "invalid sort":
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid sort, expected created_at, updated_at, title, domain or, with collection_id, position")
+		case "invalid order":
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid order, expected asc or desc")
+		case "invalid cursor":
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid cursor")
+		case "collection not found":
+			return sendCollectionError(c, err, "Failed to fetch notes")
+		}
+		if message, ok := noteFilterErrors[err.Error()]; ok {
+			return utils.SendError(c, fiber.StatusBadRequest, message)
+		}
 		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to fetch notes")
 	}
 
-	return utils.SendSuccess(c, "Notes fetched successfully", notes)
+	// data stays a plain list of notes for older clients; paging is in meta
+	return utils.SendSuccessWithMeta(c, "Notes fetched successfully", page.Notes, page.Info)
+}
+
+// noteFilterErrors maps the errors of the notes list filters to messages
+var noteFilterErrors = map[string]string{
+	"invalid date range":        "Invalid date range, the start must be before the end",
+	"invalid metadata filter":   "Invalid metadata filter, expected metadata.<key>=<value> with keys of letters, digits, _ or -",
+	"too many metadata filters": "At most 10 metadata filters can be combined",
+	"invalid tag mode":          "Invalid tag_mode, expected all or any",
+	"invalid tag filter":        "Invalid tags filter",
+}
+
+// notesQuery reads the notes list's paging, filter and sort parameters.
+// Metadata filters are the parameters named metadata.<key path>.
+func notesQuery(c *fiber.Ctx) (*services.NoteQuery, error) {
+	query := &services.NoteQuery{
+		Page:         c.QueryInt("page", 1),
+		PageSize:     c.QueryInt("page_size", 10),
+		Cursor:       c.Query("cursor"),
+		IncludeTotal: c.QueryBool("include_total"),
+		SourceURL:    c.Query("source_url", ""),
+		Domain:       c.Query("domain", ""),
+		TagMode:      c.Query("tag_mode", services.TagModeAll),
+		CollectionID: c.Query("collection_id"),
+		Sort:         c.Query("sort"),
+		Order:        c.Query("order"),
+	}
+	if tags := c.Query("tags"); tags != "" {
+		query.Tags = strings.Split(tags, ",")
+	}
+
+	dates := map[string]**time.Time{
+		"created_since": &query.CreatedSince,
+		"created_until": &query.CreatedUntil,
+		"updated_since": &query.UpdatedSince,
+		"updated_until": &query.UpdatedUntil,
+	}
+	for name, target := range dates {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid "+name+" parameter, expected an RFC 3339 timestamp")
+		}
+		*target = &t
+	}
+
+	if value := c.Query("has_summary"); value != "" {
+		hasSummary, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid has_summary parameter, expected true or false")
+		}
+		query.HasSummary = &hasSummary
+	}
+
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		if path, ok := strings.CutPrefix(string(key), "metadata."); ok {
+			if query.Metadata == nil {
+				query.Metadata = make(map[string]string)
+			}
+			query.Metadata[path] = string(value)
+		}
+	})
+	return query, nil
+}
+
+// SearchNotes handles full-text search over a user's notes
+func (h *NotesHandler) SearchNotes(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	page := c.QueryInt("page", 1)
+	pageSize := c.QueryInt("page_size", 20)
+
+	results, err := h.notesService.SearchNotes(userID, c.Query("q"), page, pageSize)
+	if err != nil {
+		switch err.Error() {
+		case "search query required":
+			return utils.SendError(c, fiber.StatusBadRequest, "Search query is required")
+		case "search query too long":
+			return utils.SendError(c, fiber.StatusBadRequest, "Search query is too long")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to search notes")
+	}
+
+	return utils.SendSuccess(c, "Notes searched successfully", results)
 }
 
 // GetNote handles getting a specific note by ID
@@ -69,9 +173,12 @@ func (h *NotesHandler) CreateNote(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Content is required")
 	}
 
-	note, err := h.notesService.CreateNote(&req, userID)
+	note, existing, err := h.notesService.CreateNote(&req, userID)
 	if err != nil {
-		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to create note")
+		return sendTagError(c, err, "Failed to create note")
+	}
+	if existing {
+		return utils.SendSuccessWithMeta(c, "A near-identical note already exists", note, fiber.Map{"existing": true})
 	}
 
 	return utils.SendSuccess(c, "Note created successfully", note)
@@ -96,13 +203,13 @@ func (h *NotesHandler) UpdateNote(c *fiber.Ctx) error {
 		if err.Error() == "note not found" {
 			return utils.SendError(c, fiber.StatusNotFound, "Note not found")
 		}
-		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to update note")
+		return sendTagError(c, err, "Failed to update note")
 	}
 
 	return utils.SendSuccess(c, "Note updated successfully", note)
 }
 
-// DeleteNote handles deleting a note
+// DeleteNote handles moving a note to the trash
 func (h *NotesHandler) DeleteNote(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	noteID := c.Params("id")
@@ -119,7 +226,7 @@ func (h *NotesHandler) DeleteNote(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to delete note")
 	}
 
-	return utils.SendSuccess(c, "Note deleted successfully")
+	return utils.SendSuccess(c, "Note moved to trash")
 }
 
 // GetNotesStats returns the number of notes per unique domain for the user
@@ -150,3 +257,51 @@ func (h *NotesHandler) SummarizeNote(c *fiber.Ctx) error {
 	}
 	return utils.SendSuccess(c, "Note summarized successfully", fiber.Map{"summary": summary})
 }
+
+// BulkNotes handles applying one action to many notes at once
+func (h *NotesHandler) BulkNotes(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.BulkNotesRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	// Queuing summaries needs the scope summarizing a single note does
+	if req.Action == services.BulkActionSummarize && !middleware.HasScope(c, models.ScopeSummariesWrite) {
+		return middleware.SendMissingScope(c, models.ScopeSummariesWrite)
+	}
+
+	result, err := h.notesService.BulkUpdate(userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "no notes given":
+			return utils.SendError(c, fiber.StatusBadRequest, "Give note IDs or a filter")
+		case "ids and filter both given":
+			return utils.SendError(c, fiber.StatusBadRequest, "Give note IDs or a filter, not both")
+		case "too many notes":
+			return utils.SendError(c, fiber.StatusBadRequest, "A bulk request can change at most 1000 notes")
+		case "invalid action":
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid action")
+		case "domain is required":
+			return utils.SendError(c, fiber.StatusBadRequest, "Domain is required")
+		case "no metadata given":
+			return utils.SendError(c, fiber.StatusBadRequest, "Metadata is required")
+		case "no tags given":
+			return utils.SendError(c, fiber.StatusBadRequest, "Tags are required")
+		case "collection not found":
+			return utils.SendError(c, fiber.StatusNotFound, "Collection not found")
+		}
+		if message, ok := noteFilterErrors[err.Error()]; ok {
+			return utils.SendError(c, fiber.StatusBadRequest, message)
+		}
+		return sendTagError(c, err, "Failed to update notes")
+	}
+
+	switch {
+	case result.DryRun:
+		return utils.SendSuccess(c, "Dry run; no notes were changed", result)
+	case !result.Applied && result.Failed > 0:
+		return utils.SendSuccess(c, "Some notes failed; no notes were changed", result)
+	}
+	return utils.SendSuccess(c, "Notes updated successfully", result)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+type OIDCHandler struct {
+	oidcService *services.OIDCService
+}
+
+func NewOIDCHandler(cfg *config.Config) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: services.NewOIDCService(cfg),
+	}
+}
+
+// GetInfo handles reporting whether single sign-on is available
+func (h *OIDCHandler) GetInfo(c *fiber.Ctx) error {
+	return utils.SendSuccess(c, "SSO info fetched successfully", h.oidcService.GetInfo())
+}
+
+// Authorize handles starting a single sign-on login
+func (h *OIDCHandler) Authorize(c *fiber.Ctx) error {
+	var req services.OIDCAuthorizeRequest
+
+	// The body is optional
+	_ = c.BodyParser(&req)
+
+	response, err := h.oidcService.StartLogin(&req)
+	if err != nil {
+		switch err.Error() {
+		case "sso not configured":
+			return utils.SendError(c, fiber.StatusNotFound, "Single sign-on is not configured")
+		case "sso provider unavailable":
+			return utils.SendError(c, fiber.StatusBadGateway, "Single sign-on provider is unavailable")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to start single sign-on")
+	}
+
+	return utils.SendSuccess(c, "Redirect to the authorization URL", response)
+}
+
+// Callback handles finishing a single sign-on login
+func (h *OIDCHandler) Callback(c *fiber.Ctx) error {
+	var req services.OIDCCallbackRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Code == "" || req.State == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Code and state are required")
+	}
+
+	response, challenge, err := h.oidcService.FinishLogin(&req, clientInfo(c))
+	if err != nil {
+		switch err.Error() {
+		case "sso not configured":
+			return utils.SendError(c, fiber.StatusNotFound, "Single sign-on is not configured")
+		case "sso provider unavailable":
+			return utils.SendError(c, fiber.StatusBadGateway, "Single sign-on provider is unavailable")
+		case "invalid state":
+			return utils.SendError(c, fiber.StatusBadRequest, "Sign-in expired, please try again", "INVALID_STATE")
+		case "sso login failed":
+			return utils.SendError(c, fiber.StatusUnauthorized, "Single sign-on failed", "SSO_FAILED")
+		case "email not verified":
+			return utils.SendError(c, fiber.StatusForbidden, "Your identity provider did not confirm your email address", "EMAIL_NOT_VERIFIED")
+		case "account email not verified":
+			return utils.SendError(c, fiber.StatusForbidden, "Verify the email of your account before signing in with single sign-on", "ACCOUNT_EMAIL_NOT_VERIFIED")
+		case "account not found":
+			return utils.SendError(c, fiber.StatusForbidden, "No account exists for this email", "ACCOUNT_NOT_FOUND")
+		case "account disabled":
+			return utils.SendError(c, fiber.StatusForbidden, "This account has been disabled", "ACCOUNT_DISABLED")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to login")
+	}
+	if challenge != nil {
+		return utils.SendSuccess(c, "Two-factor authentication required", challenge)
+	}
+
+	return utils.SendSuccess(c, "Login successful", response)
+}
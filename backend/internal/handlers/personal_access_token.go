@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+type PersonalAccessTokenHandler struct {
+	tokenService *services.PersonalAccessTokenService
+}
+
+func NewPersonalAccessTokenHandler() *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{
+		tokenService: services.NewPersonalAccessTokenService(),
+	}
+}
+
+// GetTokens handles listing the user's personal access tokens
+func (h *PersonalAccessTokenHandler) GetTokens(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	tokens, err := h.tokenService.ListTokens(userID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to fetch tokens")
+	}
+
+	return utils.SendSuccess(c, "Tokens fetched successfully", tokens)
+}
+
+// CreateToken handles creating a personal access token
+func (h *PersonalAccessTokenHandler) CreateToken(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.CreatePersonalAccessTokenRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	token, err := h.tokenService.CreateToken(userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "name is required":
+			return utils.SendError(c, fiber.StatusBadRequest, "Name is required")
+		case "at least one scope is required":
+			return utils.SendError(c, fiber.StatusBadRequest, "At least one scope is required")
+		case "invalid scope":
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid scope")
+		case "invalid expiry":
+			return utils.SendError(c, fiber.StatusBadRequest, "Expiry must be between 1 and 365 days")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to create token")
+	}
+
+	return utils.SendSuccess(c, "Token created. Copy it now, it will not be shown again", token)
+}
+
+// RevokeToken handles deleting a personal access token
+func (h *PersonalAccessTokenHandler) RevokeToken(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	tokenID := c.Params("id")
+
+	if err := h.tokenService.RevokeToken(userID, tokenID); err != nil {
+		if err.Error() == "token not found" {
+			return utils.SendError(c, fiber.StatusNotFound, "Token not found")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to revoke token")
+	}
+
+	return utils.SendSuccess(c, "Token revoked successfully")
+}
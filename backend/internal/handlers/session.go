@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+type SessionHandler struct {
+	sessionService *services.SessionService
+}
+
+func NewSessionHandler() *SessionHandler {
+	return &SessionHandler{
+		sessionService: services.NewSessionService(),
+	}
+}
+
+// GetSessions handles listing the user's active sessions
+func (h *SessionHandler) GetSessions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	sessionID, _ := c.Locals("session_id").(string)
+
+	sessions, err := h.sessionService.ListSessions(userID, sessionID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to fetch sessions")
+	}
+
+	return utils.SendSuccess(c, "Sessions fetched successfully", sessions)
+}
+
+// UpdateSession handles labelling a session
+func (h *SessionHandler) UpdateSession(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	sessionID := c.Params("id")
+	var req services.UpdateSessionRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.sessionService.UpdateSession(userID, sessionID, &req); err != nil {
+		switch err.Error() {
+		case "session not found":
+			return utils.SendError(c, fiber.StatusNotFound, "Session not found")
+		case "label too long":
+			return utils.SendError(c, fiber.StatusBadRequest, "Session labels can be at most 100 characters")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to update session")
+	}
+
+	return utils.SendSuccess(c, "Session updated successfully")
+}
+
+// RevokeSession handles revoking a single session
+func (h *SessionHandler) RevokeSession(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	sessionID := c.Params("id")
+
+	if err := h.sessionService.RevokeSession(userID, sessionID); err != nil {
+		if err.Error() == "session not found" {
+			return utils.SendError(c, fiber.StatusNotFound, "Session not found")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to revoke session")
+	}
+
+	return utils.SendSuccess(c, "Session revoked successfully")
+}
+
+// RevokeSessions handles revoking all sessions, or all but the current one
+// with ?except=current
+func (h *SessionHandler) RevokeSessions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	exceptSessionID := ""
+	switch c.Query("except") {
+	case "":
+	case "current":
+		exceptSessionID, _ = c.Locals("session_id").(string)
+		if exceptSessionID == "" {
+			return utils.SendError(c, fiber.StatusBadRequest, "Current session is unknown, please log in again")
+		}
+	default:
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid except parameter")
+	}
+
+	revoked, err := h.sessionService.RevokeSessions(userID, exceptSessionID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to revoke sessions")
+	}
+
+	return utils.SendSuccess(c, "Sessions revoked successfully", fiber.Map{"revoked": revoked})
+}
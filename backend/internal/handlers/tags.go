@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+type TagHandler struct {
+	tagService *services.TagService
+}
+
+func NewTagHandler() *TagHandler {
+	return &TagHandler{
+		tagService: services.NewTagService(),
+	}
+}
+
+// GetTags handles listing the user's tags with their note counts
+func (h *TagHandler) GetTags(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	tags, err := h.tagService.ListTags(userID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to fetch tags")
+	}
+
+	return utils.SendSuccess(c, "Tags fetched successfully", tags)
+}
+
+// CreateTag handles creating a tag
+func (h *TagHandler) CreateTag(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.CreateTagRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	tag, err := h.tagService.CreateTag(userID, &req)
+	if err != nil {
+		return sendTagError(c, err, "Failed to create tag")
+	}
+
+	return utils.SendSuccess(c, "Tag created successfully", tag)
+}
+
+// UpdateTag handles renaming or recoloring a tag
+func (h *TagHandler) UpdateTag(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.UpdateTagRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	tag, err := h.tagService.UpdateTag(userID, c.Params("id"), &req)
+	if err != nil {
+		return sendTagError(c, err, "Failed to update tag")
+	}
+
+	return utils.SendSuccess(c, "Tag updated successfully", tag)
+}
+
+// DeleteTag handles deleting a tag; its notes are kept
+func (h *TagHandler) DeleteTag(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.tagService.DeleteTag(userID, c.Params("id")); err != nil {
+		return sendTagError(c, err, "Failed to delete tag")
+	}
+
+	return utils.SendSuccess(c, "Tag deleted successfully")
+}
+
+// MergeTags handles merging tags into one
+func (h *TagHandler) MergeTags(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.MergeTagsRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	tag, err := h.tagService.MergeTags(userID, &req)
+	if err != nil {
+		return sendTagError(c, err, "Failed to merge tags")
+	}
+
+	return utils.SendSuccess(c, "Tags merged successfully", tag)
+}
+
+// sendTagError maps tag validation errors, which notes endpoints can return
+// too, to responses
+func sendTagError(c *fiber.Ctx, err error, fallback string) error {
+	switch err.Error() {
+	case "tag not found":
+		return utils.SendError(c, fiber.StatusNotFound, "Tag not found")
+	case "tag already exists":
+		return utils.SendError(c, fiber.StatusConflict, "A tag with this name already exists")
+	case "tag name is required":
+		return utils.SendError(c, fiber.StatusBadRequest, "Tag name is required")
+	case "tag name too long":
+		return utils.SendError(c, fiber.StatusBadRequest, "Tag names can be at most 50 characters")
+	case "invalid tag name":
+		return utils.SendError(c, fiber.StatusBadRequest, "Tag names cannot contain commas")
+	case "invalid color":
+		return utils.SendError(c, fiber.StatusBadRequest, "Color must be a hex color such as #3182ce")
+	case "too many tags":
+		return utils.SendError(c, fiber.StatusBadRequest, "A note can have at most 20 tags")
+	case "nothing to merge":
+		return utils.SendError(c, fiber.StatusBadRequest, "Pick at least one tag to merge into the target")
+	}
+	return utils.SendError(c, fiber.StatusInternalServerError, fallback)
+}
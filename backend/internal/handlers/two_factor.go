@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService *services.TwoFactorService
+}
+
+func NewTwoFactorHandler(cfg *config.Config) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: services.NewTwoFactorService(cfg),
+	}
+}
+
+// GetStatus handles reporting the user's 2FA status
+func (h *TwoFactorHandler) GetStatus(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	status, err := h.twoFactorService.GetStatus(userID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to fetch two-factor status")
+	}
+
+	return utils.SendSuccess(c, "Two-factor status fetched successfully", status)
+}
+
+// Setup handles starting TOTP enrollment
+func (h *TwoFactorHandler) Setup(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	setup, err := h.twoFactorService.BeginSetup(userID)
+	if err != nil {
+		if err.Error() == "two-factor already enabled" {
+			return utils.SendError(c, fiber.StatusConflict, "Two-factor authentication is already enabled")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to start two-factor setup")
+	}
+
+	return utils.SendSuccess(c, "Scan the code with your authenticator app, then confirm with a code", setup)
+}
+
+// Confirm handles finishing TOTP enrollment
+func (h *TwoFactorHandler) Confirm(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.TwoFactorCodeRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Code == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Code is required")
+	}
+
+	codes, err := h.twoFactorService.ConfirmSetup(userID, req.Code, clientInfo(c))
+	if err != nil {
+		return h.sendError(c, err, "Failed to enable two-factor authentication")
+	}
+
+	return utils.SendSuccess(c, "Two-factor authentication enabled", codes)
+}
+
+// Disable handles turning 2FA off
+func (h *TwoFactorHandler) Disable(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.DisableTwoFactorRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Password == "" || req.Code == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Password and code are required")
+	}
+
+	if err := h.twoFactorService.Disable(userID, &req, clientInfo(c)); err != nil {
+		return h.sendError(c, err, "Failed to disable two-factor authentication")
+	}
+
+	return utils.SendSuccess(c, "Two-factor authentication disabled")
+}
+
+// RegenerateRecoveryCodes handles replacing the user's recovery codes
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.TwoFactorCodeRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Code == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Code is required")
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(userID, req.Code, clientInfo(c))
+	if err != nil {
+		return h.sendError(c, err, "Failed to regenerate recovery codes")
+	}
+
+	return utils.SendSuccess(c, "Recovery codes regenerated", codes)
+}
+
+func (h *TwoFactorHandler) sendError(c *fiber.Ctx, err error, fallback string) error {
+	switch err.Error() {
+	case "invalid two-factor code":
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid two-factor code", "INVALID_2FA_CODE")
+	case "invalid password":
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid password")
+	case "two-factor already enabled":
+		return utils.SendError(c, fiber.StatusConflict, "Two-factor authentication is already enabled")
+	case "two-factor not enabled":
+		return utils.SendError(c, fiber.StatusBadRequest, "Two-factor authentication is not enabled")
+	case "two-factor setup not started":
+		return utils.SendError(c, fiber.StatusBadRequest, "Start two-factor setup first")
+	}
+	return utils.SendError(c, fiber.StatusInternalServerError, fallback)
+}
@@ -1,18 +1,25 @@
 package handlers
 
 import (
+	"fmt"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
 	"github.com/pratts/tts-study-assistant/backend/internal/services"
 	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
 )
 
 type UserHandler struct {
-	userService *services.UserService
+	userService     *services.UserService
+	authService     *services.AuthService
+	deletionService *services.AccountDeletionService
 }
 
-func NewUserHandler() *UserHandler {
+func NewUserHandler(cfg *config.Config) *UserHandler {
 	return &UserHandler{
-		userService: services.NewUserService(),
+		userService:     services.NewUserService(cfg),
+		authService:     services.NewAuthService(cfg),
+		deletionService: services.NewAccountDeletionService(cfg),
 	}
 }
 
@@ -40,7 +47,12 @@ func (h *UserHandler) UpdateProfile(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	profile, err := h.userService.UpdateProfile(userID, &req)
+	keepSessionID := ""
+	if req.KeepCurrentSession {
+		keepSessionID, _ = c.Locals("session_id").(string)
+	}
+
+	profile, err := h.userService.UpdateProfile(userID, keepSessionID, &req, clientInfo(c))
 	if err != nil {
 		if err.Error() == "user not found" {
 			return utils.SendError(c, fiber.StatusNotFound, "User not found")
@@ -48,9 +60,21 @@ func (h *UserHandler) UpdateProfile(c *fiber.Ctx) error {
 		if err.Error() == "email already taken" {
 			return utils.SendError(c, fiber.StatusConflict, "Email already taken")
 		}
+		if err.Error() == "invalid revision limit" {
+			return utils.SendError(c, fiber.StatusBadRequest, fmt.Sprintf("Note revision limit must be between 1 and %d", services.MaxNoteRevisionLimit))
+		}
 		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to update profile")
 	}
 
+	// The password change revoked the caller's access token too
+	if req.Password != "" && keepSessionID != "" {
+		accessToken, err := h.authService.ReissueAccessToken(userID, keepSessionID)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusInternalServerError, "Failed to issue access token")
+		}
+		profile.AccessToken = accessToken
+	}
+
 	return utils.SendSuccess(c, "Profile updated successfully", profile)
 }
 
@@ -58,8 +82,9 @@ func (h *UserHandler) UpdateProfile(c *fiber.Ctx) error {
 func (h *UserHandler) UpdatePassword(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	var req struct {
-		OldPassword string `json:"old_password"`
-		NewPassword string `json:"new_password"`
+		OldPassword        string `json:"old_password"`
+		NewPassword        string `json:"new_password"`
+		KeepCurrentSession bool   `json:"keep_current_session"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
@@ -67,11 +92,53 @@ func (h *UserHandler) UpdatePassword(c *fiber.Ctx) error {
 	if req.OldPassword == "" || req.NewPassword == "" {
 		return utils.SendError(c, fiber.StatusBadRequest, "Old and new password are required")
 	}
-	if err := h.userService.UpdatePassword(userID, req.OldPassword, req.NewPassword); err != nil {
+	keepSessionID := ""
+	if req.KeepCurrentSession {
+		keepSessionID, _ = c.Locals("session_id").(string)
+	}
+	if err := h.userService.UpdatePassword(userID, keepSessionID, req.OldPassword, req.NewPassword, clientInfo(c)); err != nil {
 		if err.Error() == "incorrect password" {
 			return utils.SendError(c, fiber.StatusUnauthorized, "Incorrect old password")
 		}
 		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to update password")
 	}
+
+	// Every access token was revoked; give the kept session a new one
+	if keepSessionID != "" {
+		accessToken, err := h.authService.ReissueAccessToken(userID, keepSessionID)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusInternalServerError, "Failed to issue access token")
+		}
+		return utils.SendSuccess(c, "Password updated successfully", fiber.Map{"access_token": accessToken})
+	}
 	return utils.SendSuccess(c, "Password updated successfully")
 }
+
+// DeleteAccount handles scheduling the user's account for deletion
+func (h *UserHandler) DeleteAccount(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	var req services.DeleteAccountRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Password == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Password is required")
+	}
+
+	response, err := h.deletionService.RequestDeletion(userID, &req, clientInfo(c))
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			return utils.SendError(c, fiber.StatusNotFound, "User not found")
+		case "invalid password":
+			return utils.SendError(c, fiber.StatusUnauthorized, "Incorrect password")
+		case "invalid two-factor code":
+			return utils.SendError(c, fiber.StatusUnauthorized, "Invalid two-factor code", "INVALID_2FA_CODE")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to delete account")
+	}
+
+	return utils.SendSuccess(c, "Account scheduled for deletion. Log in before then to cancel.", response)
+}
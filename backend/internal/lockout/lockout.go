@@ -0,0 +1,96 @@
+// Package lockout slows down and temporarily locks out repeated failed
+// attempts, such as password guesses against one account or from one IP.
+package lockout
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Policy decides when failures start to lock a key out and for how long
+type Policy struct {
+	// Window is the sliding window failures are counted in
+	Window time.Duration
+	// Threshold is the number of failures within Window that triggers a lockout
+	Threshold int
+	// BaseDelay is the first lockout; each further failure doubles it
+	BaseDelay time.Duration
+	// MaxDelay caps the lockout
+	MaxDelay time.Duration
+}
+
+// LockedError reports that a key is locked out
+type LockedError struct {
+	Key        string
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("account locked, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// Limiter applies a Policy to failures recorded in a Store
+type Limiter struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+// New returns a limiter for the policy backed by store
+func New(store Store, policy Policy) *Limiter {
+	return &Limiter{store: store, policy: policy, now: time.Now}
+}
+
+// Check returns a *LockedError if key is locked out
+func (l *Limiter) Check(key string) error {
+	now := l.now()
+	failures, err := l.store.Failures(key, now.Add(-l.policy.Window))
+	if err != nil {
+		return err
+	}
+	if retryAfter := l.lockedFor(failures, now); retryAfter > 0 {
+		return &LockedError{Key: key, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Fail records a failure for key. It returns a *LockedError when this
+// failure locks the key out.
+func (l *Limiter) Fail(key string) error {
+	now := l.now()
+	if err := l.store.Add(key, now, l.policy.Window); err != nil {
+		return err
+	}
+	failures, err := l.store.Failures(key, now.Add(-l.policy.Window))
+	if err != nil {
+		return err
+	}
+	if retryAfter := l.lockedFor(failures, now); retryAfter > 0 {
+		return &LockedError{Key: key, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Reset forgets the failures for key, e.g. after a successful login
+func (l *Limiter) Reset(key string) error {
+	return l.store.Reset(key)
+}
+
+// lockedFor returns how much longer the key stays locked given its failures
+// within the window, oldest first
+func (l *Limiter) lockedFor(failures []time.Time, now time.Time) time.Duration {
+	if len(failures) < l.policy.Threshold {
+		return 0
+	}
+	excess := len(failures) - l.policy.Threshold
+	delay := l.policy.MaxDelay
+	if excess < 32 {
+		delay = time.Duration(float64(l.policy.BaseDelay) * math.Pow(2, float64(excess)))
+	}
+	if delay > l.policy.MaxDelay || delay <= 0 {
+		delay = l.policy.MaxDelay
+	}
+	last := failures[len(failures)-1]
+	return last.Add(delay).Sub(now)
+}
@@ -0,0 +1,75 @@
+package lockout
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(now *time.Time) *Limiter {
+	l := New(NewMemoryStore(), Policy{
+		Window:    15 * time.Minute,
+		Threshold: 3,
+		BaseDelay: 30 * time.Second,
+		MaxDelay:  5 * time.Minute,
+	})
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestLockoutBackoff(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := newTestLimiter(&now)
+
+	for i := 0; i < 2; i++ {
+		if err := l.Fail("user"); err != nil {
+			t.Fatalf("failure %d locked out early: %v", i+1, err)
+		}
+	}
+
+	// The third failure locks for the base delay, the next ones double it
+	for i, want := range []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute} {
+		var locked *LockedError
+		if err := l.Fail("user"); !errors.As(err, &locked) || locked.RetryAfter != want {
+			t.Fatalf("failure %d: got %v, want lock of %s", i+3, err, want)
+		}
+	}
+
+	if err := l.Check("other"); err != nil {
+		t.Errorf("unrelated key locked: %v", err)
+	}
+
+	now = now.Add(5*time.Minute + time.Second)
+	if err := l.Check("user"); err != nil {
+		t.Errorf("still locked after the lockout ended: %v", err)
+	}
+}
+
+func TestLockoutSlidingWindow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := newTestLimiter(&now)
+
+	l.Fail("user")
+	l.Fail("user")
+	// The first two failures fall out of the window
+	now = now.Add(16 * time.Minute)
+	if err := l.Fail("user"); err != nil {
+		t.Errorf("old failures counted: %v", err)
+	}
+}
+
+func TestLockoutReset(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := newTestLimiter(&now)
+
+	for i := 0; i < 3; i++ {
+		l.Fail("user")
+	}
+	if err := l.Check("user"); err == nil {
+		t.Fatal("not locked after reaching the threshold")
+	}
+	l.Reset("user")
+	if err := l.Check("user"); err != nil {
+		t.Errorf("locked after reset: %v", err)
+	}
+}
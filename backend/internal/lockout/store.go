@@ -0,0 +1,76 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+// Store keeps failure timestamps per key. MemoryStore suits a single server;
+// running several servers needs a shared implementation, e.g. on Redis.
+type Store interface {
+	// Add records a failure at t. Failures older than window may be dropped.
+	Add(key string, t time.Time, window time.Duration) error
+	// Failures returns the failures after since, oldest first
+	Failures(key string, since time.Time) ([]time.Time, error)
+	// Reset drops all failures for key
+	Reset(key string) error
+}
+
+// MemoryStore is an in-process Store
+type MemoryStore struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time
+	window   time.Duration // longest window seen, used when sweeping
+	lastGC   time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{failures: make(map[string][]time.Time)}
+}
+
+func (s *MemoryStore) Add(key string, t time.Time, window time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if window > s.window {
+		s.window = window
+	}
+	s.failures[key] = append(prune(s.failures[key], t.Add(-window)), t)
+
+	// Sweep keys that stopped failing now and then so memory stays bounded
+	if t.Sub(s.lastGC) > s.window {
+		for k, failures := range s.failures {
+			if len(prune(failures, t.Add(-s.window))) == 0 {
+				delete(s.failures, k)
+			}
+		}
+		s.lastGC = t
+	}
+	return nil
+}
+
+func (s *MemoryStore) Failures(key string, since time.Time) ([]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recent := prune(s.failures[key], since)
+	return append([]time.Time(nil), recent...), nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+	return nil
+}
+
+// prune drops failures at or before since. Failures are kept in order.
+func prune(failures []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(failures) && !failures[i].After(since) {
+		i++
+	}
+	return failures[i:]
+}
@@ -0,0 +1,23 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes each message as an .eml file to an outbox directory
+// instead of sending it
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), messageID())
+	return os.WriteFile(filepath.Join(m.dir, name), msg.encode(m.from), 0o600)
+}
@@ -1,21 +1,36 @@
 package middleware
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/services"
+	"github.com/pratts/tts-study-assistant/backend/internal/signing"
 	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
 )
 
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid,omitempty"`
+	Source    string `json:"src,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Version   int    `json:"ver"`
+	Type      string `json:"typ,omitempty"` // Empty for access tokens
 	jwt.RegisteredClaims
 }
 
+// AuthMiddleware accepts either a JWT access token or a personal access
+// token. Requests made with a personal access token carry their scopes in
+// c.Locals("scopes"), so every protected route needs either RequireScope or
+// RequireSession; TestProtectedRoutesCheckTokenScopes fails for one that
+// has neither.
 func AuthMiddleware(cfg *config.Config) fiber.Handler {
+	patService := services.NewPersonalAccessTokenService()
+
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -30,10 +45,22 @@ func AuthMiddleware(cfg *config.Config) fiber.Handler {
 		// Extract the token
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
+		if strings.HasPrefix(tokenString, services.PersonalAccessTokenPrefix) {
+			token, err := patService.Authenticate(tokenString)
+			if err != nil {
+				return utils.SendErrorWithCode(c, fiber.StatusUnauthorized, "Invalid or expired token", "INVALID_TOKEN")
+			}
+			c.Locals("user_id", token.UserID)
+			c.Locals("email", token.Email)
+			c.Locals("token_id", token.TokenID)
+			c.Locals("scopes", token.Scopes)
+			c.Locals("source", "api")
+			c.Locals("role", token.Role)
+			return c.Next()
+		}
+
 		// Parse and validate the token
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWTSecret), nil
-		})
+		token, err := signing.Keys.Parse(tokenString, &Claims{})
 
 		if err != nil {
 			if strings.Contains(err.Error(), "token is expired") {
@@ -46,15 +73,57 @@ func AuthMiddleware(cfg *config.Config) fiber.Handler {
 		}
 
 		// Extract claims
+		// Access tokens have no type and no audience
 		claims, ok := token.Claims.(*Claims)
-		if !ok {
+		if !ok || claims.Type != "" || len(claims.Audience) > 0 {
 			return utils.SendErrorWithCode(c, fiber.StatusUnauthorized, "Invalid token claims", "TOKEN_EXPIRED")
 		}
 
+		if services.AccessTokenRevoked(claims.UserID, claims.SessionID, claims.Version) {
+			return utils.SendErrorWithCode(c, fiber.StatusUnauthorized, "Token has been revoked", "TOKEN_EXPIRED")
+		}
+
 		// Set user info in context
 		c.Locals("user_id", claims.UserID)
 		c.Locals("email", claims.Email)
+		c.Locals("session_id", claims.SessionID)
+		c.Locals("source", claims.Source)
+		c.Locals("role", claims.Role)
 
 		return c.Next()
 	}
 }
+
+// RequireScope lets personal access tokens through only when they have the
+// scope. JWT sessions have full access.
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if HasScope(c, scope) {
+			return c.Next()
+		}
+		return SendMissingScope(c, scope)
+	}
+}
+
+// HasScope reports whether the request may use the scope, for handlers
+// whose scope depends on the request body
+func HasScope(c *fiber.Ctx, scope string) bool {
+	scopes, ok := c.Locals("scopes").([]string)
+	return !ok || slices.Contains(scopes, scope)
+}
+
+// SendMissingScope rejects a personal access token without the scope
+func SendMissingScope(c *fiber.Ctx, scope string) error {
+	return utils.SendErrorWithCode(c, fiber.StatusForbidden, "Token is missing the "+scope+" scope", "INSUFFICIENT_SCOPE")
+}
+
+// RequireSession rejects personal access tokens, for routes that manage the
+// account itself
+func RequireSession() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("scopes").([]string); ok {
+			return utils.SendErrorWithCode(c, fiber.StatusForbidden, "Personal access tokens cannot be used for this endpoint", "INSUFFICIENT_SCOPE")
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+// RequireRole lets the request through only when the caller's role, taken
+// from the access token, is one of roles. It must run after AuthMiddleware.
+// A role change bumps the user's token version, so a stale role claim stops
+// working as soon as the change is made.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		if role == "" || !slices.Contains(roles, role) {
+			return utils.SendErrorWithCode(c, fiber.StatusForbidden, "You do not have permission to access this resource", "FORBIDDEN")
+		}
+		return c.Next()
+	}
+}
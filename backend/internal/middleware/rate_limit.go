@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/pratts/tts-study-assistant/backend/pkg/utils"
+)
+
+// RateLimitByIP allows max requests per client IP within expiration
+func RateLimitByIP(max int, expiration time.Duration) fiber.Handler {
+	return rateLimit(max, expiration, func(c *fiber.Ctx) string {
+		return c.IP()
+	})
+}
+
+// RateLimitByUser allows max requests per authenticated user within
+// expiration. It must run after AuthMiddleware.
+func RateLimitByUser(max int, expiration time.Duration) fiber.Handler {
+	return rateLimit(max, expiration, func(c *fiber.Ctx) string {
+		if userID, ok := c.Locals("user_id").(string); ok {
+			return userID
+		}
+		return c.IP()
+	})
+}
+
+func rateLimit(max int, expiration time.Duration, key func(c *fiber.Ctx) string) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:          max,
+		Expiration:   expiration,
+		KeyGenerator: key,
+		LimitReached: func(c *fiber.Ctx) error {
+			return utils.SendErrorWithCode(c, fiber.StatusTooManyRequests, "Too many requests, please try again later", "RATE_LIMITED")
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Action token purposes
+const (
+	ActionVerifyEmail   = "verify_email"
+	ActionResetPassword = "reset_password"
+	ActionChangeEmail   = "change_email"
+)
+
+// ActionToken backs a signed, single-use link sent by email. The link carries
+// the token ID and an HMAC over the fields below; nothing secret is stored.
+type ActionToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	Purpose   string    `gorm:"not null"`
+	Email     string    `gorm:"not null"` // Address the link was sent to
+	ExpiresAt time.Time `gorm:"not null;index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (at *ActionToken) BeforeCreate(tx *gorm.DB) error {
+	at.ID = uuid.New()
+	return nil
+}
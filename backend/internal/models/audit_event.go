@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Audit event outcomes
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// ErrAuditEventImmutable is returned when code tries to change or delete an
+// audit event
+var ErrAuditEventImmutable = errors.New("audit events are append-only")
+
+// AuditEvent records a security-relevant account event. UserID is the account
+// the event is about and ActorID who caused it; ActorID is empty when the
+// actor is unknown, such as a failed login. Events are append-only.
+type AuditEvent struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID    *uuid.UUID     `gorm:"type:uuid;index:idx_audit_events_user_created,priority:1"`
+	ActorID   *uuid.UUID     `gorm:"type:uuid"`
+	Event     string         `gorm:"not null;index"`
+	Outcome   string         `gorm:"not null;default:success;index"`
+	Source    string         `gorm:"type:text"` // web, extension or api
+	IPAddress string         `gorm:"type:text"`
+	UserAgent string         `gorm:"type:text"`
+	Metadata  datatypes.JSON `gorm:"type:jsonb"`
+	CreatedAt time.Time      `gorm:"index;index:idx_audit_events_user_created,priority:2"`
+}
+
+func (e *AuditEvent) BeforeCreate(tx *gorm.DB) error {
+	e.ID = uuid.New()
+	return nil
+}
+
+func (e *AuditEvent) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditEventImmutable
+}
+
+func (e *AuditEvent) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditEventImmutable
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Collection groups notes, e.g. for a course spanning many sites.
+// Collections nest through ParentID; Position orders siblings.
+type Collection struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index"`
+	ParentID    *uuid.UUID `gorm:"type:uuid;index"` // Nil for a top-level collection
+	Name        string     `gorm:"not null"`
+	Description string
+	Position    int `gorm:"not null;default:0"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	User User `gorm:"foreignKey:UserID"`
+}
+
+func (c *Collection) BeforeCreate(tx *gorm.DB) error {
+	c.ID = uuid.New()
+	return nil
+}
+
+// CollectionNote puts a note in a collection. A note can be in several
+// collections; Position orders the notes within one.
+type CollectionNote struct {
+	CollectionID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	NoteID       uuid.UUID `gorm:"type:uuid;primaryKey;index"`
+	Position     int       `gorm:"not null;default:0"`
+	CreatedAt    time.Time
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// DeletionReceipt records that an account was purged. It keeps no personal
+// data: the email is stored as a SHA-256 hash so a deletion can be confirmed
+// for someone who knows the address.
+type DeletionReceipt struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
+	EmailHash   string    `gorm:"not null;index"`
+	RequestedAt *time.Time
+	PurgedAt    time.Time      `gorm:"not null"`
+	Deleted     datatypes.JSON `gorm:"type:jsonb"` // Rows deleted per table
+	CreatedAt   time.Time
+}
+
+func (r *DeletionReceipt) BeforeCreate(tx *gorm.DB) error {
+	r.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Device code statuses
+const (
+	DeviceCodePending  = "pending"
+	DeviceCodeApproved = "approved"
+	DeviceCodeDenied   = "denied"
+	DeviceCodeConsumed = "consumed"
+)
+
+// DeviceCode is a pending device authorization. The extension holds the
+// device code (stored here only as a hash) and polls with it; the user types
+// the short user code into the signed-in web app to approve the request.
+type DeviceCode struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	DeviceCodeHash string     `gorm:"uniqueIndex;not null"`
+	UserCode       string     `gorm:"uniqueIndex;not null"`
+	UserID         *uuid.UUID `gorm:"type:uuid"`
+	Status         string     `gorm:"not null;default:'pending'"`
+	DeviceInfo     string
+	UserAgent      string
+	IPAddress      string
+	Interval       int `gorm:"not null"`
+	LastPolledAt   *time.Time
+	ExpiresAt      time.Time `gorm:"not null;index"`
+	CreatedAt      time.Time
+}
+
+func (dc *DeviceCode) BeforeCreate(tx *gorm.DB) error {
+	dc.ID = uuid.New()
+	return nil
+}
@@ -19,8 +19,21 @@ type Note struct {
 	Summary     string         `gorm:"type:text" json:"summary,omitempty"`
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"` // Set while the note is in the trash
 
-	User User `gorm:"foreignKey:UserID"`
+	// Set while the note waits for the summary job. Failed attempts push it
+	// back; the note leaves the queue after too many of them.
+	SummaryRequestedAt *time.Time `gorm:"index"`
+	SummaryAttempts    int        `gorm:"not null;default:0"` // Failed attempts since it was queued
+
+	// Fingerprint of the content for finding duplicates; see NoteFingerprintBand
+	ContentHash     string // Empty for content without words
+	MinHash         []byte `gorm:"type:bytea"`
+	FingerprintedAt *time.Time
+
+	User        User         `gorm:"foreignKey:UserID"`
+	Tags        []Tag        `gorm:"many2many:note_tags"`
+	Collections []Collection `gorm:"many2many:collection_notes"`
 }
 
 func (n *Note) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,11 @@
+package models
+
+import "github.com/google/uuid"
+
+// NoteFingerprintBand is one band of a note's MinHash signature. Notes that
+// share a band key are compared when looking for near-duplicates.
+type NoteFingerprintBand struct {
+	NoteID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Band   int       `gorm:"primaryKey;autoIncrement:false"`
+	Key    int64     `gorm:"not null;index"`
+}
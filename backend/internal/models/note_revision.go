@@ -0,0 +1,42 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ErrNoteRevisionImmutable is returned when code tries to change a revision
+var ErrNoteRevisionImmutable = errors.New("note revisions cannot be changed")
+
+// NoteRevision is a snapshot of a note after a change. Number counts up per
+// note from 1; ChangedFields lists what the change touched. Revisions are
+// never changed, only pruned when a note has more than its owner keeps.
+type NoteRevision struct {
+	ID            uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	NoteID        uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_note_revisions_note_number,priority:1"`
+	Number        int            `gorm:"not null;uniqueIndex:idx_note_revisions_note_number,priority:2"`
+	AuthorID      uuid.UUID      `gorm:"type:uuid;not null"`
+	ChangedFields datatypes.JSON `gorm:"type:jsonb"`
+	RevertedFrom  *int           // Set when the change reverted to this older revision
+
+	Content     string `gorm:"type:text;not null"`
+	SourceURL   string
+	SourceTitle string
+	Domain      string
+	Metadata    datatypes.JSON `gorm:"type:jsonb"`
+	Tags        datatypes.JSON `gorm:"type:jsonb"` // Tag names
+	CreatedAt   time.Time
+}
+
+func (r *NoteRevision) BeforeCreate(tx *gorm.DB) error {
+	r.ID = uuid.New()
+	return nil
+}
+
+func (r *NoteRevision) BeforeUpdate(tx *gorm.DB) error {
+	return ErrNoteRevisionImmutable
+}
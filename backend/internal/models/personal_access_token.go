@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Personal access token scopes
+const (
+	ScopeNotesRead      = "notes:read"
+	ScopeNotesWrite     = "notes:write"
+	ScopeSummariesWrite = "summaries:write"
+)
+
+// Scopes lists every scope a personal access token can be granted
+var Scopes = []string{ScopeNotesRead, ScopeNotesWrite, ScopeSummariesWrite}
+
+// PersonalAccessToken lets scripts call the API without a web session. Only
+// a hash of the token is stored; Prefix is kept so users can tell tokens
+// apart.
+type PersonalAccessToken struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	Name       string    `gorm:"not null"`
+	TokenHash  string    `gorm:"uniqueIndex;not null"`
+	Prefix     string    `gorm:"not null"`
+	Scopes     string    `gorm:"not null"` // Space-separated, as in OAuth
+	ExpiresAt  time.Time `gorm:"not null;index"`
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+
+	User User `gorm:"foreignKey:UserID"`
+}
+
+func (pat *PersonalAccessToken) BeforeCreate(tx *gorm.DB) error {
+	pat.ID = uuid.New()
+	return nil
+}
@@ -9,7 +9,7 @@ import (
 
 type RefreshToken struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	Token     string    `gorm:"uniqueIndex;not null"`
+	TokenHash string    `gorm:"uniqueIndex;not null"` // Hex SHA-256 of the token
 	UserID    uuid.UUID `gorm:"type:uuid;not null"`
 	ExpiresAt time.Time `gorm:"not null"`
 	CreatedAt time.Time
@@ -19,10 +19,23 @@ type RefreshToken struct {
 	LastUsedAt *time.Time
 	DeviceInfo string
 
+	// Session details shown on the active sessions list
+	Label     string
+	UserAgent string
+	IPAddress string
+
+	// Every login starts a token family; rotations stay in the same family.
+	// RotatedAt is set once the token has been exchanged for a new one.
+	FamilyID  uuid.UUID `gorm:"type:uuid;index"`
+	RotatedAt *time.Time
+
 	User User `gorm:"foreignKey:UserID"`
 }
 
 func (rt *RefreshToken) BeforeCreate(tx *gorm.DB) error {
 	rt.ID = uuid.New()
+	if rt.FamilyID == uuid.Nil {
+		rt.FamilyID = rt.ID
+	}
 	return nil
 }
@@ -7,6 +7,17 @@ import (
 	"gorm.io/gorm"
 )
 
+// User roles. Support staff can look at users and stats through the admin
+// API but cannot change anything.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+// Roles lists the valid user roles
+var Roles = []string{RoleUser, RoleSupport, RoleAdmin}
+
 type User struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
 	Email     string    `gorm:"uniqueIndex;not null"`
@@ -15,8 +26,35 @@ type User struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 
+	Role string `gorm:"not null;default:user;index"`
+
+	// Disabled accounts cannot log in or use any token
+	DisabledAt *time.Time
+
+	// Bumped to invalidate every access token issued before the change
+	TokenVersion int `gorm:"not null;default:0"`
+
+	EmailVerifiedAt *time.Time
+
+	// Two-factor authentication. The secret is stored encrypted and only
+	// takes effect once TOTPEnabledAt is set. TOTPLastStep is the time step
+	// of the last accepted code, so a code cannot be used twice.
+	TOTPSecret    string
+	TOTPEnabledAt *time.Time
+	TOTPLastStep  int64 `gorm:"not null;default:0"`
+
+	// Set while the account is waiting to be deleted. Logging in before
+	// DeletionScheduledAt cancels the deletion; after it, the account and
+	// all of its data are purged.
+	DeletionRequestedAt *time.Time
+	DeletionScheduledAt *time.Time `gorm:"index"`
+
+	// How many revisions are kept per note; older ones are pruned
+	NoteRevisionLimit int `gorm:"not null;default:50"`
+
 	Notes         []Note         `gorm:"foreignKey:UserID"`
 	RefreshTokens []RefreshToken `gorm:"foreignKey:UserID"`
+	RecoveryCodes []RecoveryCode `gorm:"foreignKey:UserID"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
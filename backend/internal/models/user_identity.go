@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserIdentity links a user to an account at an external identity provider,
+// identified by the provider's issuer and subject.
+type UserIdentity struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	Issuer      string    `gorm:"not null;uniqueIndex:idx_user_identities_issuer_subject"`
+	Subject     string    `gorm:"not null;uniqueIndex:idx_user_identities_issuer_subject"`
+	Email       string    // Email the provider reported at the last login
+	CreatedAt   time.Time
+	LastLoginAt *time.Time
+
+	User User `gorm:"foreignKey:UserID"`
+}
+
+func (ui *UserIdentity) BeforeCreate(tx *gorm.DB) error {
+	ui.ID = uuid.New()
+	return nil
+}
+
+// OIDCLoginState holds what is needed to finish a single sign-on login: the
+// PKCE verifier and nonce never leave the server. Rows are looked up by a
+// hash of the state parameter and deleted when used.
+type OIDCLoginState struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	StateHash    string    `gorm:"uniqueIndex;not null"`
+	CodeVerifier string    `gorm:"not null"`
+	Nonce        string    `gorm:"not null"`
+	Source       string    `gorm:"not null;default:'web'"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	CreatedAt    time.Time
+}
+
+func (s *OIDCLoginState) BeforeCreate(tx *gorm.DB) error {
+	s.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,204 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIdP is a minimal OpenID provider that issues one authorization code
+type mockIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	clientID  string
+	code      string
+	challenge string // PKCE challenge sent with the authorization request
+	nonce     string
+	claims    jwt.MapClaims // overrides for the issued ID token
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, clientID: "test-client", code: "test-code"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key-1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		clientID, secret, _ := r.BasicAuth()
+		if clientID != idp.clientID || secret != "test-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		if r.Form.Get("code") != idp.code || S256Challenge(r.Form.Get("code_verifier")) != idp.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     idp.idToken(t),
+		})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *mockIdP) idToken(t *testing.T) string {
+	claims := jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"sub":            "user-123",
+		"aud":            idp.clientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          idp.nonce,
+		"email":          "ada@example.com",
+		"email_verified": "true",
+		"name":           "Ada",
+	}
+	for k, v := range idp.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// login runs the authorization code flow against the mock provider
+func (idp *mockIdP) login(t *testing.T, p *Provider) (*IDTokenClaims, error) {
+	verifier, _ := GenerateVerifier()
+	state, _ := GenerateState()
+	nonce, _ := GenerateState()
+
+	authURL, err := url.Parse(p.AuthCodeURL(state, nonce, S256Challenge(verifier)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authURL.Query()
+	if query.Get("state") != state || query.Get("code_challenge_method") != "S256" || !strings.Contains(query.Get("scope"), "openid") {
+		t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+	idp.challenge = query.Get("code_challenge")
+	idp.nonce = query.Get("nonce")
+
+	ctx := context.Background()
+	token, err := p.Exchange(ctx, idp.code, verifier)
+	if err != nil {
+		return nil, err
+	}
+	return p.VerifyIDToken(ctx, token.IDToken, nonce)
+}
+
+func newTestProvider(t *testing.T, idp *mockIdP) *Provider {
+	p, err := NewProvider(context.Background(), Config{
+		IssuerURL:    idp.server.URL,
+		ClientID:     "test-client",
+		ClientSecret: "test-secret",
+		RedirectURL:  "http://localhost:5173/sso/callback",
+		Scopes:       []string{"email", "profile"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp := newMockIdP(t)
+	p := newTestProvider(t, idp)
+
+	claims, err := idp.login(t, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-123" || claims.Email != "ada@example.com" || !bool(claims.EmailVerified) {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+}
+
+func TestIDTokenValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+	}{
+		{"wrong audience", jwt.MapClaims{"aud": "other-client"}},
+		{"wrong issuer", jwt.MapClaims{"iss": "https://evil.example.com"}},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}},
+		{"wrong nonce", jwt.MapClaims{"nonce": "replayed"}},
+		{"missing subject", jwt.MapClaims{"sub": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			idp.claims = tt.claims
+			p := newTestProvider(t, idp)
+			if _, err := idp.login(t, p); err == nil {
+				t.Error("invalid ID token accepted")
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	idp := newMockIdP(t)
+	p := newTestProvider(t, idp)
+	idp.challenge = S256Challenge("the real verifier")
+
+	if _, err := p.Exchange(context.Background(), idp.code, "a different verifier"); err == nil {
+		t.Error("code exchanged without the matching PKCE verifier")
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 "https://evil.example.com",
+			"authorization_endpoint": "https://evil.example.com/authorize",
+			"token_endpoint":         "https://evil.example.com/token",
+			"jwks_uri":               "https://evil.example.com/jwks",
+		})
+	}))
+	defer server.Close()
+
+	if _, err := NewProvider(context.Background(), Config{IssuerURL: server.URL, ClientID: "test-client"}); err == nil {
+		t.Error("provider with a mismatched issuer accepted")
+	}
+}
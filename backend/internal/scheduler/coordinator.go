@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Coordinator decides which server runs a scheduled job. LocalCoordinator
+// suits a single server; PostgresCoordinator works across replicas sharing
+// a database.
+type Coordinator interface {
+	// Acquire claims the run of job scheduled at slot. ok is false when the
+	// job is still running elsewhere or the slot has already been run. When
+	// ok is true, release must be called once the run is over.
+	Acquire(ctx context.Context, job string, slot time.Time) (release func(), ok bool, err error)
+}
+
+// LocalCoordinator coordinates jobs within one process
+type LocalCoordinator struct {
+	mu       sync.Mutex
+	running  map[string]bool
+	lastSlot map[string]time.Time
+}
+
+// NewLocalCoordinator returns an in-process coordinator
+func NewLocalCoordinator() *LocalCoordinator {
+	return &LocalCoordinator{
+		running:  make(map[string]bool),
+		lastSlot: make(map[string]time.Time),
+	}
+}
+
+func (c *LocalCoordinator) Acquire(ctx context.Context, job string, slot time.Time) (func(), bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.running[job] || !slot.After(c.lastSlot[job]) {
+		return nil, false, nil
+	}
+	c.running[job] = true
+	c.lastSlot[job] = slot
+
+	release := func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.running, job)
+	}
+	return release, true, nil
+}
@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"log"
+	"time"
+)
+
+// PostgresCoordinator coordinates jobs between servers sharing a Postgres
+// database. A session-level advisory lock keeps a job from running on two
+// servers at once, and the scheduler_runs table records the last slot each
+// job ran for, so a server whose clock or jitter lags behind does not run
+// the same slot again after the first one has finished.
+type PostgresCoordinator struct {
+	db *sql.DB
+}
+
+// NewPostgresCoordinator creates the scheduler_runs table if needed
+func NewPostgresCoordinator(db *sql.DB) (*PostgresCoordinator, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS scheduler_runs (
+		job TEXT PRIMARY KEY,
+		last_slot TIMESTAMPTZ NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	return &PostgresCoordinator{db: db}, nil
+}
+
+func (c *PostgresCoordinator) Acquire(ctx context.Context, job string, slot time.Time) (func(), bool, error) {
+	// Advisory locks belong to a database session, so the lock and unlock
+	// must go through the same connection rather than the pool
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := lockKey(job)
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if !locked {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	release := func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			log.Printf("Job %s: failed to release lock: %v", job, err)
+		}
+		conn.Close()
+	}
+
+	result, err := conn.ExecContext(ctx, `INSERT INTO scheduler_runs (job, last_slot) VALUES ($1, $2)
+		ON CONFLICT (job) DO UPDATE SET last_slot = EXCLUDED.last_slot, updated_at = now()
+		WHERE scheduler_runs.last_slot < EXCLUDED.last_slot`, job, slot)
+	if err != nil {
+		release()
+		return nil, false, err
+	}
+	if claimed, _ := result.RowsAffected(); claimed == 0 {
+		release()
+		return nil, false, nil
+	}
+	return release, true, nil
+}
+
+// lockKey maps a job name to an advisory lock key. The prefix keeps the keys
+// apart from advisory locks taken for other reasons.
+func lockKey(job string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("scheduler:" + job))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,239 @@
+// Package scheduler runs periodic background jobs on cron-like schedules.
+// When several servers run the same jobs, a Coordinator makes sure each
+// scheduled run happens on only one of them.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a job run when the job does not set its own timeout
+const DefaultTimeout = 5 * time.Minute
+
+// Job is a unit of periodic work
+type Job struct {
+	// Name identifies the job across servers, so it must be unique
+	Name string
+	// Schedule is a cron expression or descriptor, see Parse. It is
+	// evaluated in UTC.
+	Schedule string
+	// Jitter delays each run by a random duration up to this long, so jobs
+	// sharing a schedule do not all hit the database at once
+	Jitter time.Duration
+	// Timeout cancels the context passed to Run, DefaultTimeout when zero
+	Timeout time.Duration
+	// Run does the work. It should return once ctx is done.
+	Run func(ctx context.Context) error
+}
+
+// JobStatus is a snapshot of a job as seen by this server
+type JobStatus struct {
+	Name         string
+	Schedule     string
+	Running      bool
+	NextRun      time.Time
+	LastRun      time.Time // Start of the last run on this server
+	LastDuration time.Duration
+	LastError    string
+	LastSuccess  time.Time
+	Runs         int // Runs started on this server
+	Failures     int // Runs that returned an error, panicked or timed out
+	Skipped      int // Scheduled runs left to another server
+}
+
+// Scheduler runs jobs on their schedules
+type Scheduler struct {
+	coordinator Coordinator
+
+	mu      sync.Mutex
+	jobs    []*job
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+type job struct {
+	Job
+	schedule Schedule
+
+	mu     sync.Mutex
+	status JobStatus
+}
+
+// New returns a scheduler that claims runs through coordinator
+func New(coordinator Coordinator) *Scheduler {
+	return &Scheduler{coordinator: coordinator}
+}
+
+// Add registers a job. Jobs must be added before Start.
+func (s *Scheduler) Add(j Job) error {
+	if j.Name == "" || j.Run == nil {
+		return errors.New("job needs a name and a run function")
+	}
+	schedule, err := Parse(j.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", j.Name, err)
+	}
+	if j.Timeout <= 0 {
+		j.Timeout = DefaultTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return errors.New("scheduler already started")
+	}
+	for _, existing := range s.jobs {
+		if existing.Name == j.Name {
+			return fmt.Errorf("job %s already registered", j.Name)
+		}
+	}
+	s.jobs = append(s.jobs, &job{
+		Job:      j,
+		schedule: schedule,
+		status:   JobStatus{Name: j.Name, Schedule: j.Schedule},
+	})
+	return nil
+}
+
+// Start runs every job on its schedule until ctx is done or Stop is called
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.loop(ctx, j)
+		}()
+	}
+	log.Printf("Scheduler started with %d jobs", len(s.jobs))
+}
+
+// Stop cancels running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+}
+
+// Status returns the status of every job, in the order they were added
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, len(s.jobs))
+	for i, j := range s.jobs {
+		j.mu.Lock()
+		statuses[i] = j.status
+		j.mu.Unlock()
+	}
+	return statuses
+}
+
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	for {
+		slot := j.schedule.Next(time.Now().UTC())
+		if slot.IsZero() {
+			log.Printf("Job %s has no upcoming runs", j.Name)
+			return
+		}
+		j.update(func(status *JobStatus) { status.NextRun = slot })
+
+		delay := time.Until(slot)
+		if j.Jitter > 0 {
+			delay += rand.N(j.Jitter)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.run(ctx, j, slot)
+	}
+}
+
+// run claims the scheduled slot and, if this server got it, runs the job
+func (s *Scheduler) run(ctx context.Context, j *job, slot time.Time) {
+	release, ok, err := s.coordinator.Acquire(ctx, j.Name, slot)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Job %s: failed to claim run: %v", j.Name, err)
+			j.update(func(status *JobStatus) {
+				status.Failures++
+				status.LastError = "claim run: " + err.Error()
+			})
+		}
+		return
+	}
+	if !ok {
+		j.update(func(status *JobStatus) { status.Skipped++ })
+		return
+	}
+	defer release()
+
+	start := time.Now()
+	j.update(func(status *JobStatus) {
+		status.Running = true
+		status.LastRun = start
+		status.Runs++
+	})
+
+	runCtx, cancel := context.WithTimeout(ctx, j.Timeout)
+	err = j.safeRun(runCtx)
+	if err != nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", j.Timeout)
+	}
+	cancel()
+
+	duration := time.Since(start)
+	j.update(func(status *JobStatus) {
+		status.Running = false
+		status.LastDuration = duration
+		status.LastError = ""
+		if err != nil {
+			status.LastError = err.Error()
+			status.Failures++
+		} else {
+			status.LastSuccess = start
+		}
+	})
+	if err != nil {
+		log.Printf("Job %s failed after %s: %v", j.Name, duration.Round(time.Millisecond), err)
+	}
+}
+
+// safeRun keeps a panicking job from taking the server down
+func (j *job) safeRun(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return j.Run(ctx)
+}
+
+func (j *job) update(fn func(status *JobStatus)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.status)
+}
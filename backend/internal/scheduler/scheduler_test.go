@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRunsJobs(t *testing.T) {
+	s := New(NewLocalCoordinator())
+	var runs atomic.Int32
+	err := s.Add(Job{
+		Name:     "count",
+		Schedule: "@every 20ms",
+		Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Start(context.Background())
+	time.Sleep(110 * time.Millisecond)
+	s.Stop()
+
+	if n := runs.Load(); n < 2 {
+		t.Fatalf("job ran %d times, want at least 2", n)
+	}
+	status := s.Status()[0]
+	if status.Runs != int(runs.Load()) || status.Failures != 0 || status.LastSuccess.IsZero() {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+func TestSchedulerRunsEachSlotOnce(t *testing.T) {
+	// Two servers sharing a coordinator
+	coordinator := NewLocalCoordinator()
+	var runs atomic.Int32
+	servers := []*Scheduler{New(coordinator), New(coordinator)}
+	for _, s := range servers {
+		err := s.Add(Job{
+			Name:     "shared",
+			Schedule: "@every 50ms",
+			Run: func(ctx context.Context) error {
+				runs.Add(1)
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Start(context.Background())
+	}
+	time.Sleep(230 * time.Millisecond)
+
+	var started, skipped int
+	for _, s := range servers {
+		s.Stop()
+		status := s.Status()[0]
+		started += status.Runs
+		skipped += status.Skipped
+	}
+	// About four slots pass; each runs on one server and is skipped by the other
+	if started != int(runs.Load()) || started == 0 || started > 5 || skipped == 0 {
+		t.Errorf("got %d runs and %d skipped slots, want every slot run exactly once", started, skipped)
+	}
+}
+
+func TestSchedulerTimeoutAndPanic(t *testing.T) {
+	s := New(NewLocalCoordinator())
+	jobs := []Job{
+		{
+			Name:     "slow",
+			Schedule: "@every 20ms",
+			Timeout:  5 * time.Millisecond,
+			Run: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+		{
+			Name:     "broken",
+			Schedule: "@every 20ms",
+			Run: func(ctx context.Context) error {
+				panic("boom")
+			},
+		},
+		{
+			Name:     "failing",
+			Schedule: "@every 20ms",
+			Run: func(ctx context.Context) error {
+				return errors.New("database unavailable")
+			},
+		},
+	}
+	for _, job := range jobs {
+		if err := s.Add(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.Start(context.Background())
+	time.Sleep(70 * time.Millisecond)
+	statuses := s.Status()
+	s.Stop()
+
+	want := []string{"timed out after 5ms", "panic: boom", "database unavailable"}
+	for i, status := range statuses {
+		if status.Failures == 0 || status.LastError != want[i] || !status.LastSuccess.IsZero() {
+			t.Errorf("%s: unexpected status %+v", status.Name, status)
+		}
+	}
+}
+
+func TestSchedulerAddValidates(t *testing.T) {
+	s := New(NewLocalCoordinator())
+	run := func(ctx context.Context) error { return nil }
+
+	if err := s.Add(Job{Name: "job", Schedule: "@daily", Run: run}); err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range []Job{
+		{Name: "job", Schedule: "@daily", Run: run},
+		{Name: "other", Schedule: "every day", Run: run},
+		{Name: "", Schedule: "@daily", Run: run},
+		{Name: "norun", Schedule: "@daily"},
+	} {
+		if err := s.Add(job); err == nil {
+			t.Errorf("Add(%q, %q) succeeded, want an error", job.Name, job.Schedule)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/database"
+	"github.com/pratts/tts-study-assistant/backend/internal/mailer"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// AccountService handles the emailed account flows: email verification,
+// password reset and email change confirmation.
+type AccountService struct {
+	db     *gorm.DB
+	cfg    *config.Config
+	mailer mailer.Mailer
+}
+
+const (
+	verifyEmailTokenTTL   = 24 * time.Hour
+	resetPasswordTokenTTL = time.Hour
+	changeEmailTokenTTL   = 24 * time.Hour
+)
+
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+type TokenRequest struct {
+	Token string `json:"token"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"` // Pre-hashed password from UI
+}
+
+func NewAccountService(cfg *config.Config) *AccountService {
+	return &AccountService{
+		db:     database.DB,
+		cfg:    cfg,
+		mailer: mailer.New(cfg),
+	}
+}
+
+// SendVerificationEmail emails a verification link to the user's address
+func (s *AccountService) SendVerificationEmail(user *models.User) error {
+	token, err := issueActionToken(s.db, s.cfg.ActionTokenSecret, user.ID, models.ActionVerifyEmail, user.Email, verifyEmailTokenTTL)
+	if err != nil {
+		return err
+	}
+	s.send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening this link:\n\n%s\n\nThe link expires in 24 hours.\n",
+			user.Name, s.link("/verify-email", token)),
+	})
+	return nil
+}
+
+// ResendVerificationEmail sends a new verification link if the address
+// belongs to an unverified account. It reports success either way so the
+// endpoint cannot be used to discover accounts.
+func (s *AccountService) ResendVerificationEmail(email string) error {
+	var user models.User
+	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	return s.SendVerificationEmail(&user)
+}
+
+// VerifyEmail marks the address in the token as verified
+func (s *AccountService) VerifyEmail(signed string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		token, err := consumeActionToken(tx, s.cfg.ActionTokenSecret, signed, models.ActionVerifyEmail)
+		if err != nil {
+			return err
+		}
+		// The link only verifies the address it was sent to
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND email = ?", token.UserID, token.Email).
+			Update("email_verified_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidActionToken
+		}
+		return nil
+	})
+}
+
+// ForgotPassword emails a password reset link if an account exists for the
+// address. It reports success either way.
+func (s *AccountService) ForgotPassword(email string) error {
+	var user models.User
+	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := issueActionToken(s.db, s.cfg.ActionTokenSecret, user.ID, models.ActionResetPassword, user.Email, resetPasswordTokenTTL)
+	if err != nil {
+		return err
+	}
+	s.send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password for your account. If it was you, open this link to choose a new password:\n\n%s\n\nThe link expires in 1 hour. If you did not ask for this, you can ignore this email.\n",
+			user.Name, s.link("/reset-password", token)),
+	})
+	return nil
+}
+
+// ResetPassword sets a new password and signs the user out everywhere. The
+// token is only used up if the password is changed.
+func (s *AccountService) ResetPassword(req *ResetPasswordRequest, client ClientInfo) error {
+	passwordHash, err := hashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	var token *models.ActionToken
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		token, err = consumeActionToken(tx, s.cfg.ActionTokenSecret, req.Token, models.ActionResetPassword)
+		if err != nil {
+			return err
+		}
+
+		// Receiving the link also proves the user controls the address
+		updates := map[string]any{"password": passwordHash}
+		result := tx.Model(&models.User{}).Where("id = ? AND email = ?", token.UserID, token.Email).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidActionToken
+		}
+		err = tx.Model(&models.User{}).
+			Where("id = ? AND email_verified_at IS NULL", token.UserID).
+			Update("email_verified_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, token.UserID.String(), "")
+	})
+	if err != nil {
+		return err
+	}
+
+	// Emailed links are opened in the web app
+	client.Source = "web"
+	recordAuditEvent(s.db, token.UserID, AuditPasswordReset, client, nil)
+	return nil
+}
+
+// RequestEmailChange emails a confirmation link to the new address and a
+// notice to the current one. The email only changes once the link is opened.
+func (s *AccountService) RequestEmailChange(user *models.User, newEmail string, client ClientInfo) error {
+	token, err := issueActionToken(s.db, s.cfg.ActionTokenSecret, user.ID, models.ActionChangeEmail, newEmail, changeEmailTokenTTL)
+	if err != nil {
+		return err
+	}
+	recordAuditEvent(s.db, user.ID, AuditEmailChangeRequested, client, map[string]any{
+		"old_email": user.Email,
+		"new_email": newEmail,
+	})
+	s.send(mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen this link to use %s as the email address for your account:\n\n%s\n\nThe link expires in 24 hours.\n",
+			user.Name, newEmail, s.link("/confirm-email", token)),
+	})
+	s.send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to change the email address for your account to %s. If this was not you, change your password right away.\n",
+			user.Name, newEmail),
+	})
+	return nil
+}
+
+// ConfirmEmailChange applies the email change in the token. The token is
+// kept if the address was taken in the meantime.
+func (s *AccountService) ConfirmEmailChange(signed string, client ClientInfo) error {
+	var token *models.ActionToken
+	var user models.User
+	var oldEmail string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		token, err = consumeActionToken(tx, s.cfg.ActionTokenSecret, signed, models.ActionChangeEmail)
+		if err != nil {
+			return err
+		}
+
+		var existingUser models.User
+		if err := tx.Where("email = ? AND id != ?", token.Email, token.UserID).First(&existingUser).Error; err == nil {
+			return errors.New("email already taken")
+		}
+
+		if err := tx.Where("id = ?", token.UserID).First(&user).Error; err != nil {
+			return errInvalidActionToken
+		}
+		oldEmail = user.Email
+		return tx.Model(&user).
+			Updates(map[string]any{"email": token.Email, "email_verified_at": time.Now()}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	client.Source = "web"
+	recordAuditEvent(s.db, user.ID, AuditEmailChanged, client, map[string]any{
+		"old_email": oldEmail,
+		"new_email": token.Email,
+	})
+	return nil
+}
+
+// PendingEmail returns the address of an outstanding email change, if any
+func (s *AccountService) PendingEmail(userID string) string {
+	var token models.ActionToken
+	err := s.db.Where("user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?",
+		userID, models.ActionChangeEmail, time.Now()).
+		Order("created_at DESC").
+		First(&token).Error
+	if err != nil {
+		return ""
+	}
+	return token.Email
+}
+
+// CleanupExpiredActionTokens deletes action tokens past their expiry
+func (s *AccountService) CleanupExpiredActionTokens(ctx context.Context) error {
+	return s.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&models.ActionToken{}).Error
+}
+
+func (s *AccountService) link(path, token string) string {
+	return s.cfg.AppURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// send delivers mail in the background so a slow mail server does not hold
+// up the request
+func (s *AccountService) send(msg mailer.Message) {
+	msg.To = strings.TrimSpace(msg.To)
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			log.Printf("Failed to send %q email: %v", msg.Subject, err)
+		}
+	}()
+}
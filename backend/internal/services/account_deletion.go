@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/database"
+	"github.com/pratts/tts-study-assistant/backend/internal/mailer"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AccountDeletionService schedules account deletion and purges accounts
+// once their grace period has ended.
+type AccountDeletionService struct {
+	db               *gorm.DB
+	cfg              *config.Config
+	accountService   *AccountService
+	twoFactorService *TwoFactorService
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`       // Pre-hashed password from UI
+	Code     string `json:"code,omitempty"` // TOTP or recovery code, required with 2FA on
+}
+
+type AccountDeletionResponse struct {
+	DeletionScheduledAt string `json:"deletion_scheduled_at"`
+}
+
+// userDataTables lists every table with rows owned by a user through a
+// user_id column. Purging an account deletes from each of them, so tables
+// added later must be listed here; TestUserDataTablesCoverSchema checks them
+// against database.Models. Note summaries are stored on the notes;
+// their rows in noteLinkTables are deleted before them.
+var userDataTables = []struct {
+	name  string
+	model any
+}{
+	{"notes", &models.Note{}},
+	{"tags", &models.Tag{}},
+	{"collections", &models.Collection{}},
+	{"refresh_tokens", &models.RefreshToken{}},
+	{"personal_access_tokens", &models.PersonalAccessToken{}},
+	{"recovery_codes", &models.RecoveryCode{}},
+	{"action_tokens", &models.ActionToken{}},
+	{"user_identities", &models.UserIdentity{}},
+	{"device_codes", &models.DeviceCode{}},
+}
+
+func NewAccountDeletionService(cfg *config.Config) *AccountDeletionService {
+	return &AccountDeletionService{
+		db:               database.DB,
+		cfg:              cfg,
+		accountService:   NewAccountService(cfg),
+		twoFactorService: NewTwoFactorService(cfg),
+	}
+}
+
+// RequestDeletion checks the user's password (and second factor, when
+// enabled), schedules the account for deletion after the grace period and
+// signs it out everywhere
+func (s *AccountDeletionService) RequestDeletion(userID string, req *DeleteAccountRequest, client ClientInfo) (*AccountDeletionResponse, error) {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	if ok, _ := verifyPassword(user.Password, req.Password); !ok {
+		recordAuditFailure(s.db, user.ID, AuditAccountDeletionRequested, client, map[string]any{
+			"reason": "invalid_password",
+		})
+		return nil, errors.New("invalid password")
+	}
+
+	now := time.Now()
+	scheduledAt := now.Add(s.cfg.AccountDeletionGracePeriod)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if user.TOTPEnabledAt != nil {
+			if err := s.twoFactorService.verifySecondFactor(tx, &user, req.Code, client); err != nil {
+				return err
+			}
+		}
+		err := tx.Model(&user).Updates(map[string]any{
+			"deletion_requested_at": now,
+			"deletion_scheduled_at": scheduledAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, userID, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordAuditEvent(s.db, user.ID, AuditAccountDeletionRequested, client, map[string]any{
+		"scheduled_at": scheduledAt.Format("2006-01-02T15:04:05Z07:00"),
+	})
+	s.accountService.send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account and all of your notes will be permanently deleted on %s.\n\nChanged your mind? Sign in before then and the deletion is cancelled.\n",
+			user.Name, scheduledAt.UTC().Format("January 2, 2006 at 15:04 UTC")),
+	})
+
+	return &AccountDeletionResponse{
+		DeletionScheduledAt: scheduledAt.Format("2006-01-02T15:04:05Z07:00"),
+	}, nil
+}
+
+// PurgeDueAccounts hard-deletes every account whose grace period has ended
+// and returns how many were purged. An account that fails to purge is
+// logged and retried on the next run.
+func (s *AccountDeletionService) PurgeDueAccounts(ctx context.Context) (int, error) {
+	var userIDs []uuid.UUID
+	err := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("deletion_scheduled_at <= ?", time.Now()).
+		Pluck("id", &userIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+		if err := s.purgeAccount(ctx, userID); err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("Failed to purge account %s: %v", userID, err)
+			}
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// purgeAccount deletes the user and all of their data in one transaction and
+// writes a deletion receipt
+func (s *AccountDeletionService) purgeAccount(ctx context.Context, userID uuid.UUID) error {
+	var user models.User
+	var receipt models.DeletionReceipt
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the user so a login cannot cancel the deletion halfway through
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND deletion_scheduled_at <= ?", userID, time.Now()).
+			First(&user).Error
+		if err != nil {
+			return err
+		}
+
+		deleted := make(map[string]int64, len(userDataTables)+len(noteLinkTables)+1)
+
+		// Rows that belong to notes have no user_id of their own
+		for _, table := range noteLinkTables {
+			result := tx.Where("note_id IN (?)", tx.Unscoped().Model(&models.Note{}).Select("id").Where("user_id = ?", userID)).
+				Delete(table.model)
+			if result.Error != nil {
+				return fmt.Errorf("%s: %w", table.name, result.Error)
+			}
+			deleted[table.name] = result.RowsAffected
+		}
+
+		for _, table := range userDataTables {
+			// Unscoped, so trashed notes go too
+			result := tx.Unscoped().Where("user_id = ?", userID).Delete(table.model)
+			if result.Error != nil {
+				return fmt.Errorf("%s: %w", table.name, result.Error)
+			}
+			deleted[table.name] = result.RowsAffected
+		}
+
+		// Audit events are append-only everywhere else; the events of a
+		// purged account go with it, since they hold its IPs and emails
+		result := tx.Session(&gorm.Session{SkipHooks: true}).
+			Where("user_id = ?", userID).
+			Delete(&models.AuditEvent{})
+		if result.Error != nil {
+			return fmt.Errorf("audit_events: %w", result.Error)
+		}
+		deleted["audit_events"] = result.RowsAffected
+
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+
+		summary, _ := json.Marshal(deleted)
+		receipt = models.DeletionReceipt{
+			UserID:      user.ID,
+			EmailHash:   hashSecret(strings.ToLower(user.Email)),
+			RequestedAt: user.DeletionRequestedAt,
+			PurgedAt:    time.Now(),
+			Deleted:     summary,
+		}
+		return tx.Create(&receipt).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.accountService.send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your account has been deleted",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account and all of its data have been permanently deleted.\n\nDeletion receipt: %s\n",
+			user.Name, receipt.ID),
+	})
+	return nil
+}
+
+// cancelAccountDeletion clears a pending deletion when the user logs back in
+// during the grace period. Once the period has ended the login is refused.
+func cancelAccountDeletion(db *gorm.DB, user *models.User, client ClientInfo) error {
+	if user.DeletionScheduledAt == nil {
+		return nil
+	}
+	result := db.Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at > ?", user.ID, time.Now()).
+		Updates(map[string]any{"deletion_requested_at": nil, "deletion_scheduled_at": nil})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invalid credentials")
+	}
+	user.DeletionRequestedAt = nil
+	user.DeletionScheduledAt = nil
+	recordAuditEvent(db, user.ID, AuditAccountDeletionCancelled, client, nil)
+	return nil
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/database"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func newTestAccountDeletionService(db *fakeDB) *AccountDeletionService {
+	cfg := &config.Config{AccountDeletionGracePeriod: 30 * 24 * time.Hour, TOTPEncryptionKey: "key"}
+	return &AccountDeletionService{
+		db:               db.DB,
+		cfg:              cfg,
+		accountService:   newTestAccountService(db),
+		twoFactorService: &TwoFactorService{db: db.DB, cfg: cfg},
+	}
+}
+
+// Tables left out of userDataTables on purpose
+var keptUserTables = map[string]string{
+	"audit_events":      "purged separately, skipping the append-only hooks",
+	"deletion_receipts": "the proof that the account was purged",
+}
+
+func TestUserDataTablesCoverSchema(t *testing.T) {
+	listed := make(map[string]bool)
+	for _, table := range slices.Concat(userDataTables, noteLinkTables) {
+		listed[table.name] = true
+	}
+
+	cache := &sync.Map{}
+	for _, model := range database.Models {
+		s, err := schema.Parse(model, cache, schema.NamingStrategy{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, hasUser := s.FieldsByDBName["user_id"]
+		_, hasNote := s.FieldsByDBName["note_id"]
+		switch {
+		case hasUser && keptUserTables[s.Table] == "" && !listed[s.Table]:
+			t.Errorf("%s has a user_id column but is not in userDataTables, so purged accounts leave rows behind", s.Table)
+		case !hasUser && hasNote && !listed[s.Table]:
+			t.Errorf("%s has a note_id column but is not in noteLinkTables, so purged notes leave rows behind", s.Table)
+		}
+	}
+
+	// Each name is the table of its model, as the receipt counts use it
+	for _, table := range slices.Concat(userDataTables, noteLinkTables) {
+		s, err := schema.Parse(table.model, cache, schema.NamingStrategy{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Table != table.name {
+			t.Errorf("table %q holds the model of %q", table.name, s.Table)
+		}
+	}
+}
+
+func TestRequestDeletion(t *testing.T) {
+	hashed, err := hashPassword("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{ID: uuid.New(), Name: "Ann", Email: "a@example.com", Password: hashed}
+
+	db := newFakeDB(t, user)
+	s := newTestAccountDeletionService(db)
+	response, err := s.RequestDeletion(user.ID.String(), &DeleteAccountRequest{Password: "password"}, ClientInfo{})
+	if err != nil {
+		t.Fatalf("RequestDeletion() error = %v", err)
+	}
+	scheduledAt, err := time.Parse(time.RFC3339, response.DeletionScheduledAt)
+	if err != nil || time.Until(scheduledAt) < s.cfg.AccountDeletionGracePeriod-time.Minute {
+		t.Errorf("RequestDeletion() scheduled at %s, want after the grace period", response.DeletionScheduledAt)
+	}
+	// Scheduled and signed out everywhere together
+	for _, write := range [][]string{
+		{`UPDATE "users" SET`, `"deletion_requested_at"=`, `"deletion_scheduled_at"=`, user.ID.String()},
+		{`"token_version"=token_version + 1`, user.ID.String()},
+		{`DELETE FROM "refresh_tokens"`, "user_id = '" + user.ID.String() + "'"},
+	} {
+		if !inTransaction(db, "COMMIT", write...) {
+			t.Errorf("RequestDeletion() did not write %q in the committed transaction: %q", write, db.writes)
+		}
+	}
+	if msg := s.accountService.mailer.(recordingMailer).sent(t, 1)[user.Email]; msg.Subject != "Your account will be deleted" {
+		t.Errorf("RequestDeletion() sent %+v, want a deletion notice", msg)
+	}
+
+	db = newFakeDB(t, user)
+	if _, err := newTestAccountDeletionService(db).RequestDeletion(user.ID.String(), &DeleteAccountRequest{Password: "wrong"}, ClientInfo{}); err == nil || err.Error() != "invalid password" {
+		t.Errorf("RequestDeletion() error = %v with the wrong password, want invalid password", err)
+	}
+	if db.wrote(`UPDATE "users"`) || !db.wrote(`INSERT INTO "audit_events"`, AuditAccountDeletionRequested, "invalid_password") {
+		t.Errorf("RequestDeletion() writes = %q with the wrong password", db.writes)
+	}
+}
+
+func TestRequestDeletionChecksSecondFactor(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := encryptTOTPSecret("key", secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := totpEncoding.DecodeString(secret)
+	code := totpCode(key, time.Now().Unix()/totpPeriod)
+	hashed, err := hashPassword("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabledAt := time.Now().Add(-time.Hour)
+	user := models.User{ID: uuid.New(), Email: "a@example.com", Password: hashed, TOTPSecret: encrypted, TOTPEnabledAt: &enabledAt}
+
+	// No recovery code matches either
+	db := newFakeDB(t, user)
+	db.affected = 0
+	if _, err := newTestAccountDeletionService(db).RequestDeletion(user.ID.String(), &DeleteAccountRequest{Password: "password"}, ClientInfo{}); err == nil || err.Error() != "invalid two-factor code" {
+		t.Errorf("RequestDeletion() error = %v without a code, want invalid two-factor code", err)
+	}
+	if db.wrote(`"deletion_scheduled_at"=`) || !slices.Contains(db.writes, "ROLLBACK") {
+		t.Errorf("RequestDeletion() writes = %q without a code", db.writes)
+	}
+
+	db = newFakeDB(t, user)
+	if _, err := newTestAccountDeletionService(db).RequestDeletion(user.ID.String(), &DeleteAccountRequest{Password: "password", Code: code}, ClientInfo{}); err != nil {
+		t.Fatalf("RequestDeletion() error = %v with a TOTP code", err)
+	}
+	// The code's step is used up with the request, so it cannot be replayed
+	if !inTransaction(db, "COMMIT", `UPDATE "users" SET "totp_last_step"=`, user.ID.String()) ||
+		!inTransaction(db, "COMMIT", `"deletion_scheduled_at"=`, user.ID.String()) {
+		t.Errorf("RequestDeletion() writes = %q with a TOTP code", db.writes)
+	}
+
+	// A code whose step was already used
+	db = newFakeDB(t, user)
+	db.affected = 0
+	if _, err := newTestAccountDeletionService(db).RequestDeletion(user.ID.String(), &DeleteAccountRequest{Password: "password", Code: code}, ClientInfo{}); err == nil || err.Error() != "invalid two-factor code" {
+		t.Errorf("RequestDeletion() error = %v with a used code, want invalid two-factor code", err)
+	}
+	if db.wrote(`"deletion_scheduled_at"=`) {
+		t.Errorf("RequestDeletion() scheduled the deletion with a used code: %q", db.writes)
+	}
+}
+
+func TestPurgeAccount(t *testing.T) {
+	requestedAt := time.Now().Add(-31 * 24 * time.Hour)
+	scheduledAt := time.Now().Add(-time.Hour)
+	user := models.User{ID: uuid.New(), Name: "Ann", Email: "A@Example.com", DeletionRequestedAt: &requestedAt, DeletionScheduledAt: &scheduledAt}
+	db := newFakeDB(t, user)
+	db.affected = 3
+	s := newTestAccountDeletionService(db)
+
+	if err := s.purgeAccount(context.Background(), user.ID); err != nil {
+		t.Fatalf("purgeAccount() error = %v", err)
+	}
+	// Only once the grace period has ended, with the user locked against a
+	// login cancelling it halfway
+	if !db.queried("id = '"+user.ID.String()+"' AND deletion_scheduled_at <= ", "FOR UPDATE") {
+		t.Errorf("purgeAccount() did not lock a user due for deletion: %q", db.queries)
+	}
+	for _, table := range userDataTables {
+		if !inTransaction(db, "COMMIT", `DELETE FROM "`+table.name+`" WHERE user_id = '`+user.ID.String()+`'`) {
+			t.Errorf("purgeAccount() did not delete from %s: %q", table.name, db.writes)
+		}
+	}
+	for _, table := range noteLinkTables {
+		if !inTransaction(db, "COMMIT", `DELETE FROM "`+table.name+`" WHERE note_id IN (SELECT "id" FROM "notes" WHERE user_id = '`+user.ID.String()+`')`) {
+			t.Errorf("purgeAccount() did not delete from %s: %q", table.name, db.writes)
+		}
+	}
+	// Trashed notes too
+	if db.wrote(`UPDATE "notes"`) || db.wrote("deleted_at") {
+		t.Errorf("purgeAccount() left trashed notes: %q", db.writes)
+	}
+	if !inTransaction(db, "COMMIT", `DELETE FROM "audit_events" WHERE user_id = '`+user.ID.String()+`'`) ||
+		!inTransaction(db, "COMMIT", `DELETE FROM "users"`, user.ID.String()) {
+		t.Errorf("purgeAccount() did not delete the user and their audit events: %q", db.writes)
+	}
+
+	// The receipt counts each table and keeps no readable email
+	receipt := db.writeIndex(`INSERT INTO "deletion_receipts"`, user.ID.String(), hashSecret("a@example.com"))
+	if receipt < 0 || db.wrote("a@example.com") || db.wrote("A@Example.com") {
+		t.Fatalf("purgeAccount() did not write a receipt with the email hash: %q", db.writes)
+	}
+	for _, table := range slices.Concat(userDataTables, noteLinkTables) {
+		if !strings.Contains(db.writes[receipt], fmt.Sprintf(`"%s":3`, table.name)) {
+			t.Errorf("purgeAccount() receipt = %s, want 3 rows of %s", db.writes[receipt], table.name)
+		}
+	}
+	if !strings.Contains(db.writes[receipt], `"audit_events":3`) {
+		t.Errorf("purgeAccount() receipt = %s, want the audit events counted", db.writes[receipt])
+	}
+	msg := s.accountService.mailer.(recordingMailer).sent(t, 1)["A@Example.com"]
+	if msg.Subject != "Your account has been deleted" || !strings.Contains(msg.Body, "Deletion receipt: ") {
+		t.Errorf("purgeAccount() sent %+v, want the receipt", msg)
+	}
+
+	// Cancelled by a login since it was found due
+	db = newFakeDB(t, missing(user))
+	if err := newTestAccountDeletionService(db).purgeAccount(context.Background(), user.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("purgeAccount() error = %v for a cancelled deletion, want %v", err, gorm.ErrRecordNotFound)
+	}
+	if slices.ContainsFunc(db.writes, func(write string) bool { return strings.HasPrefix(write, "DELETE") }) {
+		t.Errorf("purgeAccount() deleted data of a cancelled deletion: %q", db.writes)
+	}
+}
+
+func TestCancelAccountDeletion(t *testing.T) {
+	user := models.User{ID: uuid.New(), Email: "a@example.com"}
+	db := newFakeDB(t)
+	if err := cancelAccountDeletion(db.DB, &user, ClientInfo{}); err != nil || len(db.writes) != 0 {
+		t.Errorf("cancelAccountDeletion() = %v with writes %q without a deletion", err, db.writes)
+	}
+
+	requestedAt := time.Now().Add(-time.Hour)
+	scheduledAt := time.Now().Add(time.Hour)
+	user.DeletionRequestedAt, user.DeletionScheduledAt = &requestedAt, &scheduledAt
+	if err := cancelAccountDeletion(db.DB, &user, ClientInfo{}); err != nil {
+		t.Fatalf("cancelAccountDeletion() error = %v during the grace period", err)
+	}
+	// Only while the grace period lasts, however long ago the user was loaded
+	if !db.wrote(`UPDATE "users" SET`, `"deletion_requested_at"=NULL`, `"deletion_scheduled_at"=NULL`,
+		"id = '"+user.ID.String()+"' AND deletion_scheduled_at > ") {
+		t.Errorf("cancelAccountDeletion() writes = %q", db.writes)
+	}
+	if user.DeletionRequestedAt != nil || user.DeletionScheduledAt != nil {
+		t.Errorf("cancelAccountDeletion() left the user scheduled for deletion")
+	}
+	if !db.wrote(`INSERT INTO "audit_events"`, AuditAccountDeletionCancelled) {
+		t.Errorf("cancelAccountDeletion() did not record the cancellation: %q", db.writes)
+	}
+
+	// The grace period has ended and the purge may be under way
+	user.DeletionRequestedAt, user.DeletionScheduledAt = &requestedAt, &scheduledAt
+	db = newFakeDB(t)
+	db.affected = 0
+	if err := cancelAccountDeletion(db.DB, &user, ClientInfo{}); err == nil || err.Error() != "invalid credentials" {
+		t.Errorf("cancelAccountDeletion() error = %v after the grace period, want invalid credentials", err)
+	}
+	if db.wrote(`INSERT INTO "audit_events"`) || user.DeletionScheduledAt == nil {
+		t.Errorf("cancelAccountDeletion() cancelled after the grace period: %q", db.writes)
+	}
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/mailer"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+)
+
+// recordingMailer hands sent messages to the test
+type recordingMailer chan mailer.Message
+
+func (m recordingMailer) Send(msg mailer.Message) error {
+	m <- msg
+	return nil
+}
+
+// sent waits for n messages, as they are sent in the background
+func (m recordingMailer) sent(t *testing.T, n int) map[string]mailer.Message {
+	t.Helper()
+	messages := make(map[string]mailer.Message, n)
+	for range n {
+		select {
+		case msg := <-m:
+			messages[msg.To] = msg
+		case <-time.After(time.Second):
+			t.Fatalf("%d of %d messages sent", len(messages), n)
+		}
+	}
+	return messages
+}
+
+func newTestAccountService(db *fakeDB) *AccountService {
+	return &AccountService{
+		db:     db.DB,
+		cfg:    &config.Config{ActionTokenSecret: "secret", AppURL: "https://app.example.com"},
+		mailer: make(recordingMailer, 2),
+	}
+}
+
+// inTransaction reports whether the write containing every one of parts was
+// made in a transaction that then ended with end
+func inTransaction(db *fakeDB, end string, parts ...string) bool {
+	i := db.writeIndex(parts...)
+	if i < 0 {
+		return false
+	}
+	begin := lastIndex(db.writes[:i], "BEGIN")
+	return begin >= 0 && lastIndex(db.writes[:i], "COMMIT") < begin &&
+		lastIndex(db.writes[:i], "ROLLBACK") < begin && slices.Contains(db.writes[i:], end)
+}
+
+func lastIndex(writes []string, write string) int {
+	for i := len(writes) - 1; i >= 0; i-- {
+		if writes[i] == write {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestResetPassword(t *testing.T) {
+	token, signed := newActionToken("secret", models.ActionResetPassword, "a@example.com")
+	db := newFakeDB(t, token)
+	s := newTestAccountService(db)
+
+	if err := s.ResetPassword(&ResetPasswordRequest{Token: signed, Password: "new-password"}, ClientInfo{}); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	// The token is only used up along with the password change
+	for _, write := range [][]string{
+		{`UPDATE "action_tokens" SET "used_at"=`, token.ID.String()},
+		{`UPDATE "users" SET "password"=`, "id = '" + token.UserID.String() + "' AND email = 'a@example.com'"},
+		{`"token_version"=token_version + 1`, token.UserID.String()},
+		{`DELETE FROM "refresh_tokens"`, token.UserID.String()},
+	} {
+		if !inTransaction(db, "COMMIT", write...) {
+			t.Errorf("ResetPassword() did not write %q in the committed transaction: %q", write, db.writes)
+		}
+	}
+	if db.wrote("new-password") {
+		t.Error("ResetPassword() stored the password unhashed")
+	}
+	if !db.wrote(`INSERT INTO "audit_events"`, AuditPasswordReset) {
+		t.Errorf("ResetPassword() did not record the reset: %q", db.writes)
+	}
+
+	// A token for another purpose changes nothing
+	token, signed = newActionToken("secret", models.ActionVerifyEmail, "a@example.com")
+	db = newFakeDB(t, token)
+	s = newTestAccountService(db)
+	if err := s.ResetPassword(&ResetPasswordRequest{Token: signed, Password: "new-password"}, ClientInfo{}); err != errInvalidActionToken {
+		t.Errorf("ResetPassword() error = %v with a verification token, want %v", err, errInvalidActionToken)
+	}
+	if db.wrote(`UPDATE "users"`) || db.wrote(`"used_at"`) || !slices.Contains(db.writes, "ROLLBACK") {
+		t.Errorf("ResetPassword() writes = %q with a verification token", db.writes)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	token, signed := newActionToken("secret", models.ActionVerifyEmail, "a@example.com")
+	db := newFakeDB(t, token)
+	s := newTestAccountService(db)
+
+	if err := s.VerifyEmail(signed); err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+	if !inTransaction(db, "COMMIT", `UPDATE "action_tokens" SET "used_at"=`, token.ID.String()) {
+		t.Errorf("VerifyEmail() did not use up the token with the change: %q", db.writes)
+	}
+	// Only the address the link was sent to
+	if !inTransaction(db, "COMMIT", `UPDATE "users" SET "email_verified_at"=`, "id = '"+token.UserID.String()+"' AND email = 'a@example.com'") {
+		t.Errorf("VerifyEmail() did not verify the address in the token: %q", db.writes)
+	}
+
+	db = newFakeDB(t, token)
+	s = newTestAccountService(db)
+	if err := s.VerifyEmail(signed + "x"); err != errInvalidActionToken {
+		t.Errorf("VerifyEmail() error = %v with a tampered token, want %v", err, errInvalidActionToken)
+	}
+	if db.wrote(`UPDATE "users"`) {
+		t.Errorf("VerifyEmail() verified an address with a tampered token: %q", db.writes)
+	}
+}
+
+func TestEmailChangeIsBoundToNewAddress(t *testing.T) {
+	user := models.User{ID: uuid.New(), Name: "Ann", Email: "old@example.com"}
+	db := newFakeDB(t)
+	s := newTestAccountService(db)
+
+	if err := s.RequestEmailChange(&user, "new@example.com", ClientInfo{}); err != nil {
+		t.Fatalf("RequestEmailChange() error = %v", err)
+	}
+	if !db.wrote(`INSERT INTO "action_tokens"`, models.ActionChangeEmail, "new@example.com") {
+		t.Errorf("RequestEmailChange() did not issue the token for the new address: %q", db.writes)
+	}
+	if db.wrote(`UPDATE "users"`) {
+		t.Errorf("RequestEmailChange() changed the email before it was confirmed: %q", db.writes)
+	}
+	messages := s.mailer.(recordingMailer).sent(t, 2)
+	link := s.cfg.AppURL + "/confirm-email?token="
+	if !strings.Contains(messages["new@example.com"].Body, link) {
+		t.Errorf("confirmation email = %q, want a link", messages["new@example.com"].Body)
+	}
+	// Whoever reads the old mailbox cannot confirm the change
+	if notice := messages["old@example.com"]; notice.Body == "" || strings.Contains(notice.Body, link) {
+		t.Errorf("notice to the old address = %q, want no link", notice.Body)
+	}
+	_, rest, _ := strings.Cut(messages["new@example.com"].Body, link)
+	signed, err := url.QueryUnescape(strings.Fields(rest)[0])
+	if err != nil || !strings.Contains(signed, ".") {
+		t.Errorf("confirmation link token = %q", signed)
+	}
+
+	// Confirming sets the address the link was sent to
+	token, signed := newActionToken("secret", models.ActionChangeEmail, "new@example.com")
+	user.ID = token.UserID
+	db = newFakeDB(t, token, missing(models.User{}), user)
+	s = newTestAccountService(db)
+	if err := s.ConfirmEmailChange(signed, ClientInfo{}); err != nil {
+		t.Fatalf("ConfirmEmailChange() error = %v", err)
+	}
+	if !inTransaction(db, "COMMIT", `UPDATE "action_tokens" SET "used_at"=`, token.ID.String()) {
+		t.Errorf("ConfirmEmailChange() did not use up the token with the change: %q", db.writes)
+	}
+	if !inTransaction(db, "COMMIT", `UPDATE "users" SET`, `"email"='new@example.com'`, `"email_verified_at"=`, user.ID.String()) {
+		t.Errorf("ConfirmEmailChange() did not set the new address: %q", db.writes)
+	}
+	if !db.wrote(`INSERT INTO "audit_events"`, AuditEmailChanged, "old@example.com", "new@example.com") {
+		t.Errorf("ConfirmEmailChange() did not record the change: %q", db.writes)
+	}
+
+	// Taken by another account since: the link keeps working once it is free
+	db = newFakeDB(t, token, models.User{ID: uuid.New(), Email: "new@example.com"})
+	s = newTestAccountService(db)
+	if err := s.ConfirmEmailChange(signed, ClientInfo{}); err == nil || err.Error() != "email already taken" {
+		t.Errorf("ConfirmEmailChange() error = %v, want email already taken", err)
+	}
+	if !inTransaction(db, "ROLLBACK", `UPDATE "action_tokens" SET "used_at"=`) || db.wrote(`UPDATE "users"`) {
+		t.Errorf("ConfirmEmailChange() writes = %q for a taken address, want the token use rolled back", db.writes)
+	}
+
+	// A verification link for the new address cannot confirm the change
+	token, signed = newActionToken("secret", models.ActionVerifyEmail, "new@example.com")
+	db = newFakeDB(t, token, missing(models.User{}), user)
+	s = newTestAccountService(db)
+	if err := s.ConfirmEmailChange(signed, ClientInfo{}); err != errInvalidActionToken {
+		t.Errorf("ConfirmEmailChange() error = %v with a verification token, want %v", err, errInvalidActionToken)
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var errInvalidActionToken = errors.New("invalid or expired token")
+
+// issueActionToken stores a single-use token for purpose and returns the
+// signed string to put in the emailed link. Earlier unused tokens of the same
+// purpose for the user are invalidated.
+func issueActionToken(db *gorm.DB, secret string, userID uuid.UUID, purpose, email string, ttl time.Duration) (string, error) {
+	if secret == "" {
+		return "", errors.New("action token secret is not configured")
+	}
+
+	token := models.ActionToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.ActionToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&token).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	id := base64.RawURLEncoding.EncodeToString(token.ID[:])
+	return id + "." + signActionToken(secret, &token), nil
+}
+
+// consumeActionToken verifies the signed string and marks the token used.
+// It fails if the signature does not match, the purpose differs, or the
+// token expired or was already used. Call it in the transaction that makes
+// the change, so the token stays unused if the change fails.
+func consumeActionToken(db *gorm.DB, secret, signed, purpose string) (*models.ActionToken, error) {
+	idPart, signature, ok := strings.Cut(signed, ".")
+	if !ok || secret == "" {
+		return nil, errInvalidActionToken
+	}
+	idBytes, err := base64.RawURLEncoding.DecodeString(idPart)
+	if err != nil {
+		return nil, errInvalidActionToken
+	}
+	id, err := uuid.FromBytes(idBytes)
+	if err != nil {
+		return nil, errInvalidActionToken
+	}
+
+	var token models.ActionToken
+	if err := db.Where("id = ?", id).First(&token).Error; err != nil {
+		return nil, errInvalidActionToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(signActionToken(secret, &token))) {
+		return nil, errInvalidActionToken
+	}
+	if token.Purpose != purpose || token.UsedAt != nil || token.ExpiresAt.Before(time.Now()) {
+		return nil, errInvalidActionToken
+	}
+
+	result := db.Model(&models.ActionToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errInvalidActionToken
+	}
+	return &token, nil
+}
+
+func signActionToken(secret string, token *models.ActionToken) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{
+		token.ID.String(),
+		token.UserID.String(),
+		token.Purpose,
+		token.Email,
+		strconv.FormatInt(token.ExpiresAt.Unix(), 10),
+	}, "|")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+)
+
+// newActionToken returns a stored action token and its signed string, as
+// issueActionToken would have
+func newActionToken(secret, purpose, email string) (models.ActionToken, string) {
+	token := models.ActionToken{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		Purpose:   purpose,
+		Email:     email,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	return token, base64.RawURLEncoding.EncodeToString(token.ID[:]) + "." + signActionToken(secret, &token)
+}
+
+func TestIssueActionToken(t *testing.T) {
+	userID := uuid.New()
+
+	db := newFakeDB(t)
+	if _, err := issueActionToken(db.DB, "", userID, models.ActionVerifyEmail, "a@example.com", time.Hour); err == nil {
+		t.Error("issueActionToken() issued a token without a secret")
+	}
+	if len(db.writes) != 0 {
+		t.Errorf("issueActionToken() wrote without a secret: %q", db.writes)
+	}
+
+	signed, err := issueActionToken(db.DB, "secret", userID, models.ActionVerifyEmail, "a@example.com", time.Hour)
+	if err != nil {
+		t.Fatalf("issueActionToken() error = %v", err)
+	}
+	idPart, _, _ := strings.Cut(signed, ".")
+	idBytes, err := base64.RawURLEncoding.DecodeString(idPart)
+	if err != nil {
+		t.Fatalf("issueActionToken() = %q, want the token ID first", signed)
+	}
+	id, err := uuid.FromBytes(idBytes)
+	if err != nil {
+		t.Fatalf("issueActionToken() = %q, want the token ID first", signed)
+	}
+	if !db.wrote(`INSERT INTO "action_tokens"`, id.String(), userID.String(), models.ActionVerifyEmail, "a@example.com") {
+		t.Errorf("issueActionToken() did not store the token: %q", db.writes)
+	}
+	// Earlier links for the same purpose stop working
+	invalidated := db.writeIndex(`UPDATE "action_tokens" SET "used_at"=`,
+		"user_id = '"+userID.String()+"' AND purpose = '"+models.ActionVerifyEmail+"' AND used_at IS NULL")
+	if invalidated < 0 || invalidated > db.writeIndex(`INSERT INTO "action_tokens"`) {
+		t.Errorf("issueActionToken() did not invalidate earlier tokens first: %q", db.writes)
+	}
+}
+
+func TestConsumeActionToken(t *testing.T) {
+	const secret = "secret"
+	token, signed := newActionToken(secret, models.ActionResetPassword, "a@example.com")
+
+	db := newFakeDB(t, token)
+	got, err := consumeActionToken(db.DB, secret, signed, models.ActionResetPassword)
+	if err != nil {
+		t.Fatalf("consumeActionToken() error = %v", err)
+	}
+	if got.ID != token.ID || got.UserID != token.UserID || got.Email != token.Email {
+		t.Errorf("consumeActionToken() = %+v, want %+v", got, token)
+	}
+	// Only one of two concurrent requests can mark it used
+	if !db.wrote(`UPDATE "action_tokens" SET "used_at"=`, "id = '"+token.ID.String()+"' AND used_at IS NULL") {
+		t.Errorf("consumeActionToken() did not mark the token used: %q", db.writes)
+	}
+
+	usedAt := time.Now().Add(-time.Minute)
+	idPart, signature, _ := strings.Cut(signed, ".")
+	otherToken, otherSigned := newActionToken(secret, models.ActionResetPassword, "a@example.com")
+	tests := []struct {
+		name    string
+		row     func(*models.ActionToken)
+		signed  string
+		purpose string
+		secret  string
+	}{
+		{name: "used", row: func(t *models.ActionToken) { t.UsedAt = &usedAt }},
+		{name: "expired", row: func(t *models.ActionToken) { t.ExpiresAt = time.Now().Add(-time.Second) }},
+		{name: "other purpose", purpose: models.ActionVerifyEmail},
+		{name: "other secret", secret: "other"},
+		{name: "tampered signature", signed: idPart + "." + strings.Repeat("A", len(signature))},
+		{name: "signature of another token", signed: idPart + "." + strings.SplitN(otherSigned, ".", 2)[1]},
+		{
+			name:   "ID of another token",
+			row:    func(t *models.ActionToken) { *t = otherToken },
+			signed: base64.RawURLEncoding.EncodeToString(otherToken.ID[:]) + "." + signature,
+		},
+		{name: "changed email", row: func(t *models.ActionToken) { t.Email = "b@example.com" }},
+		{name: "changed user", row: func(t *models.ActionToken) { t.UserID = uuid.New() }},
+		{name: "no signature", signed: idPart},
+		{name: "not base64", signed: "!!!." + signature},
+		{name: "not a UUID", signed: base64.RawURLEncoding.EncodeToString([]byte("short")) + "." + signature},
+	}
+	for _, tt := range tests {
+		row := token
+		if tt.row != nil {
+			tt.row(&row)
+		}
+		if tt.signed == "" {
+			tt.signed = signed
+		}
+		if tt.purpose == "" {
+			tt.purpose = models.ActionResetPassword
+		}
+		if tt.secret == "" {
+			tt.secret = secret
+		}
+
+		db := newFakeDB(t, row)
+		if _, err := consumeActionToken(db.DB, tt.secret, tt.signed, tt.purpose); err != errInvalidActionToken {
+			t.Errorf("%s: consumeActionToken() error = %v, want %v", tt.name, err, errInvalidActionToken)
+		}
+		if len(db.writes) != 0 {
+			t.Errorf("%s: consumeActionToken() marked the token used: %q", tt.name, db.writes)
+		}
+	}
+
+	// Used by a concurrent request between lookup and update
+	db = newFakeDB(t, token)
+	db.affected = 0
+	if _, err := consumeActionToken(db.DB, secret, signed, models.ActionResetPassword); err != errInvalidActionToken {
+		t.Errorf("consumeActionToken() error = %v for a concurrently used token, want %v", err, errInvalidActionToken)
+	}
+
+	if _, err := consumeActionToken(newFakeDB(t).DB, secret, signed, models.ActionResetPassword); err != errInvalidActionToken {
+		t.Errorf("consumeActionToken() error = %v for an unknown token, want %v", err, errInvalidActionToken)
+	}
+	if _, err := consumeActionToken(newFakeDB(t, token).DB, "", signed, models.ActionResetPassword); err != errInvalidActionToken {
+		t.Errorf("consumeActionToken() error = %v without a secret, want %v", err, errInvalidActionToken)
+	}
+}
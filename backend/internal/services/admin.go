@@ -0,0 +1,362 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/database"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// AdminService backs the admin API: looking up users, changing roles,
+// disabling accounts and system-wide stats.
+type AdminService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+// AdminUserQuery filters the user list. Status is one of active, disabled
+// or pending_deletion.
+type AdminUserQuery struct {
+	Search   string
+	Role     string
+	Status   string
+	Page     int
+	PageSize int
+}
+
+type AdminUserResponse struct {
+	ID                  string `json:"id"`
+	Email               string `json:"email"`
+	Name                string `json:"name"`
+	Role                string `json:"role"`
+	EmailVerified       bool   `json:"email_verified"`
+	TwoFactorEnabled    bool   `json:"two_factor_enabled"`
+	DisabledAt          string `json:"disabled_at,omitempty"`
+	DeletionScheduledAt string `json:"deletion_scheduled_at,omitempty"`
+	CreatedAt           string `json:"created_at"`
+
+	// Only filled in for a single user
+	NoteCount      *int64 `json:"note_count,omitempty"`
+	ActiveSessions *int64 `json:"active_sessions,omitempty"`
+}
+
+type AdminUserList struct {
+	Users    []AdminUserResponse `json:"users"`
+	Total    int64               `json:"total"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"page_size"`
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type SystemStats struct {
+	Users struct {
+		Total           int64 `json:"total"`
+		Active          int64 `json:"active"`
+		Disabled        int64 `json:"disabled"`
+		PendingDeletion int64 `json:"pending_deletion"`
+		NewLast7Days    int64 `json:"new_last_7_days"`
+	} `json:"users"`
+	Notes struct {
+		Total         int64 `json:"total"`
+		NewLast7Days  int64 `json:"new_last_7_days"`
+		NewLast30Days int64 `json:"new_last_30_days"`
+	} `json:"notes"`
+	Summaries struct {
+		Total int64 `json:"total"`
+	} `json:"summaries"`
+	ActiveSessions int64 `json:"active_sessions"`
+}
+
+func NewAdminService(cfg *config.Config) *AdminService {
+	return &AdminService{
+		db:  database.DB,
+		cfg: cfg,
+	}
+}
+
+// ListUsers searches users by email or name, newest first
+func (s *AdminService) ListUsers(query *AdminUserQuery) (*AdminUserList, error) {
+	page, pageSize := query.Page, query.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	db := s.db.Model(&models.User{})
+	if search := strings.TrimSpace(query.Search); search != "" {
+		pattern := "%" + strings.ToLower(search) + "%"
+		db = db.Where("LOWER(email) LIKE ? OR LOWER(name) LIKE ?", pattern, pattern)
+	}
+	if query.Role != "" {
+		db = db.Where("role = ?", query.Role)
+	}
+	switch query.Status {
+	case "":
+	case "active":
+		db = db.Where("disabled_at IS NULL AND deletion_scheduled_at IS NULL")
+	case "disabled":
+		db = db.Where("disabled_at IS NOT NULL")
+	case "pending_deletion":
+		db = db.Where("deletion_scheduled_at IS NOT NULL")
+	default:
+		return nil, errors.New("invalid status")
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	var users []models.User
+	err := db.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	list := &AdminUserList{
+		Users:    make([]AdminUserResponse, len(users)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	for i := range users {
+		list.Users[i] = toAdminUserResponse(&users[i])
+	}
+	return list, nil
+}
+
+// GetUser returns one user with their note and session counts
+func (s *AdminService) GetUser(userID string) (*AdminUserResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var noteCount, activeSessions int64
+	if err := s.db.Model(&models.Note{}).Where("user_id = ?", user.ID).Count(&noteCount).Error; err != nil {
+		return nil, err
+	}
+	err = s.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND rotated_at IS NULL AND expires_at > ?", user.ID, time.Now()).
+		Count(&activeSessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := toAdminUserResponse(user)
+	response.NoteCount = &noteCount
+	response.ActiveSessions = &activeSessions
+	return &response, nil
+}
+
+// SetRole changes a user's role. The user's access tokens are revoked so the
+// new role takes effect on their next refresh.
+func (s *AdminService) SetRole(actorID, userID, role string, client ClientInfo) (*AdminUserResponse, error) {
+	if !slices.Contains(models.Roles, role) {
+		return nil, errors.New("invalid role")
+	}
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID.String() == actorID {
+		return nil, errors.New("cannot change own account")
+	}
+	if user.Role == role {
+		response := toAdminUserResponse(user)
+		return &response, nil
+	}
+
+	oldRole := user.Role
+	err = s.db.Model(user).Updates(map[string]any{
+		"role":          role,
+		"token_version": gorm.Expr("token_version + 1"),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	user.Role = role
+
+	recordAdminAction(s.db, actorID, user.ID, AuditRoleChanged, client, map[string]any{
+		"old_role": oldRole,
+		"new_role": role,
+	})
+	response := toAdminUserResponse(user)
+	return &response, nil
+}
+
+// DisableUser blocks the user from logging in and signs them out everywhere
+func (s *AdminService) DisableUser(actorID, userID string, client ClientInfo) (*AdminUserResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID.String() == actorID {
+		return nil, errors.New("cannot change own account")
+	}
+	if user.DisabledAt != nil {
+		response := toAdminUserResponse(user)
+		return &response, nil
+	}
+
+	now := time.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("disabled_at", now).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, user.ID.String(), "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.DisabledAt = &now
+
+	recordAdminAction(s.db, actorID, user.ID, AuditAccountDisabled, client, nil)
+	response := toAdminUserResponse(user)
+	return &response, nil
+}
+
+// EnableUser lets a disabled user log in again
+func (s *AdminService) EnableUser(actorID, userID string, client ClientInfo) (*AdminUserResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt == nil {
+		response := toAdminUserResponse(user)
+		return &response, nil
+	}
+
+	if err := s.db.Model(user).Update("disabled_at", nil).Error; err != nil {
+		return nil, err
+	}
+	user.DisabledAt = nil
+
+	recordAdminAction(s.db, actorID, user.ID, AuditAccountEnabled, client, nil)
+	response := toAdminUserResponse(user)
+	return &response, nil
+}
+
+// ForceLogout revokes every session and access token of the user
+func (s *AdminService) ForceLogout(actorID, userID string, client ClientInfo) error {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return revokeUserSessions(tx, user.ID.String(), "")
+	})
+	if err != nil {
+		return err
+	}
+
+	recordAdminAction(s.db, actorID, user.ID, AuditSessionsRevoked, client, nil)
+	return nil
+}
+
+// GetStats returns system-wide user, note and summary counts
+func (s *AdminService) GetStats() (*SystemStats, error) {
+	var stats SystemStats
+	now := time.Now()
+	weekAgo := now.AddDate(0, 0, -7)
+	monthAgo := now.AddDate(0, 0, -30)
+
+	counts := []struct {
+		model  any
+		target *int64
+		where  string
+		args   []any
+	}{
+		{&models.User{}, &stats.Users.Total, "", nil},
+		{&models.User{}, &stats.Users.Active, "disabled_at IS NULL AND deletion_scheduled_at IS NULL", nil},
+		{&models.User{}, &stats.Users.Disabled, "disabled_at IS NOT NULL", nil},
+		{&models.User{}, &stats.Users.PendingDeletion, "deletion_scheduled_at IS NOT NULL", nil},
+		{&models.User{}, &stats.Users.NewLast7Days, "created_at >= ?", []any{weekAgo}},
+		{&models.Note{}, &stats.Notes.Total, "", nil},
+		{&models.Note{}, &stats.Notes.NewLast7Days, "created_at >= ?", []any{weekAgo}},
+		{&models.Note{}, &stats.Notes.NewLast30Days, "created_at >= ?", []any{monthAgo}},
+		{&models.Note{}, &stats.Summaries.Total, "summary IS NOT NULL AND summary <> ''", nil},
+		{&models.RefreshToken{}, &stats.ActiveSessions, "rotated_at IS NULL AND expires_at > ?", []any{now}},
+	}
+	for _, count := range counts {
+		db := s.db.Model(count.model)
+		if count.where != "" {
+			db = db.Where(count.where, count.args...)
+		}
+		if err := db.Count(count.target).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &stats, nil
+}
+
+// PromoteConfiguredAdmins gives the admin role to the users listed in
+// ADMIN_EMAILS, so a fresh deployment has an admin without raw SQL. Only
+// verified addresses are promoted, so nobody can claim the role by
+// registering with an admin's email first.
+func (s *AdminService) PromoteConfiguredAdmins() error {
+	if len(s.cfg.AdminEmails) == 0 {
+		return nil
+	}
+	emails := make([]string, len(s.cfg.AdminEmails))
+	for i, email := range s.cfg.AdminEmails {
+		emails[i] = strings.ToLower(email)
+	}
+	result := s.db.Model(&models.User{}).
+		Where("LOWER(email) IN ? AND email_verified_at IS NOT NULL AND role <> ?", emails, models.RoleAdmin).
+		Updates(map[string]any{
+			"role":          models.RoleAdmin,
+			"token_version": gorm.Expr("token_version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Promoted %d users from ADMIN_EMAILS to admin", result.RowsAffected)
+	}
+	return nil
+}
+
+func (s *AdminService) getUser(userID string) (*models.User, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, errors.New("user not found")
+	}
+	var user models.User
+	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func toAdminUserResponse(user *models.User) AdminUserResponse {
+	response := AdminUserResponse{
+		ID:               user.ID.String(),
+		Email:            user.Email,
+		Name:             user.Name,
+		Role:             user.Role,
+		EmailVerified:    user.EmailVerifiedAt != nil,
+		TwoFactorEnabled: user.TOTPEnabledAt != nil,
+		CreatedAt:        user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if user.DisabledAt != nil {
+		response.DisabledAt = user.DisabledAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if user.DeletionScheduledAt != nil {
+		response.DeletionScheduledAt = user.DeletionScheduledAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return response
+}
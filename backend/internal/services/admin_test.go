@@ -0,0 +1,160 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+)
+
+func TestSetRole(t *testing.T) {
+	actorID := uuid.NewString()
+	user := models.User{ID: uuid.New(), Email: "a@example.com", Role: models.RoleUser}
+
+	db := newFakeDB(t, user)
+	s := &AdminService{db: db.DB}
+	response, err := s.SetRole(actorID, user.ID.String(), models.RoleSupport, ClientInfo{})
+	if err != nil {
+		t.Fatalf("SetRole() error = %v", err)
+	}
+	if response.Role != models.RoleSupport {
+		t.Errorf("SetRole() role = %q, want %q", response.Role, models.RoleSupport)
+	}
+	// Access tokens with the old role stop working
+	if !db.wrote(`UPDATE "users" SET`, `"role"='support'`, `"token_version"=token_version + 1`, user.ID.String()) {
+		t.Errorf("SetRole() did not change the role and revoke access tokens: %q", db.writes)
+	}
+	if !db.wrote(`INSERT INTO "audit_events"`, actorID, user.ID.String(), AuditRoleChanged, `"old_role":"user"`, `"new_role":"support"`) {
+		t.Errorf("SetRole() did not record the change: %q", db.writes)
+	}
+
+	// The same role changes nothing
+	db = newFakeDB(t, user)
+	s = &AdminService{db: db.DB}
+	if _, err := s.SetRole(actorID, user.ID.String(), models.RoleUser, ClientInfo{}); err != nil || len(db.writes) != 0 {
+		t.Errorf("SetRole() = %v with writes %q for the current role, want no change", err, db.writes)
+	}
+
+	tests := []struct {
+		name    string
+		actorID string
+		userID  string
+		role    string
+		want    string
+	}{
+		// An admin cannot demote themselves and leave nobody to undo it
+		{"own account", user.ID.String(), user.ID.String(), models.RoleUser, "cannot change own account"},
+		{"unknown role", actorID, user.ID.String(), "owner", "invalid role"},
+		{"invalid user ID", actorID, "not-a-uuid", models.RoleAdmin, "user not found"},
+	}
+	for _, tt := range tests {
+		db := newFakeDB(t, user)
+		s := &AdminService{db: db.DB}
+		if _, err := s.SetRole(tt.actorID, tt.userID, tt.role, ClientInfo{}); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: SetRole() error = %v, want %s", tt.name, err, tt.want)
+		}
+		if len(db.writes) != 0 {
+			t.Errorf("%s: SetRole() wrote %q", tt.name, db.writes)
+		}
+	}
+
+	db = newFakeDB(t, missing(user))
+	if _, err := (&AdminService{db: db.DB}).SetRole(actorID, user.ID.String(), models.RoleAdmin, ClientInfo{}); err == nil || err.Error() != "user not found" {
+		t.Errorf("SetRole() error = %v for an unknown user, want user not found", err)
+	}
+}
+
+func TestDisableUser(t *testing.T) {
+	actorID := uuid.NewString()
+	user := models.User{ID: uuid.New(), Email: "a@example.com"}
+
+	db := newFakeDB(t, user)
+	s := &AdminService{db: db.DB}
+	response, err := s.DisableUser(actorID, user.ID.String(), ClientInfo{})
+	if err != nil {
+		t.Fatalf("DisableUser() error = %v", err)
+	}
+	if response.DisabledAt == "" {
+		t.Errorf("DisableUser() = %+v, want it disabled", response)
+	}
+	// Disabled and signed out everywhere together
+	for _, write := range [][]string{
+		{`UPDATE "users" SET "disabled_at"=`, user.ID.String()},
+		{`"token_version"=token_version + 1`, user.ID.String()},
+		{`DELETE FROM "refresh_tokens"`, "user_id = '" + user.ID.String() + "'"},
+	} {
+		if !inTransaction(db, "COMMIT", write...) {
+			t.Errorf("DisableUser() did not write %q in the committed transaction: %q", write, db.writes)
+		}
+	}
+	if !db.wrote(`INSERT INTO "audit_events"`, actorID, user.ID.String(), AuditAccountDisabled) {
+		t.Errorf("DisableUser() did not record the change: %q", db.writes)
+	}
+
+	db = newFakeDB(t, user)
+	if _, err := (&AdminService{db: db.DB}).DisableUser(user.ID.String(), user.ID.String(), ClientInfo{}); err == nil || err.Error() != "cannot change own account" {
+		t.Errorf("DisableUser() error = %v for the admin's own account, want cannot change own account", err)
+	}
+	if len(db.writes) != 0 {
+		t.Errorf("DisableUser() disabled the admin's own account: %q", db.writes)
+	}
+
+	// Already disabled
+	disabledAt := time.Now().Add(-time.Hour)
+	user.DisabledAt = &disabledAt
+	db = newFakeDB(t, user)
+	if _, err := (&AdminService{db: db.DB}).DisableUser(actorID, user.ID.String(), ClientInfo{}); err != nil || len(db.writes) != 0 {
+		t.Errorf("DisableUser() = %v with writes %q for a disabled user, want no change", err, db.writes)
+	}
+}
+
+func TestForceLogout(t *testing.T) {
+	actorID := uuid.NewString()
+	user := models.User{ID: uuid.New(), Email: "a@example.com"}
+
+	db := newFakeDB(t, user)
+	if err := (&AdminService{db: db.DB}).ForceLogout(actorID, user.ID.String(), ClientInfo{}); err != nil {
+		t.Fatalf("ForceLogout() error = %v", err)
+	}
+	// Access tokens are revoked along with every session
+	if !inTransaction(db, "COMMIT", `UPDATE "users" SET "token_version"=token_version + 1`, user.ID.String()) ||
+		!inTransaction(db, "COMMIT", `DELETE FROM "refresh_tokens"`, "user_id = '"+user.ID.String()+"'") {
+		t.Errorf("ForceLogout() did not revoke every session: %q", db.writes)
+	}
+	if db.wrote("family_id <>") {
+		t.Errorf("ForceLogout() kept a session: %q", db.writes)
+	}
+	if !db.wrote(`INSERT INTO "audit_events"`, actorID, user.ID.String(), AuditSessionsRevoked) {
+		t.Errorf("ForceLogout() did not record the logout: %q", db.writes)
+	}
+
+	db = newFakeDB(t, missing(user))
+	if err := (&AdminService{db: db.DB}).ForceLogout(actorID, user.ID.String(), ClientInfo{}); err == nil || err.Error() != "user not found" {
+		t.Errorf("ForceLogout() error = %v for an unknown user, want user not found", err)
+	}
+	if len(db.writes) != 0 {
+		t.Errorf("ForceLogout() wrote %q for an unknown user", db.writes)
+	}
+}
+
+func TestPromoteConfiguredAdmins(t *testing.T) {
+	db := newFakeDB(t)
+	s := &AdminService{db: db.DB, cfg: &config.Config{AdminEmails: []string{"Admin@Example.com", "ops@example.com"}}}
+	if err := s.PromoteConfiguredAdmins(); err != nil {
+		t.Fatalf("PromoteConfiguredAdmins() error = %v", err)
+	}
+	// Only verified addresses, so nobody can register with an admin's email
+	// first and be promoted
+	if !db.wrote(`UPDATE "users" SET`, `"role"='admin'`, `"token_version"=token_version + 1`,
+		"LOWER(email) IN ('admin@example.com','ops@example.com') AND email_verified_at IS NOT NULL AND role <> 'admin'") {
+		t.Errorf("PromoteConfiguredAdmins() did not promote only verified users: %q", db.writes)
+	}
+
+	db = newFakeDB(t)
+	s = &AdminService{db: db.DB, cfg: &config.Config{}}
+	if err := s.PromoteConfiguredAdmins(); err != nil || len(db.writes) != 0 {
+		t.Errorf("PromoteConfiguredAdmins() = %v with writes %q without ADMIN_EMAILS", err, db.writes)
+	}
+}
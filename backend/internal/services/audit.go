@@ -0,0 +1,245 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/database"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ClientInfo describes the client that made a request
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	Source    string // web, extension or api
+}
+
+// Audit event names
+const (
+	AuditRegister                 = "register"
+	AuditLogin                    = "login"
+	AuditTokenRefresh             = "token_refresh"
+	AuditLogout                   = "logout"
+	AuditPasswordChanged          = "password_changed"
+	AuditPasswordReset            = "password_reset"
+	AuditEmailChangeRequested     = "email_change_requested"
+	AuditEmailChanged             = "email_changed"
+	AuditRefreshTokenReuse        = "refresh_token_reuse"
+	AuditTwoFactorEnabled         = "two_factor_enabled"
+	AuditTwoFactorDisabled        = "two_factor_disabled"
+	AuditRecoveryCodeUsed         = "recovery_code_used"
+	AuditRecoveryCodesRegenerated = "recovery_codes_regenerated"
+	AuditIdentityLinked           = "identity_linked"
+	AuditAccountLocked            = "account_locked"
+	AuditAccountDeletionRequested = "account_deletion_requested"
+	AuditAccountDeletionCancelled = "account_deletion_cancelled"
+	AuditRoleChanged              = "role_changed"
+	AuditAccountDisabled          = "account_disabled"
+	AuditAccountEnabled           = "account_enabled"
+	AuditSessionsRevoked          = "sessions_revoked"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+// auditEntry is an audit event before it is stored
+type auditEntry struct {
+	UserID   uuid.UUID  // Account the event is about
+	ActorID  *uuid.UUID // Who caused it, nil when unknown
+	Event    string
+	Outcome  string // Defaults to success
+	Metadata map[string]any
+}
+
+// AuditService reads the security audit log
+type AuditService struct {
+	db *gorm.DB
+}
+
+// AuditQuery filters and pages audit events. Cursor is the ID of the last
+// event of the previous page.
+type AuditQuery struct {
+	UserID    string
+	Event     string
+	Outcome   string
+	IPAddress string
+	Since     *time.Time
+	Until     *time.Time
+	Cursor    string
+	Limit     int
+}
+
+type AuditEventResponse struct {
+	ID        string         `json:"id"`
+	UserID    string         `json:"user_id,omitempty"`
+	ActorID   string         `json:"actor_id,omitempty"`
+	Event     string         `json:"event"`
+	Outcome   string         `json:"outcome"`
+	Source    string         `json:"source,omitempty"`
+	IPAddress string         `json:"ip_address,omitempty"`
+	UserAgent string         `json:"user_agent,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	CreatedAt string         `json:"created_at"`
+}
+
+type AuditEventPage struct {
+	Events     []AuditEventResponse `json:"events"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+func NewAuditService() *AuditService {
+	return &AuditService{
+		db: database.DB,
+	}
+}
+
+// ListUserEvents returns the user's own events, newest first
+func (s *AuditService) ListUserEvents(userID string, query *AuditQuery) (*AuditEventPage, error) {
+	scoped := *query
+	scoped.UserID = userID
+	return s.ListEvents(&scoped)
+}
+
+// ListEvents returns events across all users matching the query, newest
+// first. It is meant for administrators.
+func (s *AuditService) ListEvents(query *AuditQuery) (*AuditEventPage, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultAuditPageSize
+	}
+	if limit > maxAuditPageSize {
+		limit = maxAuditPageSize
+	}
+
+	db := s.db.Model(&models.AuditEvent{})
+	if query.UserID != "" {
+		if _, err := uuid.Parse(query.UserID); err != nil {
+			return nil, errors.New("invalid user ID")
+		}
+		db = db.Where("user_id = ?", query.UserID)
+	}
+	if query.Event != "" {
+		db = db.Where("event = ?", query.Event)
+	}
+	if query.Outcome != "" {
+		db = db.Where("outcome = ?", query.Outcome)
+	}
+	if query.IPAddress != "" {
+		db = db.Where("ip_address = ?", query.IPAddress)
+	}
+	if query.Since != nil {
+		db = db.Where("created_at >= ?", *query.Since)
+	}
+	if query.Until != nil {
+		db = db.Where("created_at < ?", *query.Until)
+	}
+	if query.Cursor != "" {
+		if _, err := uuid.Parse(query.Cursor); err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+		db = db.Where("(created_at, id) < (SELECT created_at, id FROM audit_events WHERE id = ?)", query.Cursor)
+	}
+
+	// Fetch one extra row to know whether there is another page
+	var events []models.AuditEvent
+	if err := db.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	page := &AuditEventPage{Events: make([]AuditEventResponse, 0, len(events))}
+	if len(events) > limit {
+		events = events[:limit]
+		page.NextCursor = events[limit-1].ID.String()
+	}
+	for _, event := range events {
+		page.Events = append(page.Events, toAuditEventResponse(&event))
+	}
+	return page, nil
+}
+
+func toAuditEventResponse(event *models.AuditEvent) AuditEventResponse {
+	response := AuditEventResponse{
+		ID:        event.ID.String(),
+		Event:     event.Event,
+		Outcome:   event.Outcome,
+		Source:    event.Source,
+		IPAddress: event.IPAddress,
+		UserAgent: event.UserAgent,
+		CreatedAt: event.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if event.UserID != nil {
+		response.UserID = event.UserID.String()
+	}
+	if event.ActorID != nil {
+		response.ActorID = event.ActorID.String()
+	}
+	if len(event.Metadata) > 0 {
+		_ = json.Unmarshal(event.Metadata, &response.Metadata)
+	}
+	return response
+}
+
+// recordAuditEvent appends a successful event that the user caused
+// themselves. Failures are logged rather than returned so that auditing
+// never breaks the request that triggered it.
+func recordAuditEvent(db *gorm.DB, userID uuid.UUID, event string, client ClientInfo, metadata map[string]any) {
+	entry := auditEntry{UserID: userID, Event: event, Metadata: metadata}
+	if userID != uuid.Nil {
+		entry.ActorID = &userID
+	}
+	recordAudit(db, entry, client)
+}
+
+// recordAuditFailure appends a failed attempt at an event. The actor is
+// unknown, since the attempt did not prove who made it.
+func recordAuditFailure(db *gorm.DB, userID uuid.UUID, event string, client ClientInfo, metadata map[string]any) {
+	recordAudit(db, auditEntry{
+		UserID:   userID,
+		Event:    event,
+		Outcome:  models.AuditOutcomeFailure,
+		Metadata: metadata,
+	}, client)
+}
+
+// recordAdminAction appends an event that an admin caused on another
+// user's account
+func recordAdminAction(db *gorm.DB, actorID string, userID uuid.UUID, event string, client ClientInfo, metadata map[string]any) {
+	entry := auditEntry{UserID: userID, Event: event, Metadata: metadata}
+	if actor, err := uuid.Parse(actorID); err == nil {
+		entry.ActorID = &actor
+	}
+	recordAudit(db, entry, client)
+}
+
+// recordAudit appends an audit event, logging any failure
+func recordAudit(db *gorm.DB, entry auditEntry, client ClientInfo) {
+	auditEvent := models.AuditEvent{
+		ActorID:   entry.ActorID,
+		Event:     entry.Event,
+		Outcome:   entry.Outcome,
+		Source:    client.Source,
+		IPAddress: client.IP,
+		UserAgent: client.UserAgent,
+	}
+	if auditEvent.Outcome == "" {
+		auditEvent.Outcome = models.AuditOutcomeSuccess
+	}
+	if entry.UserID != uuid.Nil {
+		auditEvent.UserID = &entry.UserID
+	}
+	if entry.Metadata != nil {
+		b, _ := json.Marshal(entry.Metadata)
+		auditEvent.Metadata = datatypes.JSON(b)
+	}
+	if err := db.Create(&auditEvent).Error; err != nil {
+		log.Printf("Failed to record audit event %s: %v", entry.Event, err)
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/datatypes"
+)
+
+func TestListUserEventsOnlyOwnEvents(t *testing.T) {
+	userID, otherID := uuid.NewString(), uuid.NewString()
+	db := newFakeDB(t)
+	s := &AuditService{db: db.DB}
+
+	// A user ID in the query cannot widen the list to someone else
+	if _, err := s.ListUserEvents(userID, &AuditQuery{UserID: otherID, Event: AuditLogin}); err != nil {
+		t.Fatalf("ListUserEvents() error = %v", err)
+	}
+	if !db.queried("user_id = '"+userID+"'", "event = 'login'") || db.queried(otherID) {
+		t.Errorf("ListUserEvents() did not list only the user's events: %q", db.queries)
+	}
+}
+
+func TestListEventsFilters(t *testing.T) {
+	userID, cursor := uuid.NewString(), uuid.NewString()
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.AddDate(0, 1, 0)
+	db := newFakeDB(t)
+	s := &AuditService{db: db.DB}
+
+	_, err := s.ListEvents(&AuditQuery{
+		UserID:    userID,
+		Event:     AuditLogin,
+		Outcome:   models.AuditOutcomeFailure,
+		IPAddress: "192.0.2.1",
+		Since:     &since,
+		Until:     &until,
+		Cursor:    cursor,
+		Limit:     maxAuditPageSize + 1,
+	})
+	if err != nil {
+		t.Fatalf("ListEvents() error = %v", err)
+	}
+	if !db.queried(
+		"user_id = '"+userID+"'",
+		"event = 'login'",
+		"outcome = 'failure'",
+		"ip_address = '192.0.2.1'",
+		"created_at >= '2025-01-01 00:00:00'",
+		"created_at < '2025-02-01 00:00:00'",
+		"(created_at, id) < (SELECT created_at, id FROM audit_events WHERE id = '"+cursor+"')",
+		"ORDER BY created_at DESC, id DESC LIMIT 201",
+	) {
+		t.Errorf("ListEvents() did not apply every filter: %q", db.queries)
+	}
+
+	// Without filters every user's events are listed
+	db = newFakeDB(t)
+	if _, err := (&AuditService{db: db.DB}).ListEvents(&AuditQuery{}); err != nil {
+		t.Fatalf("ListEvents() error = %v", err)
+	}
+	if db.queried("WHERE") || !db.queried("LIMIT 51") {
+		t.Errorf("ListEvents() queries = %q without filters", db.queries)
+	}
+
+	for _, query := range []AuditQuery{{UserID: "not-a-uuid"}, {Cursor: "not-a-uuid"}} {
+		db := newFakeDB(t)
+		if _, err := (&AuditService{db: db.DB}).ListEvents(&query); err == nil || len(db.queries) != 0 {
+			t.Errorf("ListEvents(%+v) error = %v with queries %q, want an invalid ID", query, err, db.queries)
+		}
+	}
+}
+
+func TestListEventsPages(t *testing.T) {
+	userID := uuid.New()
+	events := make([]models.AuditEvent, 3)
+	for i := range events {
+		events[i] = models.AuditEvent{
+			ID:        uuid.New(),
+			UserID:    &userID,
+			Event:     AuditLogin,
+			Outcome:   models.AuditOutcomeSuccess,
+			Metadata:  datatypes.JSON(`{"method":"password"}`),
+			CreatedAt: time.Now().Add(-time.Duration(i) * time.Minute),
+		}
+	}
+	s := &AuditService{db: newFakeDB(t, events).DB}
+
+	page, err := s.ListEvents(&AuditQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListEvents() error = %v", err)
+	}
+	if len(page.Events) != 2 || page.NextCursor != events[1].ID.String() {
+		t.Errorf("ListEvents() = %d events, next cursor %q, want 2 and %s", len(page.Events), page.NextCursor, events[1].ID)
+	}
+	if got := page.Events[0]; got.UserID != userID.String() || got.ActorID != "" || got.Metadata["method"] != "password" {
+		t.Errorf("ListEvents() event = %+v", got)
+	}
+
+	page, err = s.ListEvents(&AuditQuery{Limit: 3})
+	if err != nil || len(page.Events) != 3 || page.NextCursor != "" {
+		t.Errorf("ListEvents() = %+v (%v), want the last page", page, err)
+	}
+}
+
+func TestRecordAudit(t *testing.T) {
+	userID, actorID := uuid.New(), uuid.New()
+	client := ClientInfo{IP: "192.0.2.1", UserAgent: "curl", Source: "api"}
+
+	db := newFakeDB(t)
+	recordAuditEvent(db.DB, userID, AuditPasswordChanged, client, nil)
+	recordAuditFailure(db.DB, userID, AuditLogin, client, map[string]any{"reason": "invalid_password"})
+	recordAdminAction(db.DB, actorID.String(), userID, AuditAccountDisabled, client, nil)
+	want := [][]string{
+		// Users act on their own account
+		{`'` + userID.String() + `','` + userID.String() + `','password_changed','success','api','192.0.2.1','curl'`},
+		// A failed attempt did not prove who made it
+		{`'` + userID.String() + `',NULL,'login','failure'`, `"reason":"invalid_password"`},
+		{`'` + userID.String() + `','` + actorID.String() + `','account_disabled','success'`},
+	}
+	for _, parts := range want {
+		if !db.wrote(append([]string{`INSERT INTO "audit_events"`}, parts...)...) {
+			t.Errorf("audit events = %q, want %q", db.writes, parts)
+		}
+	}
+}
+
+func TestAuditEventsAreAppendOnly(t *testing.T) {
+	event := models.AuditEvent{ID: uuid.New(), Event: AuditLogin}
+	db := newFakeDB(t)
+
+	if err := db.Model(&event).Update("outcome", models.AuditOutcomeFailure).Error; !errors.Is(err, models.ErrAuditEventImmutable) {
+		t.Errorf("Update() error = %v, want %v", err, models.ErrAuditEventImmutable)
+	}
+	if err := db.Model(&models.AuditEvent{}).Where("event = ?", AuditLogin).Updates(map[string]any{"ip_address": ""}).Error; !errors.Is(err, models.ErrAuditEventImmutable) {
+		t.Errorf("Updates() error = %v, want %v", err, models.ErrAuditEventImmutable)
+	}
+	if err := db.Delete(&event).Error; !errors.Is(err, models.ErrAuditEventImmutable) {
+		t.Errorf("Delete() error = %v, want %v", err, models.ErrAuditEventImmutable)
+	}
+	if err := db.Where("user_id = ?", uuid.New()).Delete(&models.AuditEvent{}).Error; !errors.Is(err, models.ErrAuditEventImmutable) {
+		t.Errorf("Delete() error = %v, want %v", err, models.ErrAuditEventImmutable)
+	}
+	if len(db.writes) != 0 {
+		t.Errorf("audit events were changed: %q", db.writes)
+	}
+}
@@ -1,7 +1,12 @@
 package services
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -9,12 +14,15 @@ import (
 	"github.com/pratts/tts-study-assistant/backend/internal/config"
 	"github.com/pratts/tts-study-assistant/backend/internal/database"
 	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"github.com/pratts/tts-study-assistant/backend/internal/signing"
 	"gorm.io/gorm"
 )
 
 type AuthService struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db               *gorm.DB
+	cfg              *config.Config
+	accountService   *AccountService
+	twoFactorService *TwoFactorService
 }
 
 type RegisterRequest struct {
@@ -24,9 +32,10 @@ type RegisterRequest struct {
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"` // Pre-hashed password from UI
-	Source   string `json:"source"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required"` // Pre-hashed password from UI
+	Source     string `json:"source"`
+	DeviceName string `json:"device_name,omitempty"` // Optional label for the new session
 }
 
 type AuthResponse struct {
@@ -39,49 +48,103 @@ type AuthResponse struct {
 	} `json:"user"`
 }
 
+// TwoFactorChallenge is returned by Login instead of tokens when the user
+// has two-factor authentication enabled
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int    `json:"expires_in"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"` // TOTP or recovery code
+}
+
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
 // Claims struct for JWT parsing
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid,omitempty"`
+	Source    string `json:"src,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Version   int    `json:"ver"`
+	Type      string `json:"typ,omitempty"` // Empty for access tokens
+	jwt.RegisteredClaims
+}
+
+var errAccountDisabled = errors.New("account disabled")
+
+// RefreshTokenPrefix starts every refresh token. Tokens issued before the
+// prefix existed are plain UUIDs and keep working until they rotate.
+const RefreshTokenPrefix = "tsa_rt_"
+
+// Challenge tokens carry their own audience and are signed with a key of
+// their own, so nothing that verifies access tokens accepts them
+const (
+	twoFactorChallengeType     = "2fa"
+	twoFactorChallengeAudience = "tsa-2fa-challenge"
+	twoFactorChallengeLifetime = 5 * time.Minute
+)
+
+type twoFactorChallengeClaims struct {
+	UserID     string `json:"user_id"`
+	Type       string `json:"typ"`
+	Source     string `json:"src"`
+	DeviceName string `json:"dev,omitempty"`
+	Version    int    `json:"ver"`
 	jwt.RegisteredClaims
 }
 
 func NewAuthService(cfg *config.Config) *AuthService {
 	return &AuthService{
-		db:  database.DB,
-		cfg: cfg,
+		db:               database.DB,
+		cfg:              cfg,
+		accountService:   NewAccountService(cfg),
+		twoFactorService: NewTwoFactorService(cfg),
 	}
 }
 
-func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
+func (s *AuthService) Register(req *RegisterRequest, client ClientInfo) (*AuthResponse, error) {
 	// Check if user already exists
 	var existingUser models.User
 	if err := s.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
 		return nil, errors.New("user already exists")
 	}
 
-	// Create user with pre-hashed password
+	// Hash the client's pre-hashed password before storing it
+	passwordHash, err := hashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
 	user := models.User{
 		Email:    req.Email,
-		Password: req.Password, // Store the pre-hashed password directly
+		Password: passwordHash,
 		Name:     req.Name,
 	}
 
 	if err := s.db.Create(&user).Error; err != nil {
 		return nil, err
 	}
+	client.Source = "web"
+	recordAuditEvent(s.db, user.ID, AuditRegister, client, nil)
+
+	if err := s.accountService.SendVerificationEmail(&user); err != nil {
+		log.Printf("Failed to send verification email: %v", err)
+	}
 
 	// Generate tokens
-	accessToken, err := s.generateAccessToken(user.ID.String(), user.Email)
+	refreshToken, sessionID, err := s.generateRefreshTokenWithSource(user.ID.String(), "web", "", client)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := s.generateRefreshToken(user.ID.String())
+	accessToken, err := s.generateAccessTokenWithSource(&user, "web", sessionID)
 	if err != nil {
 		return nil, err
 	}
@@ -97,37 +160,211 @@ func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
 	return response, nil
 }
 
-func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
+// Login checks the password and starts a session. For users with 2FA
+// enabled it returns a challenge instead, to be finished with
+// LoginWithTwoFactor.
+func (s *AuthService) Login(req *LoginRequest, client ClientInfo) (*AuthResponse, *TwoFactorChallenge, error) {
+	source := req.Source
+	if source == "" {
+		source = "web"
+	}
+	client.Source = source
+
+	if err := checkLoginAllowed(req.Email, client); err != nil {
+		return nil, nil, err
+	}
+
 	// Find user
 	var user models.User
 	if err := s.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("invalid credentials")
+			return nil, nil, recordLoginFailure(s.db, uuid.Nil, req.Email, "unknown_email", client, errors.New("invalid credentials"))
+		}
+		return nil, nil, err
+	}
+
+	ok, needsRehash := verifyPassword(user.Password, req.Password)
+	if !ok {
+		return nil, nil, recordLoginFailure(s.db, user.ID, user.Email, "invalid_password", client, errors.New("invalid credentials"))
+	}
+
+	if user.DisabledAt != nil {
+		recordAuditFailure(s.db, user.ID, AuditLogin, client, map[string]any{
+			"email":  user.Email,
+			"reason": "account_disabled",
+		})
+		return nil, nil, errAccountDisabled
+	}
+
+	if s.cfg.RequireEmailVerification && user.EmailVerifiedAt == nil {
+		recordAuditFailure(s.db, user.ID, AuditLogin, client, map[string]any{
+			"email":  user.Email,
+			"reason": "email_not_verified",
+		})
+		return nil, nil, errors.New("email not verified")
+	}
+
+	// Upgrade rows that still hold the raw client hash
+	if needsRehash {
+		if passwordHash, err := hashPassword(req.Password); err == nil {
+			s.db.Model(&user).Update("password", passwordHash)
+		}
+	}
+
+	// With 2FA on, the password only earns a challenge for the second step
+	if user.TOTPEnabledAt != nil {
+		challenge, err := s.issueTwoFactorChallenge(&user, source, req.DeviceName)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, challenge, nil
+	}
+
+	resetLoginFailures(user.Email)
+	response, err := s.startSession(&user, source, req.DeviceName, "password", client)
+	return response, nil, err
+}
+
+// LoginWithTwoFactor finishes a login that Login answered with a challenge
+func (s *AuthService) LoginWithTwoFactor(req *TwoFactorLoginRequest, client ClientInfo) (*AuthResponse, error) {
+	claims, err := parseTwoFactorChallenge(s.cfg.TwoFactorChallengeSecret, req.ChallengeToken)
+	if err != nil {
+		return nil, errors.New("invalid challenge")
+	}
+
+	user, err := s.GetUserByID(claims.UserID)
+	if err != nil {
+		return nil, errors.New("invalid challenge")
+	}
+	// A password change or disabled 2FA since the challenge voids it
+	if user.TokenVersion != claims.Version || user.TOTPEnabledAt == nil {
+		return nil, errors.New("invalid challenge")
+	}
+
+	client.Source = claims.Source
+
+	// Codes are guessable too, so they count towards the same lockout
+	if err := checkLoginAllowed(user.Email, client); err != nil {
+		return nil, err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return s.twoFactorService.verifySecondFactor(tx, user, req.Code, client)
+	})
+	if err != nil {
+		if err.Error() == "invalid two-factor code" {
+			return nil, recordLoginFailure(s.db, user.ID, user.Email, "invalid_two_factor_code", client, err)
 		}
 		return nil, err
 	}
 
-	// Compare pre-hashed passwords directly
-	if user.Password != req.Password {
-		return nil, errors.New("invalid credentials")
+	resetLoginFailures(user.Email)
+	return s.startSession(user, claims.Source, claims.DeviceName, "two_factor", client)
+}
+
+// issueTwoFactorChallenge signs a short-lived token that proves the password
+// step passed. It carries the login details needed to finish the session.
+func (s *AuthService) issueTwoFactorChallenge(user *models.User, source, deviceName string) (*TwoFactorChallenge, error) {
+	claims := jwt.MapClaims{
+		"user_id": user.ID.String(),
+		"typ":     twoFactorChallengeType,
+		"src":     source,
+		"dev":     deviceName,
+		"ver":     user.TokenVersion,
+		"aud":     twoFactorChallengeAudience,
+		"exp":     time.Now().Add(twoFactorChallengeLifetime).Unix(),
+		"iat":     time.Now().Unix(),
+	}
+	challengeToken, err := signTwoFactorChallenge(s.cfg.TwoFactorChallengeSecret, claims)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    challengeToken,
+		ExpiresIn:         int(twoFactorChallengeLifetime.Seconds()),
+	}, nil
+}
+
+// twoFactorChallengeKey derives the HS256 key for challenge tokens from the
+// configured secret, so it differs from JWT_SECRET even when both are set to
+// the same value
+func twoFactorChallengeKey(secret string) ([]byte, error) {
+	if secret == "" {
+		return nil, errors.New("two-factor challenge secret is not configured")
 	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(twoFactorChallengeAudience))
+	return mac.Sum(nil), nil
+}
 
-	source := req.Source
-	if source == "" {
-		source = "web"
+func signTwoFactorChallenge(secret string, claims jwt.MapClaims) (string, error) {
+	key, err := twoFactorChallengeKey(secret)
+	if err != nil {
+		return "", err
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+}
+
+// parseTwoFactorChallenge verifies a challenge token and returns its claims
+func parseTwoFactorChallenge(secret, tokenString string) (*twoFactorChallengeClaims, error) {
+	key, err := twoFactorChallengeKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	claims := &twoFactorChallengeClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(twoFactorChallengeAudience))
+	if err != nil || !token.Valid || claims.Type != twoFactorChallengeType {
+		return nil, errors.New("invalid challenge")
+	}
+	return claims, nil
+}
+
+// startSession creates a new session for a fully authenticated user and
+// records the login. method says how the user proved who they are. Logging
+// in cancels a pending account deletion.
+func (s *AuthService) startSession(user *models.User, source, deviceName, method string, client ClientInfo) (*AuthResponse, error) {
+	return s.openSession(user, models.RefreshToken{
+		Source:     source,
+		DeviceInfo: describeUserAgent(client.UserAgent),
+		Label:      deviceName,
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IP,
+	}, method, client)
+}
+
+// openSession is startSession for a session described by tmpl (source and
+// device details), for logins that know more about the device than the
+// request does
+func (s *AuthService) openSession(user *models.User, tmpl models.RefreshToken, method string, client ClientInfo) (*AuthResponse, error) {
+	if user.DisabledAt != nil {
+		return nil, errAccountDisabled
+	}
+	source := tmpl.Source
+	client.Source = source
+	if err := cancelAccountDeletion(s.db, user, client); err != nil {
+		return nil, err
 	}
 
 	// Generate tokens
-	accessToken, err := s.generateAccessTokenWithSource(user.ID.String(), user.Email, source)
+	tmpl.UserID = user.ID
+	tmpl.FamilyID = uuid.Nil
+	refreshToken, session, err := s.createRefreshToken(tmpl)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := s.generateRefreshTokenWithSource(user.ID.String(), source, "")
+	accessToken, err := s.generateAccessTokenWithSource(user, source, session.FamilyID.String())
 	if err != nil {
 		return nil, err
 	}
 
+	recordAuditEvent(s.db, user.ID, AuditLogin, client, map[string]any{
+		"method":     method,
+		"session_id": session.FamilyID.String(),
+	})
+
 	response := &AuthResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
@@ -139,37 +376,73 @@ func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
 	return response, nil
 }
 
-func (s *AuthService) Refresh(req *RefreshRequest) (*AuthResponse, error) {
+func (s *AuthService) Refresh(req *RefreshRequest, client ClientInfo) (*AuthResponse, error) {
 	// Find refresh token
-	var refreshToken models.RefreshToken
-	if err := s.db.Where("token = ?", req.RefreshToken).First(&refreshToken).Error; err != nil {
+	refreshToken, err := s.findRefreshToken(req.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// A token that was already rotated should never be presented again.
+	// Either the client or an attacker holds a copy, so revoke the family.
+	if refreshToken.RotatedAt != nil {
+		s.revokeTokenFamily(refreshToken, client)
 		return nil, errors.New("invalid refresh token")
 	}
 	if refreshToken.ExpiresAt.Before(time.Now()) {
 		return nil, errors.New("invalid refresh token")
 	}
 
+	client.Source = refreshToken.Source
+
 	// Get user
 	var user models.User
 	if err := s.db.First(&user, refreshToken.UserID).Error; err != nil {
 		return nil, err
 	}
+	if user.DisabledAt != nil {
+		return nil, errors.New("invalid refresh token")
+	}
 
-	// Update LastUsedAt
+	// Token rotation: mark the old token as rotated and issue a new one in
+	// the same family. The conditional update catches a concurrent reuse.
 	now := time.Now()
-	s.db.Model(&refreshToken).Update("last_used_at", &now)
+	result := s.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL", refreshToken.ID).
+		Updates(map[string]any{"rotated_at": now, "last_used_at": now})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		s.revokeTokenFamily(refreshToken, client)
+		return nil, errors.New("invalid refresh token")
+	}
 
-	// Token rotation: delete old, create new with same source/device
-	s.db.Delete(&refreshToken)
+	familyID := refreshToken.FamilyID
+	if familyID == uuid.Nil {
+		// Tokens issued before families existed start one on first rotation
+		familyID = refreshToken.ID
+	}
 
-	accessToken, err := s.generateAccessTokenWithSource(user.ID.String(), user.Email, refreshToken.Source)
+	newRefreshToken, _, err := s.createRefreshToken(models.RefreshToken{
+		UserID:     user.ID,
+		Source:     refreshToken.Source,
+		DeviceInfo: refreshToken.DeviceInfo,
+		Label:      refreshToken.Label,
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IP,
+		FamilyID:   familyID,
+	})
 	if err != nil {
 		return nil, err
 	}
-	newRefreshToken, err := s.generateRefreshTokenWithSource(user.ID.String(), refreshToken.Source, refreshToken.DeviceInfo)
+	accessToken, err := s.generateAccessTokenWithSource(&user, refreshToken.Source, familyID.String())
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(s.db, user.ID, AuditTokenRefresh, client, map[string]any{
+		"session_id": familyID.String(),
+	})
 
 	response := &AuthResponse{
 		AccessToken:  accessToken,
@@ -182,44 +455,63 @@ func (s *AuthService) Refresh(req *RefreshRequest) (*AuthResponse, error) {
 	return response, nil
 }
 
-func (s *AuthService) Logout(refreshToken string) error {
-	// Delete refresh token (idempotent)
-	s.db.Where("token = ?", refreshToken).Delete(&models.RefreshToken{})
+func (s *AuthService) Logout(refreshToken string, client ClientInfo) error {
+	// Revoke the whole token family (idempotent)
+	token, err := s.findRefreshToken(refreshToken)
+	if err != nil {
+		return nil
+	}
+	if err := s.deleteTokenFamily(token); err != nil {
+		return err
+	}
+	client.Source = token.Source
+	recordAuditEvent(s.db, token.UserID, AuditLogout, client, map[string]any{
+		"session_id": token.FamilyID.String(),
+	})
 	return nil
 }
 
-func (s *AuthService) generateAccessToken(userID, email string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"email":   email,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // 24 hours
-		"iat":     time.Now().Unix(),
+// findRefreshToken looks a presented refresh token up by its digest. Tokens
+// with the current prefix must pass their checksum first.
+func (s *AuthService) findRefreshToken(raw string) (*models.RefreshToken, error) {
+	if strings.HasPrefix(raw, RefreshTokenPrefix) && !validTokenChecksum(RefreshTokenPrefix, raw) {
+		return nil, errors.New("invalid refresh token")
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.cfg.JWTSecret))
+	var token models.RefreshToken
+	if err := s.db.Where("token_hash = ?", hashSecret(raw)).First(&token).Error; err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+	return &token, nil
 }
 
-func (s *AuthService) generateRefreshToken(userID string) (string, error) {
-	// Generate random refresh token
-	refreshToken := uuid.New().String()
-
-	// Store refresh token in database
-	refreshTokenModel := models.RefreshToken{
-		Token:     refreshToken,
-		UserID:    uuid.MustParse(userID),
-		ExpiresAt: time.Now().Add(time.Hour * 24 * 7), // 7 days
+// revokeTokenFamily deletes every token in the family of a reused token and
+// records the reuse in the audit log.
+func (s *AuthService) revokeTokenFamily(token *models.RefreshToken, client ClientInfo) {
+	if err := s.deleteTokenFamily(token); err != nil {
+		log.Printf("Failed to revoke refresh token family %s: %v", token.FamilyID, err)
 	}
+	client.Source = token.Source
+	recordAuditFailure(s.db, token.UserID, AuditRefreshTokenReuse, client, map[string]any{
+		"family_id": token.FamilyID.String(),
+		"token_id":  token.ID.String(),
+		"source":    token.Source,
+	})
+}
 
-	if err := s.db.Create(&refreshTokenModel).Error; err != nil {
-		return "", err
+// deleteTokenFamily deletes the tokens of a session. A token issued before
+// families existed has no family of its own, but its rotations started one
+// with its ID, so they go with it.
+func (s *AuthService) deleteTokenFamily(token *models.RefreshToken) error {
+	if token.FamilyID == uuid.Nil {
+		return s.db.Where("id = ? OR family_id = ?", token.ID, token.ID).Delete(&models.RefreshToken{}).Error
 	}
-
-	return refreshToken, nil
+	return s.db.Where("family_id = ?", token.FamilyID).Delete(&models.RefreshToken{}).Error
 }
 
-// Helper: generate access token with source-based expiry
-func (s *AuthService) generateAccessTokenWithSource(userID, email, source string) (string, error) {
+// Helper: generate access token with source-based expiry. The sid claim
+// ties the token to the refresh token family (session) it was issued for and
+// ver to the user's token version, which is bumped to revoke live tokens.
+func (s *AuthService) generateAccessTokenWithSource(user *models.User, source, sessionID string) (string, error) {
 	var exp time.Duration
 	switch source {
 	case "extension":
@@ -228,57 +520,104 @@ func (s *AuthService) generateAccessTokenWithSource(userID, email, source string
 		exp = time.Minute * 15
 	}
 	claims := jwt.MapClaims{
-		"user_id": userID,
-		"email":   email,
+		"user_id": user.ID.String(),
+		"email":   user.Email,
+		"sid":     sessionID,
+		"src":     source,
+		"role":    user.Role,
+		"ver":     user.TokenVersion,
 		"exp":     time.Now().Add(exp).Unix(),
 		"iat":     time.Now().Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.cfg.JWTSecret))
+	return signing.Keys.Sign(claims)
+}
+
+// Helper: generate refresh token with source-based expiry and device info.
+// Each call starts a new token family; its ID is returned as the session ID.
+func (s *AuthService) generateRefreshTokenWithSource(userID, source, deviceInfo string, client ClientInfo) (string, string, error) {
+	if deviceInfo == "" {
+		deviceInfo = describeUserAgent(client.UserAgent)
+	}
+	refreshToken, session, err := s.createRefreshToken(models.RefreshToken{
+		UserID:     uuid.MustParse(userID),
+		Source:     source,
+		DeviceInfo: deviceInfo,
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IP,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return refreshToken, session.FamilyID.String(), nil
 }
 
-// Helper: generate refresh token with source-based expiry and device info
-func (s *AuthService) generateRefreshTokenWithSource(userID, source, deviceInfo string) (string, error) {
-	refreshToken := uuid.New().String()
+// createRefreshToken stores a new refresh token for the session described by
+// tmpl (user, source, device details and family). A nil FamilyID starts a new
+// family. It returns the raw token and the stored row, which only keeps the
+// token's SHA-256 digest.
+func (s *AuthService) createRefreshToken(tmpl models.RefreshToken) (string, *models.RefreshToken, error) {
 	var exp time.Duration
-	switch source {
+	switch tmpl.Source {
 	case "extension":
 		exp = time.Hour * 24 * 90 // 90 days
 	default:
 		exp = time.Hour * 24 * 30 // 30 days
 	}
-	refreshTokenModel := models.RefreshToken{
-		Token:      refreshToken,
-		UserID:     uuid.MustParse(userID),
-		ExpiresAt:  time.Now().Add(exp),
-		Source:     source,
-		DeviceInfo: deviceInfo,
+	raw, err := generateChecksummedToken(RefreshTokenPrefix)
+	if err != nil {
+		return "", nil, err
 	}
+	// Every new refresh token comes with an access token, so it is used now
+	now := time.Now()
+	refreshTokenModel := tmpl
+	refreshTokenModel.TokenHash = hashSecret(raw)
+	refreshTokenModel.ExpiresAt = now.Add(exp)
+	refreshTokenModel.LastUsedAt = &now
 	if err := s.db.Create(&refreshTokenModel).Error; err != nil {
-		return "", err
+		return "", nil, err
 	}
-	return refreshToken, nil
+	return raw, &refreshTokenModel, nil
 }
 
 // GenerateAccessTokenForSource is a public wrapper for generateAccessTokenWithSource
-func (s *AuthService) GenerateAccessTokenForSource(userID, email, source string) (string, error) {
-	return s.generateAccessTokenWithSource(userID, email, source)
+func (s *AuthService) GenerateAccessTokenForSource(user *models.User, source, sessionID string) (string, error) {
+	return s.generateAccessTokenWithSource(user, source, sessionID)
+}
+
+// ReissueAccessToken issues a fresh access token for an existing session, for
+// example after the user's token version changed.
+func (s *AuthService) ReissueAccessToken(userID, sessionID string) (string, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return "", err
+	}
+	var session models.RefreshToken
+	err = s.db.Where("user_id = ? AND family_id = ? AND rotated_at IS NULL", userID, sessionID).
+		First(&session).Error
+	if err != nil {
+		return "", errors.New("session not found")
+	}
+	s.db.Model(&session).UpdateColumn("last_used_at", time.Now())
+	return s.generateAccessTokenWithSource(user, session.Source, sessionID)
 }
 
 // GenerateRefreshTokenForSource is a public wrapper for generateRefreshTokenWithSource
-func (s *AuthService) GenerateRefreshTokenForSource(userID, source, deviceInfo string) (string, error) {
-	return s.generateRefreshTokenWithSource(userID, source, deviceInfo)
+func (s *AuthService) GenerateRefreshTokenForSource(userID, source, deviceInfo string, client ClientInfo) (string, string, error) {
+	return s.generateRefreshTokenWithSource(userID, source, deviceInfo, client)
 }
 
 // ParseToken parses a JWT and returns claims
 func (s *AuthService) ParseToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.cfg.JWTSecret), nil
-	})
+	token, err := signing.Keys.Parse(tokenString, claims)
 	if err != nil || !token.Valid {
 		return nil, err
 	}
+	// Other token types such as 2FA challenges are not access tokens.
+	// Access tokens never carry an audience.
+	if claims.Type != "" || len(claims.Audience) > 0 {
+		return nil, errors.New("invalid token type")
+	}
 	return claims, nil
 }
 
@@ -292,6 +631,6 @@ func (s *AuthService) GetUserByID(userID string) (*models.User, error) {
 }
 
 // CleanupExpiredRefreshTokens deletes all expired refresh tokens from the database
-func (s *AuthService) CleanupExpiredRefreshTokens() error {
-	return s.db.Where("expires_at < ?", time.Now()).Delete(&models.RefreshToken{}).Error
+func (s *AuthService) CleanupExpiredRefreshTokens(ctx context.Context) error {
+	return s.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&models.RefreshToken{}).Error
 }
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"github.com/pratts/tts-study-assistant/backend/internal/signing"
+)
+
+// useTestKeys signs access tokens with an HS256 key set for the test
+func useTestKeys(t *testing.T, secret string) {
+	t.Helper()
+	keys, err := signing.NewKeySet(&config.Config{JWTSecret: secret, JWTIssuer: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := signing.Keys
+	signing.Keys = keys
+	t.Cleanup(func() { signing.Keys = previous })
+}
+
+func TestTwoFactorChallengeIsNotAnAccessToken(t *testing.T) {
+	// Even with the challenge secret equal to the access token secret
+	const secret = "shared-secret"
+	useTestKeys(t, secret)
+	s := &AuthService{cfg: &config.Config{TwoFactorChallengeSecret: secret}}
+	user := &models.User{ID: uuid.New(), TokenVersion: 3}
+
+	challenge, err := s.issueTwoFactorChallenge(user, "web", "laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := parseTwoFactorChallenge(secret, challenge.ChallengeToken)
+	if err != nil {
+		t.Fatalf("parseTwoFactorChallenge() error = %v", err)
+	}
+	if claims.UserID != user.ID.String() || claims.Source != "web" || claims.DeviceName != "laptop" || claims.Version != 3 {
+		t.Errorf("challenge claims = %+v", claims)
+	}
+
+	if _, err := s.ParseToken(challenge.ChallengeToken); err == nil {
+		t.Error("ParseToken() accepted a two-factor challenge")
+	}
+	if _, err := signing.Keys.Parse(challenge.ChallengeToken, &Claims{}); err == nil {
+		t.Error("the access token key set verified a two-factor challenge")
+	}
+	if _, err := parseTwoFactorChallenge("other-secret", challenge.ChallengeToken); err == nil {
+		t.Error("parseTwoFactorChallenge() accepted a challenge signed with another secret")
+	}
+
+	accessToken, err := s.generateAccessTokenWithSource(user, "web", uuid.NewString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseTwoFactorChallenge(secret, accessToken); err == nil {
+		t.Error("parseTwoFactorChallenge() accepted an access token")
+	}
+}
+
+func TestParseTokenRejectsAudience(t *testing.T) {
+	useTestKeys(t, "secret")
+	s := &AuthService{}
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"user_id": uuid.NewString(),
+			"exp":     time.Now().Add(time.Minute).Unix(),
+		}
+	}
+
+	token, err := signing.Keys.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ParseToken(token); err != nil {
+		t.Errorf("ParseToken() error = %v for an access token", err)
+	}
+
+	withAudience := claims()
+	withAudience["aud"] = "some-service"
+	token, err = signing.Keys.Sign(withAudience)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ParseToken(token); err == nil {
+		t.Error("ParseToken() accepted a token with an audience")
+	}
+}
+
+func TestRefreshRotatesWithinFamily(t *testing.T) {
+	useTestKeys(t, "secret")
+	user := models.User{ID: uuid.New(), Email: "a@example.com"}
+	token := models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		FamilyID:  uuid.New(),
+		Source:    "web",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	db := newFakeDB(t, user, token)
+	s := &AuthService{db: db.DB}
+
+	response, err := s.Refresh(&RefreshRequest{RefreshToken: uuid.NewString()}, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if !db.wrote(`UPDATE "refresh_tokens" SET`, `"rotated_at"=`, "rotated_at IS NULL") {
+		t.Errorf("Refresh() did not mark the token rotated: %q", db.writes)
+	}
+	if !db.wrote(`INSERT INTO "refresh_tokens"`, token.FamilyID.String()) {
+		t.Errorf("Refresh() did not issue the new token in the same family: %q", db.writes)
+	}
+	claims, err := s.ParseToken(response.AccessToken)
+	if err != nil || claims.SessionID != token.FamilyID.String() {
+		t.Errorf("access token session = %v (%v), want %s", claims, err, token.FamilyID)
+	}
+}
+
+func TestRefreshRevokesFamilyOnReuse(t *testing.T) {
+	useTestKeys(t, "secret")
+	user := models.User{ID: uuid.New(), Email: "a@example.com"}
+	rotatedAt := time.Now().Add(-time.Minute)
+	familyID, legacyID := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name   string
+		token  models.RefreshToken
+		delete string
+	}{
+		{
+			name:   "rotated token",
+			token:  models.RefreshToken{ID: uuid.New(), FamilyID: familyID, RotatedAt: &rotatedAt},
+			delete: "WHERE family_id = '" + familyID.String() + "'",
+		},
+		{
+			// Rotated by a concurrent request between lookup and update
+			name:   "concurrently rotated token",
+			token:  models.RefreshToken{ID: uuid.New(), FamilyID: familyID},
+			delete: "WHERE family_id = '" + familyID.String() + "'",
+		},
+		{
+			// Its rotations started a family with its ID
+			name:   "rotated token issued before families",
+			token:  models.RefreshToken{ID: legacyID, RotatedAt: &rotatedAt},
+			delete: "WHERE id = '" + legacyID.String() + "' OR family_id = '" + legacyID.String() + "'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.token.UserID = user.ID
+			tt.token.Source = "extension"
+			tt.token.ExpiresAt = time.Now().Add(time.Hour)
+			db := newFakeDB(t, user, tt.token)
+			db.affected = 0 // The conditional rotation finds the token rotated
+			s := &AuthService{db: db.DB}
+
+			if _, err := s.Refresh(&RefreshRequest{RefreshToken: uuid.NewString()}, ClientInfo{}); err == nil || err.Error() != "invalid refresh token" {
+				t.Fatalf("Refresh() error = %v, want invalid refresh token", err)
+			}
+			if !db.wrote(`DELETE FROM "refresh_tokens"`, tt.delete) {
+				t.Errorf("Refresh() did not revoke the family with %s: %q", tt.delete, db.writes)
+			}
+			if !db.wrote(`INSERT INTO "audit_events"`, AuditRefreshTokenReuse) {
+				t.Errorf("Refresh() did not record the reuse: %q", db.writes)
+			}
+			if db.wrote(`INSERT INTO "refresh_tokens"`) {
+				t.Error("Refresh() issued a new token for a reused one")
+			}
+		})
+	}
+}
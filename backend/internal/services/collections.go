@@ -0,0 +1,740 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/database"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxCollectionNameLength  = 100
+	maxCollectionDepth       = 8
+	maxCollectionNotesChange = 100 // Notes added or reordered per request
+)
+
+// Collection delete modes
+const (
+	CollectionDeleteKeepChildren = "keep_children" // Subcollections move up to the parent (default)
+	CollectionDeleteCascade      = "cascade"       // Subcollections are deleted too; notes are kept
+	CollectionDeleteCascadeNotes = "cascade_notes" // Subcollections are deleted and their notes moved to the trash
+)
+
+type CollectionService struct {
+	db *gorm.DB
+}
+
+type CreateCollectionRequest struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	ParentID    *string `json:"parent_id,omitempty"` // Nil for a top-level collection
+}
+
+// UpdateCollectionRequest renames or redescribes a collection. Nil fields
+// are left as they are.
+type UpdateCollectionRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+// MoveCollectionRequest moves a collection under a new parent, or to the top
+// level when ParentID is nil, at Position among its new siblings. A nil
+// Position puts it last.
+type MoveCollectionRequest struct {
+	ParentID *string `json:"parent_id"`
+	Position *int    `json:"position,omitempty"`
+}
+
+// CollectionNotesRequest lists notes to add to a collection, or the new
+// order of its notes
+type CollectionNotesRequest struct {
+	NoteIDs []string `json:"note_ids"`
+}
+
+type CollectionResponse struct {
+	ID          string               `json:"id"`
+	ParentID    string               `json:"parent_id,omitempty"`
+	Name        string               `json:"name"`
+	Description string               `json:"description,omitempty"`
+	Position    int                  `json:"position"`
+	NoteCount   int64                `json:"note_count"`
+	Children    []CollectionResponse `json:"children"`
+	CreatedAt   string               `json:"created_at"`
+	UpdatedAt   string               `json:"updated_at"`
+}
+
+// NoteCollectionResponse is a collection as shown on a note
+type NoteCollectionResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type DeleteCollectionResponse struct {
+	DeletedCollections int   `json:"deleted_collections"`
+	TrashedNotes       int64 `json:"trashed_notes"`
+}
+
+func NewCollectionService() *CollectionService {
+	return &CollectionService{
+		db: database.DB,
+	}
+}
+
+// ListCollections returns the user's collections as a tree
+func (s *CollectionService) ListCollections(userID string) ([]CollectionResponse, error) {
+	tree, err := s.loadTree(s.db, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.noteCounts(userID)
+	if err != nil {
+		return nil, err
+	}
+	return tree.responses(uuid.Nil, counts), nil
+}
+
+// GetCollection returns a collection with its subcollections
+func (s *CollectionService) GetCollection(userID, collectionID string) (*CollectionResponse, error) {
+	tree, err := s.loadTree(s.db, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := tree.get(collectionID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.noteCounts(userID)
+	if err != nil {
+		return nil, err
+	}
+	response := tree.response(collection, counts)
+	return &response, nil
+}
+
+// CreateCollection creates a collection as the last child of its parent
+func (s *CollectionService) CreateCollection(userID string, req *CreateCollectionRequest) (*CollectionResponse, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+	name, err := normalizeCollectionName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := models.Collection{
+		UserID:      userUUID,
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		tree, err := s.loadTree(tx, userID, true)
+		if err != nil {
+			return err
+		}
+		parentID := uuid.Nil
+		if req.ParentID != nil && *req.ParentID != "" {
+			parent, err := tree.get(*req.ParentID)
+			if err != nil {
+				return errors.New("parent collection not found")
+			}
+			if tree.depth(parent.ID)+1 > maxCollectionDepth {
+				return errors.New("collection too deep")
+			}
+			parentID = parent.ID
+			collection.ParentID = &parent.ID
+		}
+		collection.Position = len(tree.children[parentID])
+		return tx.Create(&collection).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := toCollectionResponse(&collection, 0)
+	return &response, nil
+}
+
+// UpdateCollection renames a collection or changes its description
+func (s *CollectionService) UpdateCollection(userID, collectionID string, req *UpdateCollectionRequest) (*CollectionResponse, error) {
+	tree, err := s.loadTree(s.db, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := tree.get(collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name, err := normalizeCollectionName(*req.Name)
+		if err != nil {
+			return nil, err
+		}
+		collection.Name = name
+	}
+	if req.Description != nil {
+		collection.Description = strings.TrimSpace(*req.Description)
+	}
+	if err := s.db.Model(collection).Select("name", "description").Updates(collection).Error; err != nil {
+		return nil, err
+	}
+
+	counts, err := s.noteCounts(userID)
+	if err != nil {
+		return nil, err
+	}
+	response := tree.response(collection, counts)
+	return &response, nil
+}
+
+// MoveCollection moves a collection, with its subcollections, to a new
+// parent or a new position among its siblings
+func (s *CollectionService) MoveCollection(userID, collectionID string, req *MoveCollectionRequest) (*CollectionResponse, error) {
+	var response CollectionResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		tree, err := s.loadTree(tx, userID, true)
+		if err != nil {
+			return err
+		}
+		collection, err := tree.get(collectionID)
+		if err != nil {
+			return err
+		}
+
+		newParent := uuid.Nil
+		if req.ParentID != nil && *req.ParentID != "" {
+			parent, err := tree.get(*req.ParentID)
+			if err != nil {
+				return errors.New("parent collection not found")
+			}
+			newParent = parent.ID
+		}
+		if err := tree.canMove(collection.ID, newParent); err != nil {
+			return err
+		}
+
+		oldParent := parentKey(collection)
+		position := len(tree.children[newParent])
+		if req.Position != nil {
+			position = *req.Position
+		}
+		if oldParent != newParent {
+			if err := tx.Model(collection).Update("parent_id", nullableID(newParent)).Error; err != nil {
+				return err
+			}
+			collection.ParentID = nullableID(newParent)
+			if err := tree.setChildren(tx, oldParent, removeID(tree.ids(oldParent), collection.ID)); err != nil {
+				return err
+			}
+		}
+		if err := tree.setChildren(tx, newParent, insertID(tree.ids(newParent), collection.ID, position)); err != nil {
+			return err
+		}
+
+		counts, err := s.noteCounts(userID)
+		if err != nil {
+			return err
+		}
+		response = tree.response(collection, counts)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// DeleteCollection deletes a collection. The mode picks what happens to its
+// subcollections and notes; see the CollectionDelete constants.
+func (s *CollectionService) DeleteCollection(userID, collectionID, mode string) (*DeleteCollectionResponse, error) {
+	switch mode {
+	case "":
+		mode = CollectionDeleteKeepChildren
+	case CollectionDeleteKeepChildren, CollectionDeleteCascade, CollectionDeleteCascadeNotes:
+	default:
+		return nil, errors.New("invalid delete mode")
+	}
+
+	response := &DeleteCollectionResponse{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		tree, err := s.loadTree(tx, userID, true)
+		if err != nil {
+			return err
+		}
+		collection, err := tree.get(collectionID)
+		if err != nil {
+			return err
+		}
+		parent := parentKey(collection)
+
+		deleted := []uuid.UUID{collection.ID}
+		if mode == CollectionDeleteKeepChildren {
+			// Children take the collection's place among its siblings
+			var siblings []uuid.UUID
+			for _, id := range tree.ids(parent) {
+				if id == collection.ID {
+					siblings = append(siblings, tree.ids(collection.ID)...)
+				} else {
+					siblings = append(siblings, id)
+				}
+			}
+			err := tx.Model(&models.Collection{}).Where("parent_id = ?", collection.ID).
+				Update("parent_id", nullableID(parent)).Error
+			if err != nil {
+				return err
+			}
+			if err := tree.setChildren(tx, parent, siblings); err != nil {
+				return err
+			}
+		} else {
+			deleted = tree.subtree(collection.ID)
+		}
+
+		if mode == CollectionDeleteCascadeNotes {
+			var noteIDs []uuid.UUID
+			err := tx.Model(&models.CollectionNote{}).Distinct("note_id").
+				Where("collection_id IN ?", deleted).Pluck("note_id", &noteIDs).Error
+			if err != nil {
+				return err
+			}
+			if len(noteIDs) > 0 {
+				result := tx.Where("id IN ? AND user_id = ?", noteIDs, userID).Delete(&models.Note{})
+				if result.Error != nil {
+					return result.Error
+				}
+				response.TrashedNotes = result.RowsAffected
+			}
+		}
+
+		if err := tx.Where("collection_id IN ?", deleted).Delete(&models.CollectionNote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ?", deleted).Delete(&models.Collection{}).Error; err != nil {
+			return err
+		}
+		response.DeletedCollections = len(deleted)
+		if mode != CollectionDeleteKeepChildren {
+			return tree.setChildren(tx, parent, removeID(tree.ids(parent), collection.ID))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// AddNotes appends notes to a collection. Notes already in it keep their
+// place.
+func (s *CollectionService) AddNotes(userID, collectionID string, req *CollectionNotesRequest) error {
+	noteIDs, err := s.ownedNoteIDs(userID, req.NoteIDs)
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		collection, err := s.lockCollection(tx, userID, collectionID)
+		if err != nil {
+			return err
+		}
+		var next int
+		err = tx.Model(&models.CollectionNote{}).Select("COALESCE(MAX(position) + 1, 0)").
+			Where("collection_id = ?", collection.ID).Scan(&next).Error
+		if err != nil {
+			return err
+		}
+		rows := make([]models.CollectionNote, len(noteIDs))
+		for i, noteID := range noteIDs {
+			rows[i] = models.CollectionNote{CollectionID: collection.ID, NoteID: noteID, Position: next + i}
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+	})
+}
+
+// RemoveNote takes a note out of a collection; the note itself is kept
+func (s *CollectionService) RemoveNote(userID, collectionID, noteID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		collection, err := s.lockCollection(tx, userID, collectionID)
+		if err != nil {
+			return err
+		}
+		if _, err := uuid.Parse(noteID); err != nil {
+			return errors.New("note not in collection")
+		}
+		result := tx.Where("collection_id = ? AND note_id = ?", collection.ID, noteID).Delete(&models.CollectionNote{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("note not in collection")
+		}
+		return nil
+	})
+}
+
+// ReorderNotes puts the listed notes first, in the given order. Notes that
+// are not listed follow in their current order.
+func (s *CollectionService) ReorderNotes(userID, collectionID string, req *CollectionNotesRequest) error {
+	if len(req.NoteIDs) > maxCollectionNotesChange {
+		return errors.New("too many notes")
+	}
+	ordered := make([]uuid.UUID, len(req.NoteIDs))
+	for i, id := range req.NoteIDs {
+		noteID, err := uuid.Parse(id)
+		if err != nil {
+			return errors.New("note not in collection")
+		}
+		ordered[i] = noteID
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		collection, err := s.lockCollection(tx, userID, collectionID)
+		if err != nil {
+			return err
+		}
+		var current []uuid.UUID
+		err = tx.Model(&models.CollectionNote{}).Where("collection_id = ?", collection.ID).
+			Order("position, created_at").Pluck("note_id", &current).Error
+		if err != nil {
+			return err
+		}
+		order, err := reorderIDs(current, ordered)
+		if err != nil {
+			return err
+		}
+		for i, noteID := range order {
+			err := tx.Model(&models.CollectionNote{}).
+				Where("collection_id = ? AND note_id = ? AND position <> ?", collection.ID, noteID, i).
+				Update("position", i).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// loadTree loads all of the user's collections. Locking them serializes
+// changes to the tree, so concurrent moves cannot create a cycle.
+func (s *CollectionService) loadTree(db *gorm.DB, userID string, lock bool) (*collectionTree, error) {
+	var collections []models.Collection
+	if lock {
+		db = db.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	if err := db.Where("user_id = ?", userID).Order("position, created_at").Find(&collections).Error; err != nil {
+		return nil, err
+	}
+	return newCollectionTree(collections), nil
+}
+
+// lockCollection loads one of the user's collections for update
+func (s *CollectionService) lockCollection(tx *gorm.DB, userID, collectionID string) (*models.Collection, error) {
+	if _, err := uuid.Parse(collectionID); err != nil {
+		return nil, errors.New("collection not found")
+	}
+	var collection models.Collection
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ?", collectionID, userID).First(&collection).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("collection not found")
+		}
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// noteCounts returns the number of notes in each of the user's collections
+func (s *CollectionService) noteCounts(userID string) (map[uuid.UUID]int64, error) {
+	var rows []struct {
+		CollectionID uuid.UUID
+		Count        int64
+	}
+	err := s.db.Model(&models.CollectionNote{}).
+		Select("collection_notes.collection_id, COUNT(*) AS count").
+		Joins("JOIN collections ON collections.id = collection_notes.collection_id").
+		Joins("JOIN notes ON notes.id = collection_notes.note_id AND notes.deleted_at IS NULL").
+		Where("collections.user_id = ?", userID).
+		Group("collection_notes.collection_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CollectionID] = row.Count
+	}
+	return counts, nil
+}
+
+// ownedNoteIDs parses note IDs, dropping duplicates, and checks that the
+// user owns every note
+func (s *CollectionService) ownedNoteIDs(userID string, ids []string) ([]uuid.UUID, error) {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	var noteIDs []uuid.UUID
+	for _, id := range ids {
+		noteID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, errors.New("note not found")
+		}
+		if !seen[noteID] {
+			seen[noteID] = true
+			noteIDs = append(noteIDs, noteID)
+		}
+	}
+	switch {
+	case len(noteIDs) == 0:
+		return nil, errors.New("no notes given")
+	case len(noteIDs) > maxCollectionNotesChange:
+		return nil, errors.New("too many notes")
+	}
+
+	var owned int64
+	if err := s.db.Model(&models.Note{}).Where("id IN ? AND user_id = ?", noteIDs, userID).Count(&owned).Error; err != nil {
+		return nil, err
+	}
+	if owned != int64(len(noteIDs)) {
+		return nil, errors.New("note not found")
+	}
+	return noteIDs, nil
+}
+
+// collectionTree indexes a user's collections by ID and by parent.
+// Top-level collections are the children of uuid.Nil.
+type collectionTree struct {
+	byID     map[uuid.UUID]*models.Collection
+	children map[uuid.UUID][]*models.Collection
+}
+
+func newCollectionTree(collections []models.Collection) *collectionTree {
+	tree := &collectionTree{
+		byID:     make(map[uuid.UUID]*models.Collection, len(collections)),
+		children: make(map[uuid.UUID][]*models.Collection),
+	}
+	for i := range collections {
+		tree.byID[collections[i].ID] = &collections[i]
+	}
+	for i := range collections {
+		collection := &collections[i]
+		parent := parentKey(collection)
+		if _, ok := tree.byID[parent]; parent != uuid.Nil && !ok {
+			parent = uuid.Nil // Orphans show at the top level
+		}
+		tree.children[parent] = append(tree.children[parent], collection)
+	}
+	for _, siblings := range tree.children {
+		sort.SliceStable(siblings, func(i, j int) bool {
+			return siblings[i].Position < siblings[j].Position
+		})
+	}
+	return tree
+}
+
+func (t *collectionTree) get(id string) (*models.Collection, error) {
+	collectionID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("collection not found")
+	}
+	collection, ok := t.byID[collectionID]
+	if !ok {
+		return nil, errors.New("collection not found")
+	}
+	return collection, nil
+}
+
+// ids returns the IDs of a collection's children, in order
+func (t *collectionTree) ids(parent uuid.UUID) []uuid.UUID {
+	ids := make([]uuid.UUID, len(t.children[parent]))
+	for i, child := range t.children[parent] {
+		ids[i] = child.ID
+	}
+	return ids
+}
+
+// depth returns how deep a collection is; top-level collections have depth 1
+func (t *collectionTree) depth(id uuid.UUID) int {
+	depth := 0
+	for id != uuid.Nil && depth <= len(t.byID) {
+		collection, ok := t.byID[id]
+		if !ok {
+			break
+		}
+		depth++
+		id = parentKey(collection)
+	}
+	return depth
+}
+
+// height returns the number of levels in a collection's subtree, itself
+// included
+func (t *collectionTree) height(id uuid.UUID) int {
+	height := 0
+	for _, child := range t.children[id] {
+		height = max(height, t.height(child.ID))
+	}
+	return height + 1
+}
+
+// subtree returns a collection's ID followed by those of its descendants
+func (t *collectionTree) subtree(id uuid.UUID) []uuid.UUID {
+	ids := []uuid.UUID{id}
+	for _, child := range t.children[id] {
+		ids = append(ids, t.subtree(child.ID)...)
+	}
+	return ids
+}
+
+// canMove checks that a collection can be moved under parent: not into
+// itself or one of its descendants, and not deeper than the maximum depth
+func (t *collectionTree) canMove(id, parent uuid.UUID) error {
+	for ancestor := parent; ancestor != uuid.Nil; {
+		if ancestor == id {
+			return errors.New("cannot move collection into itself")
+		}
+		collection, ok := t.byID[ancestor]
+		if !ok {
+			break
+		}
+		ancestor = parentKey(collection)
+	}
+	depth := 0
+	if parent != uuid.Nil {
+		depth = t.depth(parent)
+	}
+	if depth+t.height(id) > maxCollectionDepth {
+		return errors.New("collection too deep")
+	}
+	return nil
+}
+
+// setChildren stores the order of a collection's children, writing only
+// the positions that changed
+func (t *collectionTree) setChildren(tx *gorm.DB, parent uuid.UUID, ids []uuid.UUID) error {
+	children := make([]*models.Collection, len(ids))
+	for i, id := range ids {
+		collection := t.byID[id]
+		if collection.Position != i {
+			if err := tx.Model(collection).Update("position", i).Error; err != nil {
+				return err
+			}
+			collection.Position = i
+		}
+		children[i] = collection
+	}
+	t.children[parent] = children
+	return nil
+}
+
+func (t *collectionTree) responses(parent uuid.UUID, counts map[uuid.UUID]int64) []CollectionResponse {
+	responses := make([]CollectionResponse, len(t.children[parent]))
+	for i, child := range t.children[parent] {
+		responses[i] = t.response(child, counts)
+	}
+	return responses
+}
+
+func (t *collectionTree) response(collection *models.Collection, counts map[uuid.UUID]int64) CollectionResponse {
+	response := toCollectionResponse(collection, counts[collection.ID])
+	response.Children = t.responses(collection.ID, counts)
+	return response
+}
+
+// parentKey returns a collection's parent ID, or uuid.Nil at the top level
+func parentKey(collection *models.Collection) uuid.UUID {
+	if collection.ParentID == nil {
+		return uuid.Nil
+	}
+	return *collection.ParentID
+}
+
+func nullableID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
+
+// insertID moves id to position in ids, clamping the position to the list
+func insertID(ids []uuid.UUID, id uuid.UUID, position int) []uuid.UUID {
+	ids = removeID(ids, id)
+	position = min(max(position, 0), len(ids))
+	result := make([]uuid.UUID, 0, len(ids)+1)
+	result = append(result, ids[:position]...)
+	result = append(result, id)
+	return append(result, ids[position:]...)
+}
+
+func removeID(ids []uuid.UUID, id uuid.UUID) []uuid.UUID {
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, other := range ids {
+		if other != id {
+			result = append(result, other)
+		}
+	}
+	return result
+}
+
+// reorderIDs puts the ordered IDs first and the rest of current after them,
+// keeping their relative order. Every ordered ID must be in current.
+func reorderIDs(current, ordered []uuid.UUID) ([]uuid.UUID, error) {
+	present := make(map[uuid.UUID]bool, len(current))
+	for _, id := range current {
+		present[id] = true
+	}
+	placed := make(map[uuid.UUID]bool, len(ordered))
+	result := make([]uuid.UUID, 0, len(current))
+	for _, id := range ordered {
+		if !present[id] {
+			return nil, errors.New("note not in collection")
+		}
+		if !placed[id] {
+			placed[id] = true
+			result = append(result, id)
+		}
+	}
+	for _, id := range current {
+		if !placed[id] {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
+
+func normalizeCollectionName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	switch {
+	case name == "":
+		return "", errors.New("collection name is required")
+	case utf8.RuneCountInString(name) > maxCollectionNameLength:
+		return "", errors.New("collection name too long")
+	}
+	return name, nil
+}
+
+// orderCollections sorts preloaded collections by name
+func orderCollections(db *gorm.DB) *gorm.DB {
+	return db.Order("LOWER(collections.name)")
+}
+
+func toCollectionResponse(collection *models.Collection, noteCount int64) CollectionResponse {
+	response := CollectionResponse{
+		ID:          collection.ID.String(),
+		Name:        collection.Name,
+		Description: collection.Description,
+		Position:    collection.Position,
+		NoteCount:   noteCount,
+		Children:    []CollectionResponse{},
+		CreatedAt:   collection.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:   collection.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if collection.ParentID != nil {
+		response.ParentID = collection.ParentID.String()
+	}
+	return response
+}
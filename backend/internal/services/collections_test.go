@@ -0,0 +1,97 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+)
+
+// chain returns n collections, each nested in the one before
+func chain(n int) []models.Collection {
+	collections := make([]models.Collection, n)
+	for i := range collections {
+		collections[i].ID = uuid.New()
+		if i > 0 {
+			collections[i].ParentID = &collections[i-1].ID
+		}
+	}
+	return collections
+}
+
+func TestCollectionTreeCanMove(t *testing.T) {
+	collections := append(chain(3), models.Collection{ID: uuid.New()})
+	tree := newCollectionTree(collections)
+	root, middle, leaf, other := collections[0].ID, collections[1].ID, collections[2].ID, collections[3].ID
+
+	if got := tree.depth(leaf); got != 3 {
+		t.Errorf("depth(leaf) = %d, want 3", got)
+	}
+	if got := tree.height(root); got != 3 {
+		t.Errorf("height(root) = %d, want 3", got)
+	}
+	if got := tree.subtree(middle); !slices.Equal(got, []uuid.UUID{middle, leaf}) {
+		t.Errorf("subtree(middle) = %v, want [middle leaf]", got)
+	}
+
+	for _, parent := range []uuid.UUID{root, middle, leaf} {
+		if err := tree.canMove(root, parent); err == nil || err.Error() != "cannot move collection into itself" {
+			t.Errorf("canMove(root, descendant) error = %v, want a cycle error", err)
+		}
+	}
+	if err := tree.canMove(middle, other); err != nil {
+		t.Errorf("canMove(middle, other) = %v, want nil", err)
+	}
+	if err := tree.canMove(leaf, uuid.Nil); err != nil {
+		t.Errorf("canMove(leaf, top level) = %v, want nil", err)
+	}
+}
+
+func TestCollectionTreeMaxDepth(t *testing.T) {
+	deep := chain(maxCollectionDepth)
+	branch := chain(2)
+	tree := newCollectionTree(append(deep, branch...))
+
+	if err := tree.canMove(branch[0].ID, deep[maxCollectionDepth-3].ID); err != nil {
+		t.Errorf("moving a 2-level branch to depth %d = %v, want nil", maxCollectionDepth, err)
+	}
+	if err := tree.canMove(branch[0].ID, deep[maxCollectionDepth-2].ID); err == nil || err.Error() != "collection too deep" {
+		t.Errorf("moving a 2-level branch past the max depth error = %v, want collection too deep", err)
+	}
+}
+
+func TestInsertID(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	tests := []struct {
+		ids      []uuid.UUID
+		id       uuid.UUID
+		position int
+		want     []uuid.UUID
+	}{
+		{[]uuid.UUID{a, b}, c, 0, []uuid.UUID{c, a, b}},
+		{[]uuid.UUID{a, b}, c, 1, []uuid.UUID{a, c, b}},
+		{[]uuid.UUID{a, b}, c, 99, []uuid.UUID{a, b, c}},
+		{[]uuid.UUID{a, b}, c, -1, []uuid.UUID{c, a, b}},
+		{[]uuid.UUID{a, b, c}, a, 2, []uuid.UUID{b, c, a}},
+		{[]uuid.UUID{a, b, c}, c, 0, []uuid.UUID{c, a, b}},
+	}
+	for _, tt := range tests {
+		if got := insertID(tt.ids, tt.id, tt.position); !slices.Equal(got, tt.want) {
+			t.Errorf("insertID(%v, %v, %d) = %v, want %v", tt.ids, tt.id, tt.position, got, tt.want)
+		}
+	}
+}
+
+func TestReorderIDs(t *testing.T) {
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	current := []uuid.UUID{a, b, c, d}
+
+	got, err := reorderIDs(current, []uuid.UUID{c, a, c})
+	if err != nil || !slices.Equal(got, []uuid.UUID{c, a, b, d}) {
+		t.Errorf("reorderIDs() = %v, %v, want [c a b d]", got, err)
+	}
+	if _, err := reorderIDs(current, []uuid.UUID{uuid.New()}); err == nil {
+		t.Error("reorderIDs() with a note outside the collection succeeded, want an error")
+	}
+}
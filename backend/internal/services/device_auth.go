@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/database"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// DeviceAuthService implements the OAuth 2.0 device authorization grant
+// (RFC 8628) used to pair the browser extension with a web session.
+type DeviceAuthService struct {
+	db          *gorm.DB
+	cfg         *config.Config
+	authService *AuthService
+}
+
+const (
+	deviceCodeLifetime     = 10 * time.Minute
+	deviceCodePollInterval = 5 // seconds
+	deviceCodeSlowDown     = 5 // seconds added to the interval on slow_down
+
+	// Consonants only, so user codes never spell words and are easy to read
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+)
+
+type DeviceAuthorizationRequest struct {
+	DeviceName string `json:"device_name,omitempty"`
+}
+
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type DeviceTokenRequest struct {
+	DeviceCode string `json:"device_code"`
+}
+
+type DeviceApprovalRequest struct {
+	UserCode string `json:"user_code"`
+	Approve  bool   `json:"approve"`
+}
+
+type PendingDeviceResponse struct {
+	UserCode   string `json:"user_code"`
+	DeviceInfo string `json:"device_info,omitempty"`
+	IPAddress  string `json:"ip_address,omitempty"`
+	CreatedAt  string `json:"created_at"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+func NewDeviceAuthService(cfg *config.Config) *DeviceAuthService {
+	return &DeviceAuthService{
+		db:          database.DB,
+		cfg:         cfg,
+		authService: NewAuthService(cfg),
+	}
+}
+
+// StartAuthorization creates a pending device code for the extension
+func (s *DeviceAuthService) StartAuthorization(req *DeviceAuthorizationRequest, client ClientInfo) (*DeviceAuthorizationResponse, error) {
+	deviceCode, err := generateSecret(32)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceInfo := strings.TrimSpace(req.DeviceName)
+	if deviceInfo == "" {
+		deviceInfo = describeUserAgent(client.UserAgent)
+	}
+
+	var record models.DeviceCode
+	// Retry on the rare user code collision
+	for attempt := 0; attempt < 3; attempt++ {
+		userCode, err := generateUserCode()
+		if err != nil {
+			return nil, err
+		}
+		record = models.DeviceCode{
+			DeviceCodeHash: hashSecret(deviceCode),
+			UserCode:       userCode,
+			Status:         models.DeviceCodePending,
+			DeviceInfo:     deviceInfo,
+			UserAgent:      client.UserAgent,
+			IPAddress:      client.IP,
+			Interval:       deviceCodePollInterval,
+			ExpiresAt:      time.Now().Add(deviceCodeLifetime),
+		}
+		if err = s.db.Create(&record).Error; err == nil {
+			break
+		}
+		if !errors.Is(err, gorm.ErrDuplicatedKey) && !strings.Contains(err.Error(), "duplicate key") {
+			return nil, err
+		}
+		if attempt == 2 {
+			return nil, err
+		}
+	}
+
+	verificationURI := s.cfg.AppURL + "/device"
+	return &DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                formatUserCode(record.UserCode),
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?code=" + formatUserCode(record.UserCode),
+		ExpiresIn:               int(deviceCodeLifetime.Seconds()),
+		Interval:                record.Interval,
+	}, nil
+}
+
+// PollToken exchanges an approved device code for extension tokens. Until
+// the code is approved it returns "authorization_pending", or "slow_down" if
+// the client polls faster than the interval. A code can be exchanged once.
+func (s *DeviceAuthService) PollToken(req *DeviceTokenRequest, client ClientInfo) (*AuthResponse, error) {
+	var record models.DeviceCode
+	if err := s.db.Where("device_code_hash = ?", hashSecret(req.DeviceCode)).First(&record).Error; err != nil {
+		return nil, errors.New("invalid device code")
+	}
+
+	now := time.Now()
+	if record.ExpiresAt.Before(now) {
+		return nil, errors.New("expired token")
+	}
+
+	switch record.Status {
+	case models.DeviceCodeDenied:
+		return nil, errors.New("access denied")
+	case models.DeviceCodeConsumed:
+		return nil, errors.New("invalid device code")
+	case models.DeviceCodePending:
+		tooSoon := record.LastPolledAt != nil &&
+			now.Sub(*record.LastPolledAt) < time.Duration(record.Interval)*time.Second
+		updates := map[string]any{"last_polled_at": now}
+		if tooSoon {
+			updates["interval"] = record.Interval + deviceCodeSlowDown
+		}
+		if err := s.db.Model(&record).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		if tooSoon {
+			return nil, errors.New("slow down")
+		}
+		return nil, errors.New("authorization pending")
+	}
+
+	// Approved: consume the code exactly once
+	result := s.db.Model(&models.DeviceCode{}).
+		Where("id = ? AND status = ?", record.ID, models.DeviceCodeApproved).
+		Update("status", models.DeviceCodeConsumed)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || record.UserID == nil {
+		return nil, errors.New("invalid device code")
+	}
+
+	user, err := s.authService.GetUserByID(record.UserID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the device as seen when it started pairing
+	device := ClientInfo{IP: record.IPAddress, UserAgent: record.UserAgent, Source: "extension"}
+	if device.IP == "" {
+		device = client
+		device.Source = "extension"
+	}
+	// The account may have been disabled since the code was approved
+	if user.DisabledAt != nil {
+		recordAuditFailure(s.db, user.ID, AuditLogin, device, map[string]any{
+			"email":  user.Email,
+			"reason": "account_disabled",
+		})
+		return nil, errAccountDisabled
+	}
+
+	deviceInfo := record.DeviceInfo
+	if deviceInfo == "" {
+		deviceInfo = describeUserAgent(device.UserAgent)
+	}
+	return s.authService.openSession(user, models.RefreshToken{
+		Source:     "extension",
+		DeviceInfo: deviceInfo,
+		UserAgent:  device.UserAgent,
+		IPAddress:  device.IP,
+	}, "device_code", device)
+}
+
+// GetPendingDevice describes a pending device so the user can check it is
+// theirs before approving
+func (s *DeviceAuthService) GetPendingDevice(userCode string) (*PendingDeviceResponse, error) {
+	record, err := s.findPending(userCode)
+	if err != nil {
+		return nil, err
+	}
+	return &PendingDeviceResponse{
+		UserCode:   formatUserCode(record.UserCode),
+		DeviceInfo: record.DeviceInfo,
+		IPAddress:  record.IPAddress,
+		CreatedAt:  record.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ExpiresAt:  record.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+	}, nil
+}
+
+// ApproveDevice approves or denies a pending device code for the user
+func (s *DeviceAuthService) ApproveDevice(userID string, req *DeviceApprovalRequest) error {
+	record, err := s.findPending(req.UserCode)
+	if err != nil {
+		return err
+	}
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return errors.New("invalid user ID")
+	}
+
+	status := models.DeviceCodeDenied
+	if req.Approve {
+		status = models.DeviceCodeApproved
+	}
+	result := s.db.Model(&models.DeviceCode{}).
+		Where("id = ? AND status = ?", record.ID, models.DeviceCodePending).
+		Updates(map[string]any{"status": status, "user_id": userUUID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("device code not found")
+	}
+	return nil
+}
+
+// CleanupExpiredDeviceCodes deletes device codes past their expiry
+func (s *DeviceAuthService) CleanupExpiredDeviceCodes(ctx context.Context) error {
+	return s.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&models.DeviceCode{}).Error
+}
+
+func (s *DeviceAuthService) findPending(userCode string) (*models.DeviceCode, error) {
+	var record models.DeviceCode
+	err := s.db.Where("user_code = ? AND status = ? AND expires_at > ?",
+		normalizeUserCode(userCode), models.DeviceCodePending, time.Now()).
+		First(&record).Error
+	if err != nil {
+		return nil, errors.New("device code not found")
+	}
+	return &record, nil
+}
+
+func generateUserCode() (string, error) {
+	var b strings.Builder
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	for i := 0; i < userCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(userCodeAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// formatUserCode renders a stored user code as XXXX-XXXX
+func formatUserCode(code string) string {
+	if len(code) != userCodeLength {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
+
+// normalizeUserCode accepts user input with any case, spaces or dashes
+func normalizeUserCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(userCodeAlphabet, r) {
+			return r
+		}
+		return -1
+	}, code)
+}
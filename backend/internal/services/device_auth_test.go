@@ -0,0 +1,177 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+)
+
+func newTestDeviceAuthService(db *fakeDB) *DeviceAuthService {
+	return &DeviceAuthService{db: db.DB, cfg: &config.Config{AppURL: "https://app.example.com"}, authService: &AuthService{db: db.DB}}
+}
+
+// deviceCode returns a code the extension started pairing with a minute ago
+func deviceCode(status string) models.DeviceCode {
+	return models.DeviceCode{
+		ID:             uuid.New(),
+		DeviceCodeHash: hashSecret("device-code"),
+		UserCode:       "BCDFGHJK",
+		Status:         status,
+		DeviceInfo:     "Chrome on macOS",
+		IPAddress:      "192.0.2.1",
+		Interval:       deviceCodePollInterval,
+		ExpiresAt:      time.Now().Add(deviceCodeLifetime - time.Minute),
+	}
+}
+
+func TestStartDeviceAuthorization(t *testing.T) {
+	db := newFakeDB(t)
+	response, err := newTestDeviceAuthService(db).StartAuthorization(&DeviceAuthorizationRequest{DeviceName: " Laptop "}, ClientInfo{IP: "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("StartAuthorization() error = %v", err)
+	}
+	userCode := normalizeUserCode(response.UserCode)
+	if len(userCode) != userCodeLength || response.UserCode != formatUserCode(userCode) {
+		t.Errorf("StartAuthorization() user code = %q, want XXXX-XXXX", response.UserCode)
+	}
+	if response.VerificationURIComplete != "https://app.example.com/device?code="+response.UserCode ||
+		response.Interval != deviceCodePollInterval || response.ExpiresIn != int(deviceCodeLifetime.Seconds()) {
+		t.Errorf("StartAuthorization() = %+v", response)
+	}
+	if !db.wrote(`INSERT INTO "device_codes"`, hashSecret(response.DeviceCode), userCode, "Laptop", models.DeviceCodePending) ||
+		db.wrote(response.DeviceCode) {
+		t.Errorf("StartAuthorization() did not store only the device code hash: %q", db.writes)
+	}
+}
+
+func TestPollDeviceTokenBeforeApproval(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		row        func(*models.DeviceCode)
+		want       string
+		polled     bool
+		slowedDown bool
+	}{
+		{name: "first poll", row: func(*models.DeviceCode) {}, want: "authorization pending", polled: true},
+		{name: "polled after the interval", row: func(r *models.DeviceCode) {
+			polled := now.Add(-time.Duration(r.Interval+1) * time.Second)
+			r.LastPolledAt = &polled
+		}, want: "authorization pending", polled: true},
+		{name: "polled too soon", row: func(r *models.DeviceCode) {
+			polled := now.Add(-time.Second)
+			r.LastPolledAt = &polled
+		}, want: "slow down", polled: true, slowedDown: true},
+		{name: "expired", row: func(r *models.DeviceCode) { r.ExpiresAt = now.Add(-time.Second) }, want: "expired token"},
+		{name: "denied", row: func(r *models.DeviceCode) { r.Status = models.DeviceCodeDenied }, want: "access denied"},
+		{name: "already exchanged", row: func(r *models.DeviceCode) { r.Status = models.DeviceCodeConsumed }, want: "invalid device code"},
+	}
+	for _, tt := range tests {
+		record := deviceCode(models.DeviceCodePending)
+		tt.row(&record)
+		db := newFakeDB(t, record)
+
+		if _, err := newTestDeviceAuthService(db).PollToken(&DeviceTokenRequest{DeviceCode: "device-code"}, ClientInfo{}); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: PollToken() error = %v, want %s", tt.name, err, tt.want)
+		}
+		if !db.queried("device_code_hash = '" + hashSecret("device-code") + "'") {
+			t.Errorf("%s: PollToken() did not look up the code by hash: %q", tt.name, db.queries)
+		}
+		if db.wrote(`"last_polled_at"=`, record.ID.String()) != tt.polled {
+			t.Errorf("%s: PollToken() writes = %q, want the poll recorded %v", tt.name, db.writes, tt.polled)
+		}
+		// Each poll that comes too soon adds to the interval
+		if db.wrote(`"interval"=10`) != tt.slowedDown {
+			t.Errorf("%s: PollToken() writes = %q, want the interval raised %v", tt.name, db.writes, tt.slowedDown)
+		}
+		if db.wrote(`INSERT INTO "refresh_tokens"`) {
+			t.Errorf("%s: PollToken() opened a session: %q", tt.name, db.writes)
+		}
+	}
+
+	if _, err := newTestDeviceAuthService(newFakeDB(t)).PollToken(&DeviceTokenRequest{DeviceCode: "unknown"}, ClientInfo{}); err == nil || err.Error() != "invalid device code" {
+		t.Errorf("PollToken() error = %v for an unknown code, want invalid device code", err)
+	}
+}
+
+func TestPollDeviceTokenAfterApproval(t *testing.T) {
+	useTestKeys(t, "secret")
+	user := models.User{ID: uuid.New(), Email: "a@example.com"}
+	record := deviceCode(models.DeviceCodeApproved)
+	record.UserID = &user.ID
+
+	db := newFakeDB(t, record, user)
+	response, err := newTestDeviceAuthService(db).PollToken(&DeviceTokenRequest{DeviceCode: "device-code"}, ClientInfo{IP: "198.51.100.1"})
+	if err != nil {
+		t.Fatalf("PollToken() error = %v", err)
+	}
+	if response.AccessToken == "" || response.RefreshToken == "" || response.User.ID != user.ID.String() {
+		t.Errorf("PollToken() = %+v", response)
+	}
+	// Consumed only if still approved, so a second poll cannot get tokens too
+	consumed := db.writeIndex(`UPDATE "device_codes" SET "status"='consumed'`,
+		"id = '"+record.ID.String()+"' AND status = 'approved'")
+	opened := db.writeIndex(`INSERT INTO "refresh_tokens"`, user.ID.String(), "extension", "Chrome on macOS", "192.0.2.1")
+	if consumed < 0 || opened < consumed {
+		t.Errorf("PollToken() did not consume the code before opening the session: %q", db.writes)
+	}
+
+	// A concurrent poll consumed it first
+	db = newFakeDB(t, record, user)
+	db.affected = 0
+	if _, err := newTestDeviceAuthService(db).PollToken(&DeviceTokenRequest{DeviceCode: "device-code"}, ClientInfo{}); err == nil || err.Error() != "invalid device code" {
+		t.Errorf("PollToken() error = %v for a code consumed concurrently, want invalid device code", err)
+	}
+	if db.wrote(`INSERT INTO "refresh_tokens"`) {
+		t.Errorf("PollToken() opened a second session: %q", db.writes)
+	}
+
+	// The account was disabled after the code was approved
+	disabledAt := time.Now()
+	user.DisabledAt = &disabledAt
+	db = newFakeDB(t, record, user)
+	if _, err := newTestDeviceAuthService(db).PollToken(&DeviceTokenRequest{DeviceCode: "device-code"}, ClientInfo{}); err != errAccountDisabled {
+		t.Errorf("PollToken() error = %v for a disabled user, want %v", err, errAccountDisabled)
+	}
+	if db.wrote(`INSERT INTO "refresh_tokens"`) {
+		t.Errorf("PollToken() opened a session for a disabled user: %q", db.writes)
+	}
+	if !db.wrote(`INSERT INTO "audit_events"`, AuditLogin, "account_disabled") {
+		t.Errorf("PollToken() did not record the refused login: %q", db.writes)
+	}
+}
+
+func TestApproveDevice(t *testing.T) {
+	userID := uuid.New()
+	record := deviceCode(models.DeviceCodePending)
+
+	for _, approve := range []bool{true, false} {
+		db := newFakeDB(t, record)
+		s := newTestDeviceAuthService(db)
+		if err := s.ApproveDevice(userID.String(), &DeviceApprovalRequest{UserCode: "bcdf-ghjk", Approve: approve}); err != nil {
+			t.Fatalf("ApproveDevice() error = %v", err)
+		}
+		if !db.queried("user_code = 'BCDFGHJK' AND status = 'pending' AND expires_at > ") {
+			t.Errorf("ApproveDevice() did not look up a pending code: %q", db.queries)
+		}
+		status := models.DeviceCodeDenied
+		if approve {
+			status = models.DeviceCodeApproved
+		}
+		if !db.wrote(`UPDATE "device_codes" SET`, `"status"='`+status+`'`, `"user_id"='`+userID.String()+`'`,
+			"id = '"+record.ID.String()+"' AND status = 'pending'") {
+			t.Errorf("ApproveDevice(%v) writes = %q, want status %s", approve, db.writes, status)
+		}
+	}
+
+	// Approved or denied by someone else in the meantime
+	db := newFakeDB(t, record)
+	db.affected = 0
+	if err := newTestDeviceAuthService(db).ApproveDevice(userID.String(), &DeviceApprovalRequest{UserCode: "BCDFGHJK", Approve: true}); err == nil ||
+		err.Error() != "device code not found" {
+		t.Errorf("ApproveDevice() error = %v for a code no longer pending, want device code not found", err)
+	}
+}
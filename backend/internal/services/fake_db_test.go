@@ -0,0 +1,327 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// fakeDB is a dry-run database for testing services without Postgres.
+// Queries return the row of their result type given to newFakeDB, whatever
+// their conditions; their SQL is kept in queries. Several rows of a type are
+// returned in turn, the last one to every later query, and missing(row)
+// stands for a query that finds nothing. Preloads are not run, so give rows
+// with their associations set. Scan finds nothing unless a value was given
+// with scan. Writes are not run but their SQL is kept in writes, along with
+// BEGIN, COMMIT and ROLLBACK of transactions. Updates and deletes report
+// affected rows, 1 unless set otherwise.
+type fakeDB struct {
+	*gorm.DB
+	rows     []any
+	scans    []fakeScan
+	queries  []string
+	writes   []string
+	affected int64
+
+	sqlDB   *sql.DB
+	scanned any // Value for the next query on sqlDB
+}
+
+type fakeScan struct {
+	query string
+	value any
+}
+
+func newFakeDB(t *testing.T, rows ...any) *fakeDB {
+	t.Helper()
+	f := &fakeDB{rows: rows, affected: 1}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: fakeConnPool{f}}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+		Logger:                 logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.DB = db
+
+	if err := db.Callback().Query().Replace("gorm:query", f.query); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Query().Replace("gorm:preload", func(*gorm.DB) {}); err != nil {
+		t.Fatal(err)
+	}
+	f.sqlDB = sql.OpenDB(fakeConnector{f})
+	t.Cleanup(func() { f.sqlDB.Close() })
+	if err := db.Callback().Row().Replace("gorm:row", f.row); err != nil {
+		t.Fatal(err)
+	}
+	record := func(db *gorm.DB) {
+		// Statements turned away by a hook are not run
+		if db.Error != nil {
+			return
+		}
+		f.writes = append(f.writes, db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...))
+	}
+	affect := func(db *gorm.DB) {
+		record(db)
+		db.RowsAffected = f.affected
+	}
+	if err := db.Callback().Create().After("gorm:create").Register("test:record", record); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("test:record", affect); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register("test:record", affect); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func (f *fakeDB) query(db *gorm.DB) {
+	// Subqueries are built by running the query callbacks
+	callbacks.BuildQuerySQL(db)
+	f.queries = append(f.queries, db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...))
+	dest := reflect.ValueOf(db.Statement.Dest)
+	if dest.Kind() != reflect.Pointer {
+		return
+	}
+	for i, row := range f.rows {
+		found := true
+		if m, ok := row.(missingRow); ok {
+			row, found = m.row, false
+		}
+		value := reflect.ValueOf(row)
+		if value.Kind() == reflect.Pointer {
+			value = value.Elem()
+		}
+		if value.Type() != dest.Elem().Type() {
+			continue
+		}
+		if f.hasLaterRow(i, value.Type()) {
+			f.rows = slices.Delete(f.rows, i, i+1)
+		}
+		if found {
+			dest.Elem().Set(value)
+			db.RowsAffected = 1
+			return
+		}
+		break
+	}
+	if db.Statement.RaiseErrorOnNotFound {
+		db.AddError(gorm.ErrRecordNotFound)
+	}
+}
+
+// scan makes Scan return value for queries containing query. A struct is
+// a row with a column per field, a slice several rows, and anything else a
+// single column.
+func (f *fakeDB) scan(query string, value any) {
+	f.scans = append(f.scans, fakeScan{query, value})
+}
+
+func (f *fakeDB) row(db *gorm.DB) {
+	callbacks.BuildQuerySQL(db)
+	if db.Error != nil {
+		return
+	}
+	query := db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...)
+	f.queries = append(f.queries, query)
+
+	f.scanned = nil
+	for _, scan := range f.scans {
+		if strings.Contains(query, scan.query) {
+			f.scanned = scan.value
+			break
+		}
+	}
+	if isRows, ok := db.Get("rows"); ok && isRows.(bool) {
+		db.Statement.Settings.Delete("rows")
+		db.Statement.Dest, db.Error = f.sqlDB.QueryContext(db.Statement.Context, query)
+	} else {
+		db.Statement.Dest = f.sqlDB.QueryRowContext(db.Statement.Context, query)
+	}
+	db.RowsAffected = -1
+}
+
+// missingRow makes a query for the type of row find nothing
+type missingRow struct{ row any }
+
+func missing(row any) any {
+	return missingRow{row}
+}
+
+func (f *fakeDB) hasLaterRow(i int, typ reflect.Type) bool {
+	for _, row := range f.rows[i+1:] {
+		if m, ok := row.(missingRow); ok {
+			row = m.row
+		}
+		value := reflect.ValueOf(row)
+		if value.Kind() == reflect.Pointer {
+			value = value.Elem()
+		}
+		if value.Type() == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// wrote reports whether a write contained every one of parts
+func (f *fakeDB) wrote(parts ...string) bool {
+	return f.writeIndex(parts...) >= 0
+}
+
+// writeIndex returns the index in writes of the first write containing
+// every one of parts, or -1
+func (f *fakeDB) writeIndex(parts ...string) int {
+	return statementIndex(f.writes, parts)
+}
+
+// queried reports whether a query contained every one of parts
+func (f *fakeDB) queried(parts ...string) bool {
+	return statementIndex(f.queries, parts) >= 0
+}
+
+func statementIndex(statements, parts []string) int {
+	for i, statement := range statements {
+		found := true
+		for _, part := range parts {
+			if !strings.Contains(statement, part) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return i
+		}
+	}
+	return -1
+}
+
+// fakeConnPool lets dry-run transactions begin and commit, and records them
+// in the writes of its fakeDB. Nothing is ever run on it.
+type fakeConnPool struct{ f *fakeDB }
+
+var errFakeConnPool = errors.New("fake database does not run statements")
+
+func (fakeConnPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errFakeConnPool
+}
+
+func (fakeConnPool) ExecContext(context.Context, string, ...any) (sql.Result, error) {
+	return nil, errFakeConnPool
+}
+
+func (fakeConnPool) QueryContext(context.Context, string, ...any) (*sql.Rows, error) {
+	return nil, errFakeConnPool
+}
+
+func (fakeConnPool) QueryRowContext(context.Context, string, ...any) *sql.Row {
+	return nil
+}
+
+func (p fakeConnPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	p.f.writes = append(p.f.writes, "BEGIN")
+	return &fakeTx{p}, nil
+}
+
+type fakeTx struct{ fakeConnPool }
+
+func (tx *fakeTx) Commit() error {
+	tx.f.writes = append(tx.f.writes, "COMMIT")
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.f.writes = append(tx.f.writes, "ROLLBACK")
+	return nil
+}
+
+// fakeConnector opens connections that answer every query with the value
+// its fakeDB was told to scan
+type fakeConnector struct{ f *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (fakeConnector) Driver() driver.Driver                          { return nil }
+
+type fakeConn struct{ f *fakeDB }
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errFakeConnPool }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errFakeConnPool }
+
+func (c fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	rows := &fakeRows{}
+	if c.f.scanned == nil {
+		return rows, nil
+	}
+	value := reflect.ValueOf(c.f.scanned)
+	values := []reflect.Value{value}
+	if value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8 {
+		values = values[:0]
+		for i := range value.Len() {
+			values = append(values, value.Index(i))
+		}
+	}
+	for _, value := range values {
+		if value.Kind() != reflect.Struct || value.Type().Implements(reflect.TypeFor[driver.Valuer]()) {
+			rows.columns = []string{"value"}
+			rows.values = append(rows.values, []driver.Value{driverValue(value)})
+			continue
+		}
+		rows.columns = rows.columns[:0]
+		var row []driver.Value
+		for i := range value.NumField() {
+			if field := value.Type().Field(i); field.IsExported() {
+				rows.columns = append(rows.columns, schema.NamingStrategy{}.ColumnName("", field.Name))
+				row = append(row, driverValue(value.Field(i)))
+			}
+		}
+		rows.values = append(rows.values, row)
+	}
+	return rows, nil
+}
+
+func driverValue(value reflect.Value) driver.Value {
+	if valuer, ok := value.Interface().(driver.Valuer); ok {
+		v, _ := valuer.Value()
+		return v
+	}
+	v, err := driver.DefaultParameterConverter.ConvertValue(value.Interface())
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/lockout"
+	"gorm.io/gorm"
+)
+
+// Failed logins are counted per account and per client IP. The IP policy is
+// looser because many users can share an address.
+var (
+	loginAttempts lockout.Store = lockout.NewMemoryStore()
+
+	accountLoginLimiter = lockout.New(loginAttempts, lockout.Policy{
+		Window:    15 * time.Minute,
+		Threshold: 5,
+		BaseDelay: 30 * time.Second,
+		MaxDelay:  15 * time.Minute,
+	})
+	ipLoginLimiter = lockout.New(loginAttempts, lockout.Policy{
+		Window:    15 * time.Minute,
+		Threshold: 20,
+		BaseDelay: time.Minute,
+		MaxDelay:  time.Hour,
+	})
+)
+
+func accountLoginKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipLoginKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLoginAllowed returns a *lockout.LockedError when the account or the
+// client IP is locked out
+func checkLoginAllowed(email string, client ClientInfo) error {
+	if err := accountLoginLimiter.Check(accountLoginKey(email)); err != nil {
+		return err
+	}
+	return ipLoginLimiter.Check(ipLoginKey(client.IP))
+}
+
+// recordLoginFailure counts a failed login and audits it. It returns a
+// *lockout.LockedError if the failure locked the account or IP, and
+// otherwise fallback.
+func recordLoginFailure(db *gorm.DB, userID uuid.UUID, email, reason string, client ClientInfo, fallback error) error {
+	recordAuditFailure(db, userID, AuditLogin, client, map[string]any{
+		"email":  email,
+		"reason": reason,
+	})
+
+	var locked *lockout.LockedError
+	for _, err := range []error{
+		accountLoginLimiter.Fail(accountLoginKey(email)),
+		ipLoginLimiter.Fail(ipLoginKey(client.IP)),
+	} {
+		if errors.As(err, &locked) {
+			recordAuditFailure(db, userID, AuditAccountLocked, client, map[string]any{
+				"key":         locked.Key,
+				"retry_after": int(locked.RetryAfter.Seconds()),
+			})
+			return locked
+		}
+	}
+	return fallback
+}
+
+// resetLoginFailures clears the account's failures after a successful login.
+// The IP count is kept so one good login cannot hide a spraying attack.
+func resetLoginFailures(email string) {
+	accountLoginLimiter.Reset(accountLoginKey(email))
+}
@@ -0,0 +1,479 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Bulk note actions
+const (
+	BulkActionDelete           = "delete" // Moves the notes to the trash
+	BulkActionSetDomain        = "set_domain"
+	BulkActionMergeMetadata    = "merge_metadata" // Top-level keys; a null value removes the key
+	BulkActionAddTags          = "add_tags"
+	BulkActionRemoveTags       = "remove_tags"
+	BulkActionMoveToCollection = "move_to_collection" // Takes the notes out of their other collections
+	BulkActionSummarize        = "summarize"          // Queues the notes for the summary job
+)
+
+// Outcomes of a bulk action for one note
+const (
+	BulkStatusChanged   = "changed"
+	BulkStatusUnchanged = "unchanged"
+	BulkStatusQueued    = "queued"
+	BulkStatusNotFound  = "not_found"
+	BulkStatusFailed    = "failed"
+)
+
+const (
+	// maxBulkNotes bounds how many notes one bulk request can change
+	maxBulkNotes = 1000
+	// summaryBatchSize bounds how many queued notes one job run summarizes
+	summaryBatchSize = 20
+	// maxSummaryAttempts is how often the summary job tries a note before
+	// taking it out of the queue
+	maxSummaryAttempts = 5
+	// summaryRetryDelay is the wait after the first failed attempt. It
+	// doubles with every further one.
+	summaryRetryDelay = 5 * time.Minute
+)
+
+// errBulkRollback undoes a bulk transaction that ran as a dry run or in
+// which a note failed
+var errBulkRollback = errors.New("bulk changes rolled back")
+
+// BulkNoteFilter selects notes like the notes list filters do
+type BulkNoteFilter struct {
+	SourceURL    string            `json:"source_url,omitempty"`
+	Domain       string            `json:"domain,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`
+	TagMode      string            `json:"tag_mode,omitempty"`
+	CollectionID string            `json:"collection_id,omitempty"`
+	CreatedSince *time.Time        `json:"created_since,omitempty"`
+	CreatedUntil *time.Time        `json:"created_until,omitempty"`
+	UpdatedSince *time.Time        `json:"updated_since,omitempty"`
+	UpdatedUntil *time.Time        `json:"updated_until,omitempty"`
+	HasSummary   *bool             `json:"has_summary,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"` // Dotted key path to value
+}
+
+// BulkNotesRequest applies one action to the listed notes or, without IDs,
+// to the notes matching Filter. The fields after Action are its arguments.
+type BulkNotesRequest struct {
+	IDs    []string        `json:"ids,omitempty"`
+	Filter *BulkNoteFilter `json:"filter,omitempty"`
+	Action string          `json:"action"`
+	DryRun bool            `json:"dry_run,omitempty"` // Report what would change without changing it
+
+	Domain       string         `json:"domain,omitempty"`
+	Metadata     map[string]any `json:"metadata,omitempty"`
+	Tags         []string       `json:"tags,omitempty"`
+	CollectionID string         `json:"collection_id,omitempty"`
+}
+
+type BulkNoteResult struct {
+	ID            string   `json:"id"`
+	Status        string   `json:"status"`
+	ChangedFields []string `json:"changed_fields,omitempty"` // Revised fields that changed
+	Error         string   `json:"error,omitempty"`
+}
+
+// BulkNotesResponse reports what a bulk action did to each note. Changes
+// are applied together or not at all: when a note fails, or on a dry run,
+// Applied is false and nothing is kept.
+type BulkNotesResponse struct {
+	Action  string           `json:"action"`
+	DryRun  bool             `json:"dry_run"`
+	Applied bool             `json:"applied"`
+	Matched int              `json:"matched"`
+	Changed int              `json:"changed"` // Changed or queued
+	Failed  int              `json:"failed"`
+	Results []BulkNoteResult `json:"results"`
+}
+
+// BulkUpdate applies a bulk action in one transaction. Notes are handled
+// one at a time, so field changes are saved as revisions like single edits.
+func (s *NotesService) BulkUpdate(userID string, req *BulkNotesRequest) (*BulkNotesResponse, error) {
+	authorID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+	if err := validateBulkRequest(req); err != nil {
+		return nil, err
+	}
+
+	response := &BulkNotesResponse{Action: req.Action, DryRun: req.DryRun, Results: []BulkNoteResult{}}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		ids, labels, err := s.bulkSelection(tx, userID, req)
+		if err != nil {
+			return err
+		}
+		// Lock in a fixed order so concurrent bulk requests cannot deadlock
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&models.Note{}).
+			Where("id IN ? AND user_id = ?", ids, userID).Order("id").Pluck("id", &[]uuid.UUID{}).Error
+		if err != nil {
+			return err
+		}
+		var notes []models.Note
+		if err := preloadNoteLinks(tx).Where("id IN ? AND user_id = ?", ids, userID).Find(&notes).Error; err != nil {
+			return err
+		}
+		byID := make(map[uuid.UUID]*models.Note, len(notes))
+		for i := range notes {
+			byID[notes[i].ID] = &notes[i]
+		}
+
+		action, err := s.bulkAction(tx, authorID, req)
+		if err != nil {
+			return err
+		}
+		for i, id := range ids {
+			result := BulkNoteResult{ID: labels[i], Status: BulkStatusNotFound}
+			if note := byID[id]; note != nil {
+				response.Matched++
+				result.Status, result.ChangedFields, err = action(note)
+			}
+			if err != nil {
+				// Only the note's own problems are reported per note
+				if !isBulkNoteError(err) {
+					return err
+				}
+				result.Status, result.Error = BulkStatusFailed, err.Error()
+				err = nil
+			}
+			switch result.Status {
+			case BulkStatusChanged, BulkStatusQueued:
+				response.Changed++
+			case BulkStatusFailed:
+				response.Failed++
+			}
+			response.Results = append(response.Results, result)
+		}
+
+		if req.DryRun || response.Failed > 0 {
+			return errBulkRollback
+		}
+		response.Applied = true
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBulkRollback) {
+		return nil, err
+	}
+	return response, nil
+}
+
+// SummarizeQueued summarizes notes queued by the summarize bulk action,
+// oldest request first. Notes that fail are retried with backoff, up to
+// maxSummaryAttempts times, so they cannot hold up the rest of the queue. It
+// returns how many notes were summarized.
+func (s *NotesService) SummarizeQueued(ctx context.Context, summarizer *SummarizerService) (int, error) {
+	var notes []models.Note
+	err := s.db.WithContext(ctx).
+		Where("summary_requested_at <= ?", time.Now()).
+		Order("summary_requested_at").
+		Limit(summaryBatchSize).
+		Find(&notes).Error
+	if err != nil {
+		return 0, err
+	}
+	summarized := 0
+	var errs []error
+	for _, note := range notes {
+		if err := ctx.Err(); err != nil {
+			return summarized, err
+		}
+		summary, err := summarizer.Summarize(note.Content)
+		if err != nil {
+			errs = append(errs, err)
+			if err := s.retrySummary(ctx, &note); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		// A summary is not an edit, so updated_at is left alone
+		err = s.db.WithContext(ctx).Model(&note).UpdateColumns(map[string]any{
+			"summary":              summary,
+			"summary_requested_at": nil,
+			"summary_attempts":     0,
+		}).Error
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		summarized++
+	}
+	return summarized, errors.Join(errs...)
+}
+
+// retrySummary records a failed summary attempt. The note is tried again
+// after the backoff, or taken out of the queue after maxSummaryAttempts.
+func (s *NotesService) retrySummary(ctx context.Context, note *models.Note) error {
+	attempts := note.SummaryAttempts + 1
+	updates := map[string]any{
+		"summary_requested_at": summaryRetryAt(time.Now(), attempts),
+		"summary_attempts":     attempts,
+	}
+	if attempts >= maxSummaryAttempts {
+		log.Printf("Gave up summarizing note %s after %d attempts", note.ID, attempts)
+		updates = map[string]any{"summary_requested_at": nil, "summary_attempts": 0}
+	}
+	return s.db.WithContext(ctx).Model(note).UpdateColumns(updates).Error
+}
+
+// summaryRetryAt returns when to try a note again after its failed attempts
+func summaryRetryAt(now time.Time, attempts int) time.Time {
+	return now.Add(summaryRetryDelay << (attempts - 1))
+}
+
+// validateBulkRequest checks the selection and the action's arguments
+// before anything is loaded
+func validateBulkRequest(req *BulkNotesRequest) error {
+	switch {
+	case len(req.IDs) > 0 && req.Filter != nil:
+		return errors.New("ids and filter both given")
+	case len(req.IDs) == 0 && req.Filter == nil:
+		return errors.New("no notes given")
+	case len(req.IDs) > maxBulkNotes:
+		return errors.New("too many notes")
+	}
+
+	switch req.Action {
+	case BulkActionDelete, BulkActionSummarize:
+	case BulkActionSetDomain:
+		if strings.TrimSpace(req.Domain) == "" {
+			return errors.New("domain is required")
+		}
+	case BulkActionMergeMetadata:
+		if len(req.Metadata) == 0 {
+			return errors.New("no metadata given")
+		}
+	case BulkActionAddTags, BulkActionRemoveTags:
+		if len(req.Tags) == 0 {
+			return errors.New("no tags given")
+		}
+		for _, name := range req.Tags {
+			if _, err := normalizeTagName(name); err != nil {
+				return err
+			}
+		}
+	case BulkActionMoveToCollection:
+		if req.CollectionID == "" {
+			return errors.New("collection not found")
+		}
+	default:
+		return errors.New("invalid action")
+	}
+	return nil
+}
+
+// bulkSelection returns the IDs of the notes a bulk request applies to, in
+// the order given or, for a filter, newest first, with the ID to report for
+// each. Listed IDs that are not the user's notes are later reported as not
+// found; those that are not IDs at all come back as uuid.Nil.
+func (s *NotesService) bulkSelection(tx *gorm.DB, userID string, req *BulkNotesRequest) ([]uuid.UUID, []string, error) {
+	if req.Filter != nil {
+		f := req.Filter
+		db, err := s.filterNotes(tx, userID, &NoteQuery{
+			SourceURL:    f.SourceURL,
+			Domain:       f.Domain,
+			Tags:         f.Tags,
+			TagMode:      f.TagMode,
+			CollectionID: f.CollectionID,
+			CreatedSince: f.CreatedSince,
+			CreatedUntil: f.CreatedUntil,
+			UpdatedSince: f.UpdatedSince,
+			UpdatedUntil: f.UpdatedUntil,
+			HasSummary:   f.HasSummary,
+			Metadata:     f.Metadata,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		var ids []uuid.UUID
+		err = db.Order("notes.created_at DESC, notes.id DESC").Limit(maxBulkNotes+1).Pluck("notes.id", &ids).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(ids) > maxBulkNotes {
+			return nil, nil, errors.New("too many notes")
+		}
+		labels := make([]string, len(ids))
+		for i, id := range ids {
+			labels[i] = id.String()
+		}
+		return ids, labels, nil
+	}
+
+	seen := make(map[string]bool, len(req.IDs))
+	var ids []uuid.UUID
+	var labels []string
+	for _, raw := range req.IDs {
+		if seen[raw] {
+			continue
+		}
+		seen[raw] = true
+		id, _ := uuid.Parse(raw)
+		ids = append(ids, id)
+		labels = append(labels, raw)
+	}
+	return ids, labels, nil
+}
+
+// bulkNoteAction applies a bulk action to one note and returns its status
+// and the revised fields it changed
+type bulkNoteAction func(note *models.Note) (string, []string, error)
+
+// bulkNoteErrors are the errors reported for a single note instead of
+// failing the whole request
+var bulkNoteErrors = []string{"too many tags"}
+
+func isBulkNoteError(err error) bool {
+	for _, message := range bulkNoteErrors {
+		if err.Error() == message {
+			return true
+		}
+	}
+	return false
+}
+
+// bulkAction prepares the request's action, resolving what it needs once
+func (s *NotesService) bulkAction(tx *gorm.DB, authorID uuid.UUID, req *BulkNotesRequest) (bulkNoteAction, error) {
+	// save writes a note changed in place and records the revision
+	save := func(note *models.Note, before noteSnapshot, setTags bool) (string, []string, error) {
+		changed := before.changedFields(snapshotNote(note))
+		if len(changed) == 0 {
+			return BulkStatusUnchanged, nil, nil
+		}
+		if err := saveNote(tx, note, setTags, &before, authorID, nil); err != nil {
+			return "", nil, err
+		}
+		return BulkStatusChanged, changed, nil
+	}
+
+	switch req.Action {
+	case BulkActionDelete:
+		return func(note *models.Note) (string, []string, error) {
+			if err := tx.Delete(note).Error; err != nil {
+				return "", nil, err
+			}
+			return BulkStatusChanged, nil, nil
+		}, nil
+
+	case BulkActionSetDomain:
+		domain := strings.TrimSpace(req.Domain)
+		return func(note *models.Note) (string, []string, error) {
+			before := snapshotNote(note)
+			note.Domain = domain
+			return save(note, before, false)
+		}, nil
+
+	case BulkActionMergeMetadata:
+		return func(note *models.Note) (string, []string, error) {
+			before := snapshotNote(note)
+			metadata := map[string]any{}
+			if len(note.Metadata) > 0 {
+				_ = json.Unmarshal(note.Metadata, &metadata)
+			}
+			for key, value := range req.Metadata {
+				if value == nil {
+					delete(metadata, key)
+				} else {
+					metadata[key] = value
+				}
+			}
+			note.Metadata = nil
+			if len(metadata) > 0 {
+				b, _ := json.Marshal(metadata)
+				note.Metadata = datatypes.JSON(b)
+			}
+			return save(note, before, false)
+		}, nil
+
+	case BulkActionAddTags:
+		return func(note *models.Note) (string, []string, error) {
+			before := snapshotNote(note)
+			tags, err := resolveTags(tx, note.UserID, append(slices.Clone(before.tags), req.Tags...))
+			if err != nil {
+				return "", nil, err
+			}
+			note.Tags = tags
+			return save(note, before, true)
+		}, nil
+
+	case BulkActionRemoveTags:
+		remove := make(map[string]bool, len(req.Tags))
+		for _, name := range req.Tags {
+			name, _ = normalizeTagName(name)
+			remove[strings.ToLower(name)] = true
+		}
+		return func(note *models.Note) (string, []string, error) {
+			before := snapshotNote(note)
+			kept := make([]models.Tag, 0, len(note.Tags))
+			for _, tag := range note.Tags {
+				if !remove[strings.ToLower(tag.Name)] {
+					kept = append(kept, tag)
+				}
+			}
+			note.Tags = kept
+			return save(note, before, true)
+		}, nil
+
+	case BulkActionMoveToCollection:
+		collection, err := s.collections.lockCollection(tx, authorID.String(), req.CollectionID)
+		if err != nil {
+			return nil, err
+		}
+		var next int
+		err = tx.Model(&models.CollectionNote{}).Select("COALESCE(MAX(position) + 1, 0)").
+			Where("collection_id = ?", collection.ID).Scan(&next).Error
+		if err != nil {
+			return nil, err
+		}
+		return func(note *models.Note) (string, []string, error) {
+			inTarget := false
+			for _, other := range note.Collections {
+				if other.ID == collection.ID {
+					inTarget = true
+				}
+			}
+			if inTarget && len(note.Collections) == 1 {
+				return BulkStatusUnchanged, nil, nil
+			}
+			err := tx.Where("note_id = ? AND collection_id <> ?", note.ID, collection.ID).Delete(&models.CollectionNote{}).Error
+			if err != nil {
+				return "", nil, err
+			}
+			if !inTarget {
+				row := models.CollectionNote{CollectionID: collection.ID, NoteID: note.ID, Position: next}
+				if err := tx.Create(&row).Error; err != nil {
+					return "", nil, err
+				}
+				next++
+			}
+			return BulkStatusChanged, nil, nil
+		}, nil
+
+	case BulkActionSummarize:
+		now := time.Now()
+		return func(note *models.Note) (string, []string, error) {
+			if note.SummaryRequestedAt == nil {
+				if err := tx.Model(note).UpdateColumn("summary_requested_at", now).Error; err != nil {
+					return "", nil, err
+				}
+			}
+			return BulkStatusQueued, nil, nil
+		}, nil
+	}
+	return nil, errors.New("invalid action")
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+)
+
+func TestValidateBulkRequest(t *testing.T) {
+	ids := []string{"6f1c2a9e-8d3b-4b7a-9c1e-2f4a5b6c7d8e"}
+	tooMany := make([]string, maxBulkNotes+1)
+	tests := []struct {
+		name string
+		req  BulkNotesRequest
+		want string
+	}{
+		{"delete by ids", BulkNotesRequest{IDs: ids, Action: BulkActionDelete}, ""},
+		{"summarize by filter", BulkNotesRequest{Filter: &BulkNoteFilter{Domain: "example.com"}, Action: BulkActionSummarize}, ""},
+		{"empty filter", BulkNotesRequest{Filter: &BulkNoteFilter{}, Action: BulkActionDelete}, ""},
+		{"no selection", BulkNotesRequest{Action: BulkActionDelete}, "no notes given"},
+		{"ids and filter", BulkNotesRequest{IDs: ids, Filter: &BulkNoteFilter{}, Action: BulkActionDelete}, "ids and filter both given"},
+		{"too many ids", BulkNotesRequest{IDs: tooMany, Action: BulkActionDelete}, "too many notes"},
+		{"unknown action", BulkNotesRequest{IDs: ids, Action: "archive"}, "invalid action"},
+		{"missing action", BulkNotesRequest{IDs: ids}, "invalid action"},
+		{"set domain", BulkNotesRequest{IDs: ids, Action: BulkActionSetDomain, Domain: "example.com"}, ""},
+		{"blank domain", BulkNotesRequest{IDs: ids, Action: BulkActionSetDomain, Domain: "  "}, "domain is required"},
+		{"merge metadata", BulkNotesRequest{IDs: ids, Action: BulkActionMergeMetadata, Metadata: map[string]any{"read": nil}}, ""},
+		{"no metadata", BulkNotesRequest{IDs: ids, Action: BulkActionMergeMetadata}, "no metadata given"},
+		{"add tags", BulkNotesRequest{IDs: ids, Action: BulkActionAddTags, Tags: []string{"go"}}, ""},
+		{"no tags", BulkNotesRequest{IDs: ids, Action: BulkActionRemoveTags}, "no tags given"},
+		{"bad tag", BulkNotesRequest{IDs: ids, Action: BulkActionAddTags, Tags: []string{"a,b"}}, "invalid tag name"},
+		{"long tag", BulkNotesRequest{IDs: ids, Action: BulkActionRemoveTags, Tags: []string{strings.Repeat("x", maxTagNameLength+1)}}, "tag name too long"},
+		{"no collection", BulkNotesRequest{IDs: ids, Action: BulkActionMoveToCollection}, "collection not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBulkRequest(&tt.req)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("validateBulkRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func newTestNotesService(db *fakeDB) *NotesService {
+	return &NotesService{db: db.DB, collections: &CollectionService{db: db.DB}}
+}
+
+// bulkNote returns a note of the user that was already fingerprinted
+func bulkNote(userID uuid.UUID, tags ...string) models.Note {
+	now := time.Now()
+	note := models.Note{ID: uuid.New(), UserID: userID, Content: "content", Domain: "a.com", FingerprintedAt: &now}
+	for _, name := range tags {
+		note.Tags = append(note.Tags, models.Tag{ID: uuid.New(), UserID: userID, Name: name})
+	}
+	return note
+}
+
+func bulkStatuses(response *BulkNotesResponse) []string {
+	statuses := make([]string, len(response.Results))
+	for i, result := range response.Results {
+		statuses[i] = result.Status
+	}
+	return statuses
+}
+
+func TestBulkUpdateDryRun(t *testing.T) {
+	userID := uuid.New()
+	notes := []models.Note{bulkNote(userID), bulkNote(userID)}
+	notes[1].Domain = "b.com"
+	req := BulkNotesRequest{IDs: []string{notes[0].ID.String(), notes[1].ID.String()}, Action: BulkActionSetDomain, Domain: " b.com ", DryRun: true}
+
+	for _, dryRun := range []bool{true, false} {
+		db := newFakeDB(t, slices.Clone(notes))
+		req.DryRun = dryRun
+		response, err := newTestNotesService(db).BulkUpdate(userID.String(), &req)
+		if err != nil {
+			t.Fatalf("BulkUpdate() error = %v", err)
+		}
+		if response.DryRun != dryRun || response.Applied == dryRun || response.Matched != 2 || response.Changed != 1 {
+			t.Errorf("dry run %v: BulkUpdate() = %+v", dryRun, response)
+		}
+		if got := bulkStatuses(response); !slices.Equal(got, []string{BulkStatusChanged, BulkStatusUnchanged}) ||
+			!slices.Equal(response.Results[0].ChangedFields, []string{"domain"}) {
+			t.Errorf("dry run %v: BulkUpdate() results = %+v", dryRun, response.Results)
+		}
+
+		// A dry run makes the changes to report them, then rolls them back
+		end := "COMMIT"
+		if dryRun {
+			end = "ROLLBACK"
+		}
+		if !inTransaction(db, end, `UPDATE "notes" SET`, `"domain"='b.com'`, notes[0].ID.String()) {
+			t.Errorf("dry run %v: BulkUpdate() did not end the change with %s: %q", dryRun, end, db.writes)
+		}
+		if !inTransaction(db, end, `INSERT INTO "note_revisions"`, notes[0].ID.String()) {
+			t.Errorf("dry run %v: BulkUpdate() did not record a revision: %q", dryRun, db.writes)
+		}
+		if db.wrote(notes[1].ID.String()) {
+			t.Errorf("dry run %v: BulkUpdate() wrote an unchanged note: %q", dryRun, db.writes)
+		}
+	}
+}
+
+func TestBulkUpdateIsAllOrNothing(t *testing.T) {
+	userID := uuid.New()
+	full := make([]string, maxTagsPerNote)
+	for i := range full {
+		full[i] = fmt.Sprintf("tag %d", i)
+	}
+	notes := []models.Note{bulkNote(userID), bulkNote(userID, full...)}
+	db := newFakeDB(t, notes)
+
+	response, err := newTestNotesService(db).BulkUpdate(userID.String(), &BulkNotesRequest{
+		IDs:    []string{notes[0].ID.String(), notes[1].ID.String()},
+		Action: BulkActionAddTags,
+		Tags:   []string{"new"},
+	})
+	if err != nil {
+		t.Fatalf("BulkUpdate() error = %v", err)
+	}
+	if response.Applied || response.Changed != 1 || response.Failed != 1 {
+		t.Errorf("BulkUpdate() = %+v, want one change not applied", response)
+	}
+	if got := bulkStatuses(response); !slices.Equal(got, []string{BulkStatusChanged, BulkStatusFailed}) || response.Results[1].Error != "too many tags" {
+		t.Errorf("BulkUpdate() results = %+v", response.Results)
+	}
+	// The note that could be tagged is rolled back too
+	if !inTransaction(db, "ROLLBACK", `INSERT INTO "note_tags"`, notes[0].ID.String()) || slices.Contains(db.writes, "COMMIT") {
+		t.Errorf("BulkUpdate() did not roll back every change: %q", db.writes)
+	}
+}
+
+func TestBulkUpdateReportsNotFound(t *testing.T) {
+	userID := uuid.New()
+	note := bulkNote(userID)
+	other := uuid.NewString() // Not found, or someone else's
+	db := newFakeDB(t, []models.Note{note})
+
+	response, err := newTestNotesService(db).BulkUpdate(userID.String(), &BulkNotesRequest{
+		IDs:    []string{note.ID.String(), other, "not-a-uuid", note.ID.String()},
+		Action: BulkActionDelete,
+	})
+	if err != nil {
+		t.Fatalf("BulkUpdate() error = %v", err)
+	}
+	if !response.Applied || response.Matched != 1 || response.Changed != 1 || response.Failed != 0 {
+		t.Errorf("BulkUpdate() = %+v", response)
+	}
+	want := []BulkNoteResult{
+		{ID: note.ID.String(), Status: BulkStatusChanged},
+		{ID: other, Status: BulkStatusNotFound},
+		{ID: "not-a-uuid", Status: BulkStatusNotFound},
+	}
+	if len(response.Results) != len(want) {
+		t.Fatalf("BulkUpdate() results = %+v, want %+v", response.Results, want)
+	}
+	for i := range want {
+		if response.Results[i].ID != want[i].ID || response.Results[i].Status != want[i].Status {
+			t.Errorf("BulkUpdate() result %d = %+v, want %+v", i, response.Results[i], want[i])
+		}
+	}
+	if !db.queried("user_id = '" + userID.String() + "'") {
+		t.Errorf("BulkUpdate() did not limit the notes to the user's: %q", db.queries)
+	}
+	if !inTransaction(db, "COMMIT", `UPDATE "notes" SET "deleted_at"=`, note.ID.String()) || db.wrote(other) {
+		t.Errorf("BulkUpdate() did not trash only the found note: %q", db.writes)
+	}
+}
+
+func TestBulkMoveToCollection(t *testing.T) {
+	userID := uuid.New()
+	target := models.Collection{ID: uuid.New(), UserID: userID, Name: "target"}
+	other := models.Collection{ID: uuid.New(), UserID: userID, Name: "other"}
+	notes := []models.Note{bulkNote(userID), bulkNote(userID), bulkNote(userID), bulkNote(userID)}
+	notes[1].Collections = []models.Collection{other}
+	notes[2].Collections = []models.Collection{target}
+	notes[3].Collections = []models.Collection{other, target}
+	ids := make([]string, len(notes))
+	for i, note := range notes {
+		ids[i] = note.ID.String()
+	}
+	db := newFakeDB(t, target, notes)
+	db.scan("MAX(position)", 3)
+
+	response, err := newTestNotesService(db).BulkUpdate(userID.String(), &BulkNotesRequest{
+		IDs:          ids,
+		Action:       BulkActionMoveToCollection,
+		CollectionID: target.ID.String(),
+	})
+	if err != nil {
+		t.Fatalf("BulkUpdate() error = %v", err)
+	}
+	want := []string{BulkStatusChanged, BulkStatusChanged, BulkStatusUnchanged, BulkStatusChanged}
+	if got := bulkStatuses(response); !slices.Equal(got, want) || !response.Applied {
+		t.Errorf("BulkUpdate() = %+v, want statuses %v", response, want)
+	}
+
+	// Added notes go to the end of the collection, in the order given
+	for i, position := range map[int]int{0: 3, 1: 4} {
+		if !db.wrote(`INSERT INTO "collection_notes"`, fmt.Sprintf("'%s','%s',%d", target.ID, notes[i].ID, position)) {
+			t.Errorf("BulkUpdate() did not add note %d at position %d: %q", i, position, db.writes)
+		}
+	}
+	if db.wrote(`INSERT INTO "collection_notes"`, notes[3].ID.String()) {
+		t.Errorf("BulkUpdate() added a note already in the collection: %q", db.writes)
+	}
+	for _, i := range []int{1, 3} {
+		if !db.wrote(`DELETE FROM "collection_notes"`, "note_id = '"+notes[i].ID.String()+"' AND collection_id <> '"+target.ID.String()+"'") {
+			t.Errorf("BulkUpdate() did not take note %d out of its other collections: %q", i, db.writes)
+		}
+	}
+	if db.wrote(notes[2].ID.String()) {
+		t.Errorf("BulkUpdate() changed a note only in the collection: %q", db.writes)
+	}
+
+	db = newFakeDB(t, missing(target), notes)
+	if _, err := newTestNotesService(db).BulkUpdate(userID.String(), &BulkNotesRequest{
+		IDs:          ids,
+		Action:       BulkActionMoveToCollection,
+		CollectionID: target.ID.String(),
+	}); err == nil || err.Error() != "collection not found" {
+		t.Errorf("BulkUpdate() error = %v for another user's collection, want collection not found", err)
+	}
+}
+
+func TestBulkRemoveTags(t *testing.T) {
+	userID := uuid.New()
+	notes := []models.Note{bulkNote(userID, "go", "Rust", "machine learning"), bulkNote(userID, "go")}
+	tags := slices.Clone(notes[0].Tags)
+	db := newFakeDB(t, notes)
+
+	response, err := newTestNotesService(db).BulkUpdate(userID.String(), &BulkNotesRequest{
+		IDs:    []string{notes[0].ID.String(), notes[1].ID.String()},
+		Action: BulkActionRemoveTags,
+		Tags:   []string{"rust", " Machine   Learning "},
+	})
+	if err != nil {
+		t.Fatalf("BulkUpdate() error = %v", err)
+	}
+	if got := bulkStatuses(response); !slices.Equal(got, []string{BulkStatusChanged, BulkStatusUnchanged}) ||
+		!slices.Equal(response.Results[0].ChangedFields, []string{"tags"}) {
+		t.Errorf("BulkUpdate() results = %+v", response.Results)
+	}
+
+	// Tags are matched ignoring case and spacing, and only the rest are kept
+	if !db.wrote(`DELETE FROM "note_tags"`, notes[0].ID.String()) ||
+		!db.wrote(`INSERT INTO "note_tags"`, notes[0].ID.String(), tags[0].ID.String()) ||
+		db.wrote(`INSERT INTO "note_tags"`, tags[1].ID.String()) ||
+		db.wrote(`INSERT INTO "note_tags"`, tags[2].ID.String()) {
+		t.Errorf("BulkUpdate() did not keep only the go tag: %q", db.writes)
+	}
+	if db.wrote(notes[1].ID.String()) {
+		t.Errorf("BulkUpdate() changed a note without the tags: %q", db.writes)
+	}
+}
+
+func TestSummarizeQueued(t *testing.T) {
+	requestedAt := time.Now().Add(-time.Minute)
+	note := models.Note{ID: uuid.New(), Content: "short", SummaryRequestedAt: &requestedAt, SummaryAttempts: 2}
+	db := newFakeDB(t, []models.Note{note})
+
+	summarized, err := newTestNotesService(db).SummarizeQueued(context.Background(), &SummarizerService{})
+	if err != nil || summarized != 1 {
+		t.Fatalf("SummarizeQueued() = %d, %v", summarized, err)
+	}
+	// Notes waiting for a retry are skipped
+	if !db.queried("summary_requested_at <= ") {
+		t.Errorf("SummarizeQueued() did not leave out notes waiting for a retry: %q", db.queries)
+	}
+	if !db.wrote(`UPDATE "notes" SET`, `"summary"='unavailable'`, `"summary_attempts"=0`, `"summary_requested_at"=NULL`, note.ID.String()) {
+		t.Errorf("SummarizeQueued() did not store the summary: %q", db.writes)
+	}
+}
+
+func TestRetrySummary(t *testing.T) {
+	now := time.Now()
+	for attempts, want := range map[int]time.Duration{1: 5 * time.Minute, 2: 10 * time.Minute, 4: 40 * time.Minute} {
+		if got := summaryRetryAt(now, attempts).Sub(now); got != want {
+			t.Errorf("summaryRetryAt(%d) = now + %v, want now + %v", attempts, got, want)
+		}
+	}
+
+	note := models.Note{ID: uuid.New(), SummaryAttempts: 1}
+	db := newFakeDB(t)
+	if err := newTestNotesService(db).retrySummary(context.Background(), &note); err != nil {
+		t.Fatalf("retrySummary() error = %v", err)
+	}
+	if !db.wrote(`UPDATE "notes" SET`, `"summary_attempts"=2`, note.ID.String()) || db.wrote(`"summary_requested_at"=NULL`) {
+		t.Errorf("retrySummary() did not queue the note again: %q", db.writes)
+	}
+
+	// The last attempt takes the note out of the queue
+	note.SummaryAttempts = maxSummaryAttempts - 1
+	db = newFakeDB(t)
+	if err := newTestNotesService(db).retrySummary(context.Background(), &note); err != nil {
+		t.Fatalf("retrySummary() error = %v", err)
+	}
+	if !db.wrote(`UPDATE "notes" SET`, `"summary_attempts"=0`, `"summary_requested_at"=NULL`, note.ID.String()) {
+		t.Errorf("retrySummary() kept retrying after %d attempts: %q", maxSummaryAttempts, db.writes)
+	}
+}
@@ -0,0 +1,459 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultDuplicateClusters = 50
+	maxDuplicateClusters     = 200
+	// maxMergeNotes bounds how many notes one merge folds into its target
+	maxMergeNotes = 100
+	// fingerprintBatchSize bounds how many notes one backfill transaction
+	// fingerprints
+	fingerprintBatchSize = 500
+)
+
+// unfingerprinted selects notes saved before fingerprints were kept
+const unfingerprinted = "fingerprinted_at IS NULL"
+
+// MergedSourcesKey is the metadata key under which a merge keeps the source
+// of each note merged into the target
+const MergedSourcesKey = "merged_sources"
+
+// DuplicateCluster is a group of notes that are near-duplicates of each
+// other, oldest first. Similarity is the lowest similarity between two
+// notes that linked the group; Exact is set when every note has the same
+// normalized content.
+type DuplicateCluster struct {
+	Notes      []NoteResponse `json:"notes"`
+	Similarity float64        `json:"similarity"`
+	Exact      bool           `json:"exact"`
+}
+
+// MergeNotesRequest folds notes into a target note, which keeps its content
+type MergeNotesRequest struct {
+	TargetID string   `json:"target_id"`
+	NoteIDs  []string `json:"note_ids"` // Moved to the trash once merged
+}
+
+// FindDuplicates groups the user's notes that are at least threshold alike,
+// largest groups first. Notes saved before fingerprints were kept are left
+// out until FingerprintMissing has run.
+func (s *NotesService) FindDuplicates(ctx context.Context, userID string, threshold float64, limit int) ([]DuplicateCluster, error) {
+	if threshold == 0 {
+		threshold = DefaultDuplicateThreshold
+	}
+	if threshold < 0.5 || threshold > 1 {
+		return nil, errors.New("invalid threshold")
+	}
+	if limit < 1 {
+		limit = defaultDuplicateClusters
+	}
+	limit = min(limit, maxDuplicateClusters)
+
+	var candidates []duplicateCandidate
+	err := s.db.WithContext(ctx).Model(&models.Note{}).
+		Select("id, content_hash, min_hash, created_at").
+		Where("user_id = ? AND content_hash <> ''", userID).
+		Order("created_at, id").
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	groups := clusterDuplicates(candidates, threshold)
+	if len(groups) > limit {
+		groups = groups[:limit]
+	}
+
+	var ids []uuid.UUID
+	for _, group := range groups {
+		for _, i := range group.members {
+			ids = append(ids, candidates[i].ID)
+		}
+	}
+	var notes []models.Note
+	if err := preloadNoteLinks(s.db.WithContext(ctx)).Where("id IN ?", ids).Find(&notes).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.Note, len(notes))
+	for i := range notes {
+		byID[notes[i].ID] = &notes[i]
+	}
+
+	clusters := make([]DuplicateCluster, 0, len(groups))
+	for _, group := range groups {
+		cluster := DuplicateCluster{Similarity: group.similarity, Exact: true}
+		for _, i := range group.members {
+			note := byID[candidates[i].ID]
+			if note == nil {
+				continue // Deleted meanwhile
+			}
+			cluster.Notes = append(cluster.Notes, toNoteResponse(note))
+			cluster.Exact = cluster.Exact && candidates[i].ContentHash == candidates[group.members[0]].ContentHash
+		}
+		if len(cluster.Notes) > 1 {
+			clusters = append(clusters, cluster)
+		}
+	}
+	return clusters, nil
+}
+
+// MergeNotes folds notes into a target note and moves them to the trash.
+// The target keeps its content and gains their tags, collections, metadata
+// keys it lacks and, if it has none, their source and summary. The source of
+// each merged note is listed in the target's metadata under MergedSourcesKey.
+func (s *NotesService) MergeNotes(userID string, req *MergeNotesRequest) (*NoteResponse, error) {
+	authorID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+	targetID, err := uuid.Parse(req.TargetID)
+	if err != nil {
+		return nil, errors.New("note not found")
+	}
+	var mergedIDs []uuid.UUID
+	for _, raw := range req.NoteIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, errors.New("note not found")
+		}
+		if id == targetID {
+			return nil, errors.New("cannot merge note into itself")
+		}
+		if !slices.Contains(mergedIDs, id) {
+			mergedIDs = append(mergedIDs, id)
+		}
+	}
+	switch {
+	case len(mergedIDs) == 0:
+		return nil, errors.New("no notes given")
+	case len(mergedIDs) > maxMergeNotes:
+		return nil, errors.New("too many notes")
+	}
+
+	var target models.Note
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		allIDs := append([]uuid.UUID{targetID}, mergedIDs...)
+		var locked []uuid.UUID
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&models.Note{}).
+			Where("id IN ? AND user_id = ?", allIDs, userID).Order("id").Pluck("id", &locked).Error
+		if err != nil {
+			return err
+		}
+		if len(locked) != len(allIDs) {
+			return errors.New("note not found")
+		}
+		if err := preloadNoteLinks(tx).Where("id = ?", targetID).First(&target).Error; err != nil {
+			return err
+		}
+		var merged []models.Note
+		if err := preloadNoteLinks(tx).Where("id IN ?", mergedIDs).Order("created_at, id").Find(&merged).Error; err != nil {
+			return err
+		}
+		before := snapshotNote(&target)
+
+		metadata := map[string]any{}
+		if len(target.Metadata) > 0 {
+			_ = json.Unmarshal(target.Metadata, &metadata)
+		}
+		sources, _ := metadata[MergedSourcesKey].([]any)
+		tagNames := slices.Clone(before.tags)
+		for _, note := range merged {
+			var noteMetadata map[string]any
+			if len(note.Metadata) > 0 {
+				_ = json.Unmarshal(note.Metadata, &noteMetadata)
+			}
+			// Sources the note itself had merged in are carried over
+			if earlier, ok := noteMetadata[MergedSourcesKey].([]any); ok {
+				sources = append(sources, earlier...)
+			}
+			sources = append(sources, mergedSource(&note))
+			for key, value := range noteMetadata {
+				if _, ok := metadata[key]; !ok && key != MergedSourcesKey {
+					metadata[key] = value
+				}
+			}
+
+			if target.SourceURL == "" && note.SourceURL != "" {
+				target.SourceURL, target.SourceTitle, target.Domain = note.SourceURL, note.SourceTitle, note.Domain
+			}
+			if target.Summary == "" {
+				target.Summary = note.Summary
+			}
+			for _, tag := range note.Tags {
+				tagNames = append(tagNames, tag.Name)
+			}
+		}
+		metadata[MergedSourcesKey] = sources
+		b, _ := json.Marshal(metadata)
+		target.Metadata = datatypes.JSON(b)
+
+		tags, err := resolveTags(tx, target.UserID, tagNames)
+		if err != nil {
+			return err
+		}
+		target.Tags = tags
+		if err := saveNote(tx, &target, true, &before, authorID, nil); err != nil {
+			return err
+		}
+
+		// The target takes each merged note's place in its collections
+		var rows []models.CollectionNote
+		if err := tx.Where("note_id IN ?", mergedIDs).Order("position").Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			placed := models.CollectionNote{CollectionID: row.CollectionID, NoteID: target.ID, Position: row.Position}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&placed).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("id IN ?", mergedIDs).Delete(&models.Note{}).Error; err != nil {
+			return err
+		}
+		return preloadNoteLinks(tx).Where("id = ?", target.ID).First(&target).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := toNoteResponse(&target)
+	return &response, nil
+}
+
+// FingerprintMissing fingerprints notes saved before fingerprints were kept,
+// in batches, and returns how many it did
+func (s *NotesService) FingerprintMissing(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		count, err := s.fingerprintBatch(ctx)
+		total += count
+		if err != nil || count < fingerprintBatchSize {
+			return total, err
+		}
+	}
+}
+
+// fingerprintBatch fingerprints up to fingerprintBatchSize notes without one,
+// trashed ones included
+func (s *NotesService) fingerprintBatch(ctx context.Context) (int, error) {
+	var notes []models.Note
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Select("id", "content").
+			Where(unfingerprinted).
+			Limit(fingerprintBatchSize).
+			Find(&notes).Error
+		if err != nil {
+			return err
+		}
+		for i := range notes {
+			note := &notes[i]
+			setFingerprint(note)
+			err := tx.Unscoped().Model(note).UpdateColumns(map[string]any{
+				"content_hash":     note.ContentHash,
+				"min_hash":         note.MinHash,
+				"fingerprinted_at": note.FingerprintedAt,
+			}).Error
+			if err != nil {
+				return err
+			}
+			if err := saveFingerprintBands(tx, note); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(notes), err
+}
+
+// findDuplicate returns the ID of the user's oldest note that is at least
+// threshold alike to the fingerprinted note, or uuid.Nil
+func findDuplicate(tx *gorm.DB, userID uuid.UUID, note *models.Note, threshold float64) (uuid.UUID, error) {
+	if note.ContentHash == "" {
+		return uuid.Nil, nil
+	}
+	var exact []uuid.UUID
+	err := tx.Model(&models.Note{}).
+		Where("user_id = ? AND content_hash = ?", userID, note.ContentHash).
+		Order("created_at, id").Limit(1).
+		Pluck("id", &exact).Error
+	if err != nil || len(exact) > 0 {
+		return uuidOrNil(exact), err
+	}
+	keys := fingerprintBandKeys(note.MinHash)
+	if len(keys) == 0 {
+		return uuid.Nil, nil
+	}
+
+	var candidates []duplicateCandidate
+	err = tx.Model(&models.Note{}).
+		Select("id, content_hash, min_hash, created_at").
+		Where("user_id = ? AND id IN (?)", userID,
+			tx.Model(&models.NoteFingerprintBand{}).Select("note_id").Where("key IN ?", keys)).
+		Order("created_at, id").
+		Find(&candidates).Error
+	if err != nil {
+		return uuid.Nil, err
+	}
+	fingerprint := noteFingerprint{Hash: note.ContentHash, MinHash: note.MinHash}
+	for _, candidate := range candidates {
+		if fingerprintSimilarity(fingerprint, candidate.fingerprint()) >= threshold {
+			return candidate.ID, nil
+		}
+	}
+	return uuid.Nil, nil
+}
+
+// setFingerprint fingerprints the note's content
+func setFingerprint(note *models.Note) {
+	fingerprint := fingerprintContent(note.Content)
+	now := time.Now()
+	note.ContentHash, note.MinHash, note.FingerprintedAt = fingerprint.Hash, fingerprint.MinHash, &now
+}
+
+// saveFingerprintBands replaces the stored bands of a fingerprinted note
+func saveFingerprintBands(tx *gorm.DB, note *models.Note) error {
+	if err := tx.Where("note_id = ?", note.ID).Delete(&models.NoteFingerprintBand{}).Error; err != nil {
+		return err
+	}
+	keys := fingerprintBandKeys(note.MinHash)
+	if len(keys) == 0 {
+		return nil
+	}
+	rows := make([]models.NoteFingerprintBand, len(keys))
+	for band, key := range keys {
+		rows[band] = models.NoteFingerprintBand{NoteID: note.ID, Band: band, Key: key}
+	}
+	return tx.Create(&rows).Error
+}
+
+// mergedSource records where a merged note came from
+func mergedSource(note *models.Note) map[string]any {
+	source := map[string]any{
+		"note_id":    note.ID.String(),
+		"created_at": note.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if note.SourceURL != "" {
+		source["source_url"] = note.SourceURL
+	}
+	if note.SourceTitle != "" {
+		source["source_title"] = note.SourceTitle
+	}
+	if note.Domain != "" {
+		source["domain"] = note.Domain
+	}
+	return source
+}
+
+func uuidOrNil(ids []uuid.UUID) uuid.UUID {
+	if len(ids) == 0 {
+		return uuid.Nil
+	}
+	return ids[0]
+}
+
+// duplicateCandidate is the part of a note duplicate detection reads
+type duplicateCandidate struct {
+	ID          uuid.UUID
+	ContentHash string
+	MinHash     []byte
+	CreatedAt   time.Time
+}
+
+func (c duplicateCandidate) fingerprint() noteFingerprint {
+	return noteFingerprint{Hash: c.ContentHash, MinHash: c.MinHash}
+}
+
+// duplicateGroup lists candidates by index, in their original order
+type duplicateGroup struct {
+	members    []int
+	similarity float64
+}
+
+// clusterDuplicates groups candidates linked by similarity of at least
+// threshold. Only candidates sharing a content hash or a band are compared.
+// Groups are ordered largest first, then by their newest member.
+func clusterDuplicates(candidates []duplicateCandidate, threshold float64) []duplicateGroup {
+	parent := make([]int, len(candidates))
+	similarity := make([]float64, len(candidates))
+	for i := range parent {
+		parent[i] = i
+		similarity[i] = 1
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	link := func(i, j int) {
+		a, b := find(i), find(j)
+		if a == b {
+			return
+		}
+		edge := fingerprintSimilarity(candidates[i].fingerprint(), candidates[j].fingerprint())
+		if edge < threshold {
+			return
+		}
+		parent[b] = a
+		similarity[a] = min(similarity[a], similarity[b], edge)
+	}
+
+	byHash := make(map[string]int)
+	byBand := make(map[int64][]int)
+	for i, candidate := range candidates {
+		if candidate.ContentHash == "" {
+			continue // No words to compare
+		}
+		if first, ok := byHash[candidate.ContentHash]; ok {
+			link(first, i)
+			continue // Its bands are the same as the first's
+		}
+		byHash[candidate.ContentHash] = i
+		for _, key := range fingerprintBandKeys(candidate.MinHash) {
+			for _, other := range byBand[key] {
+				link(other, i)
+			}
+			byBand[key] = append(byBand[key], i)
+		}
+	}
+
+	members := make(map[int][]int)
+	for i := range candidates {
+		root := find(i)
+		members[root] = append(members[root], i)
+	}
+	var groups []duplicateGroup
+	for root, group := range members {
+		if len(group) > 1 {
+			groups = append(groups, duplicateGroup{members: group, similarity: similarity[root]})
+		}
+	}
+	newest := func(g duplicateGroup) time.Time {
+		return candidates[g.members[len(g.members)-1]].CreatedAt
+	}
+	slices.SortFunc(groups, func(a, b duplicateGroup) int {
+		if c := cmp.Compare(len(b.members), len(a.members)); c != 0 {
+			return c
+		}
+		if c := newest(b).Compare(newest(a)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.members[0], b.members[0])
+	})
+	return groups
+}
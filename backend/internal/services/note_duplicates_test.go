@@ -0,0 +1,75 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+)
+
+func TestClusterDuplicates(t *testing.T) {
+	passage := "Active recall means testing yourself on material instead of rereading it. Each attempt to retrieve " +
+		"an answer strengthens the memory more than another pass over the notes, even when the attempt fails."
+	contents := []string{
+		passage, // 0
+		"Photosynthesis turns light into chemical energy stored in glucose inside the chloroplasts.", // 1
+		passage + " Flashcards are one way to do it.",                                                // 2: overlaps 0
+		"ACTIVE recall means testing yourself on material, instead of rereading it! Each attempt to retrieve " +
+			"an answer strengthens the memory more than another pass over the notes; even when the attempt fails.", // 3: same as 0 once normalized
+		"Photosynthesis turns light into chemical energy stored in glucose inside the chloroplasts.", // 4: same as 1
+		"The French Revolution began in 1789 with the storming of the Bastille.",                     // 5
+		"🎉🎉", // 6: no words
+		"?!", // 7: no words
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	candidates := make([]duplicateCandidate, len(contents))
+	for i, content := range contents {
+		fingerprint := fingerprintContent(content)
+		candidates[i] = duplicateCandidate{
+			ID:          uuid.New(),
+			ContentHash: fingerprint.Hash,
+			MinHash:     fingerprint.MinHash,
+			CreatedAt:   start.Add(time.Duration(i) * time.Hour),
+		}
+	}
+
+	groups := clusterDuplicates(candidates, DefaultDuplicateThreshold)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	if want := []int{0, 2, 3}; !reflect.DeepEqual(groups[0].members, want) {
+		t.Errorf("first group = %v, want %v", groups[0].members, want)
+	}
+	if groups[0].similarity < DefaultDuplicateThreshold || groups[0].similarity == 1 {
+		t.Errorf("first group similarity = %v, want between the threshold and 1", groups[0].similarity)
+	}
+	if want := []int{1, 4}; !reflect.DeepEqual(groups[1].members, want) {
+		t.Errorf("second group = %v, want %v", groups[1].members, want)
+	}
+	if groups[1].similarity != 1 {
+		t.Errorf("exact group similarity = %v, want 1", groups[1].similarity)
+	}
+
+	// A strict threshold keeps only the exact copies
+	strict := clusterDuplicates(candidates, 1)
+	if len(strict) != 2 || !reflect.DeepEqual(strict[0].members, []int{1, 4}) || !reflect.DeepEqual(strict[1].members, []int{0, 3}) {
+		t.Errorf("strict groups = %+v, want [1 4] and [0 3]", strict)
+	}
+}
+
+func TestFindDuplicateSkipsContentWithoutWords(t *testing.T) {
+	// Every lookup would find this note
+	other := uuid.New()
+	db := newFakeDB(t, []uuid.UUID{other})
+	note := &models.Note{Content: "🎉"}
+	setFingerprint(note)
+	if note.FingerprintedAt == nil {
+		t.Fatal("setFingerprint() did not mark the note fingerprinted")
+	}
+	id, err := findDuplicate(db.DB, uuid.New(), note, DefaultDuplicateThreshold)
+	if err != nil || id != uuid.Nil {
+		t.Errorf("findDuplicate() = %v, %v for a note without words, want no duplicate", id, err)
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxMetadataFilters     = 10
+	maxMetadataPathDepth   = 5
+	maxMetadataValueLength = 256
+)
+
+var (
+	metadataKeyPattern  = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	jsonNumberPattern   = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+	errInvalidDateRange = errors.New("invalid date range")
+)
+
+// applyNoteFilters adds the notes list filters that only look at the notes
+// table itself
+func applyNoteFilters(db *gorm.DB, query *NoteQuery) (*gorm.DB, error) {
+	if query.SourceURL != "" {
+		db = db.Where("notes.source_url = ?", query.SourceURL)
+	}
+	if query.Domain != "" {
+		db = db.Where("notes.domain = ?", query.Domain)
+	}
+
+	if query.CreatedSince != nil && query.CreatedUntil != nil && !query.CreatedSince.Before(*query.CreatedUntil) {
+		return nil, errInvalidDateRange
+	}
+	if query.UpdatedSince != nil && query.UpdatedUntil != nil && !query.UpdatedSince.Before(*query.UpdatedUntil) {
+		return nil, errInvalidDateRange
+	}
+	if query.CreatedSince != nil {
+		db = db.Where("notes.created_at >= ?", *query.CreatedSince)
+	}
+	if query.CreatedUntil != nil {
+		db = db.Where("notes.created_at < ?", *query.CreatedUntil)
+	}
+	if query.UpdatedSince != nil {
+		db = db.Where("notes.updated_at >= ?", *query.UpdatedSince)
+	}
+	if query.UpdatedUntil != nil {
+		db = db.Where("notes.updated_at < ?", *query.UpdatedUntil)
+	}
+
+	if query.HasSummary != nil {
+		if *query.HasSummary {
+			db = db.Where("COALESCE(notes.summary, '') <> ''")
+		} else {
+			db = db.Where("COALESCE(notes.summary, '') = ''")
+		}
+	}
+
+	if len(query.Metadata) > maxMetadataFilters {
+		return nil, errors.New("too many metadata filters")
+	}
+	for path, value := range query.Metadata {
+		condition, args, err := metadataFilter(path, value)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(condition, args...)
+	}
+	return db, nil
+}
+
+// metadataFilter compiles a metadata filter into JSONB containment, which
+// the GIN index on notes.metadata serves. The path is a dotted key path into
+// nested objects. Values that read as JSON numbers or booleans also match
+// the typed value, so metadata.page=3 matches both {"page": 3} and
+// {"page": "3"}.
+func metadataFilter(path, value string) (string, []any, error) {
+	keys := strings.Split(path, ".")
+	if len(keys) > maxMetadataPathDepth || len(value) > maxMetadataValueLength {
+		return "", nil, errors.New("invalid metadata filter")
+	}
+	for _, key := range keys {
+		if !metadataKeyPattern.MatchString(key) {
+			return "", nil, errors.New("invalid metadata filter")
+		}
+	}
+
+	values := []any{value}
+	switch {
+	case value == "true" || value == "false":
+		values = append(values, value == "true")
+	case jsonNumberPattern.MatchString(value):
+		values = append(values, json.RawMessage(value))
+	}
+
+	conditions := make([]string, len(values))
+	args := make([]any, len(values))
+	for i, v := range values {
+		for j := len(keys) - 1; j >= 0; j-- {
+			v = map[string]any{keys[j]: v}
+		}
+		doc, err := json.Marshal(v)
+		if err != nil {
+			return "", nil, errors.New("invalid metadata filter")
+		}
+		conditions[i] = "notes.metadata @> CAST(? AS jsonb)"
+		args[i] = string(doc)
+	}
+	if len(conditions) == 1 {
+		return conditions[0], args, nil
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args, nil
+}
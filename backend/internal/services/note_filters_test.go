@@ -0,0 +1,41 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMetadataFilter(t *testing.T) {
+	tests := []struct {
+		path, value string
+		condition   string
+		args        []any
+	}{
+		{"color", "yellow", "notes.metadata @> CAST(? AS jsonb)", []any{`{"color":"yellow"}`}},
+		{"book.chapter", "intro", "notes.metadata @> CAST(? AS jsonb)", []any{`{"book":{"chapter":"intro"}}`}},
+		{"page", "3", "(notes.metadata @> CAST(? AS jsonb) OR notes.metadata @> CAST(? AS jsonb))",
+			[]any{`{"page":"3"}`, `{"page":3}`}},
+		{"pinned", "true", "(notes.metadata @> CAST(? AS jsonb) OR notes.metadata @> CAST(? AS jsonb))",
+			[]any{`{"pinned":"true"}`, `{"pinned":true}`}},
+		{"version", "1.2.3", "notes.metadata @> CAST(? AS jsonb)", []any{`{"version":"1.2.3"}`}},
+		{"quote", `say "hi"`, "notes.metadata @> CAST(? AS jsonb)", []any{`{"quote":"say \"hi\""}`}},
+	}
+	for _, tt := range tests {
+		condition, args, err := metadataFilter(tt.path, tt.value)
+		if err != nil || condition != tt.condition || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("metadataFilter(%q, %q) = %q, %v, %v, want %q, %v", tt.path, tt.value, condition, args, err, tt.condition, tt.args)
+		}
+	}
+}
+
+func TestMetadataFilterRejectsBadPaths(t *testing.T) {
+	for _, path := range []string{"", "a..b", "a.", "color'", "a b", "a.b.c.d.e.f", strings.Repeat("k", 65)} {
+		if _, _, err := metadataFilter(path, "x"); err == nil {
+			t.Errorf("metadataFilter(%q) succeeded, want an error", path)
+		}
+	}
+	if _, _, err := metadataFilter("color", strings.Repeat("x", 257)); err == nil {
+		t.Error("metadataFilter() with a long value succeeded, want an error")
+	}
+}
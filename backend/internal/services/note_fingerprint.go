@@ -0,0 +1,131 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// A MinHash signature has minHashSize values, split into fingerprintBands
+// bands for locality-sensitive lookup. Notes sharing any band are compared;
+// with 16 bands of 4 values, notes 80% alike share one over 99.9% of the
+// time, and notes 30% alike under 13%.
+const (
+	minHashSize      = 64
+	fingerprintBands = 16
+	shingleSize      = 3 // Words per shingle
+)
+
+// DefaultDuplicateThreshold is how alike two notes must be, as the share
+// of word shingles they have in common, to count as near-duplicates
+const DefaultDuplicateThreshold = 0.8
+
+var fingerprintWordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// minHashSeeds are the seeds of the hash functions of a signature
+var minHashSeeds = func() [minHashSize]uint64 {
+	var seeds [minHashSize]uint64
+	state := uint64(0x5eed)
+	for i := range seeds {
+		state = splitMix64(state)
+		seeds[i] = state
+	}
+	return seeds
+}()
+
+// noteFingerprint identifies a note's content. Hash matches content that is
+// the same once case, punctuation and spacing are ignored; MinHash estimates
+// how much of the wording two notes share. Both are empty without words.
+type noteFingerprint struct {
+	Hash    string
+	MinHash []byte // minHashSize big-endian uint32 values
+}
+
+// normalizeContent lowercases content and keeps only its words
+func normalizeContent(content string) []string {
+	return fingerprintWordPattern.FindAllString(strings.ToLower(content), -1)
+}
+
+func fingerprintContent(content string) noteFingerprint {
+	// Content without words, such as only emoji, is like no other note
+	words := normalizeContent(content)
+	if len(words) == 0 {
+		return noteFingerprint{}
+	}
+	sum := sha256.Sum256([]byte(strings.Join(words, " ")))
+	fingerprint := noteFingerprint{Hash: hex.EncodeToString(sum[:])}
+
+	var signature [minHashSize]uint32
+	for i := range signature {
+		signature[i] = math.MaxUint32
+	}
+	// Short notes are a single shingle
+	count := max(len(words)-shingleSize+1, 1)
+	for i := 0; i < count; i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:min(i+shingleSize, len(words))], " ")))
+		shingle := h.Sum64()
+		for j, seed := range minHashSeeds {
+			if v := uint32(splitMix64(shingle^seed) >> 32); v < signature[j] {
+				signature[j] = v
+			}
+		}
+	}
+	fingerprint.MinHash = make([]byte, 4*minHashSize)
+	for i, v := range signature {
+		binary.BigEndian.PutUint32(fingerprint.MinHash[4*i:], v)
+	}
+	return fingerprint
+}
+
+// fingerprintBandKeys returns a key per band of a signature. Bands are
+// numbered into their keys, so keys of different bands never match.
+func fingerprintBandKeys(minHash []byte) []int64 {
+	if len(minHash) != 4*minHashSize {
+		return nil
+	}
+	rows := 4 * minHashSize / fingerprintBands
+	keys := make([]int64, fingerprintBands)
+	for band := range keys {
+		h := fnv.New64a()
+		h.Write([]byte{byte(band)})
+		h.Write(minHash[band*rows : (band+1)*rows])
+		keys[band] = int64(h.Sum64())
+	}
+	return keys
+}
+
+// minHashSimilarity estimates the share of shingles two notes have in
+// common from their signatures
+func minHashSimilarity(a, b []byte) float64 {
+	if len(a) != 4*minHashSize || len(b) != 4*minHashSize {
+		return 0
+	}
+	same := 0
+	for i := 0; i < len(a); i += 4 {
+		if binary.BigEndian.Uint32(a[i:]) == binary.BigEndian.Uint32(b[i:]) {
+			same++
+		}
+	}
+	return float64(same) / minHashSize
+}
+
+// fingerprintSimilarity is 1 for notes with the same normalized content and
+// their MinHash estimate otherwise
+func fingerprintSimilarity(a, b noteFingerprint) float64 {
+	if a.Hash != "" && a.Hash == b.Hash {
+		return 1
+	}
+	return minHashSimilarity(a.MinHash, b.MinHash)
+}
+
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,61 @@
+package services
+
+import "testing"
+
+func TestFingerprintContentHash(t *testing.T) {
+	a := fingerprintContent("The mitochondria is the powerhouse of the cell.")
+	b := fingerprintContent("  the MITOCHONDRIA is the powerhouse\nof the cell ")
+	if a.Hash != b.Hash {
+		t.Errorf("hashes differ for content that only differs in case, spacing and punctuation")
+	}
+	if fingerprintSimilarity(a, b) != 1 {
+		t.Errorf("fingerprintSimilarity() = %v, want 1", fingerprintSimilarity(a, b))
+	}
+	if c := fingerprintContent("The nucleus holds the cell's DNA."); c.Hash == a.Hash {
+		t.Errorf("different content has the same hash")
+	}
+}
+
+func TestFingerprintContentSimilarity(t *testing.T) {
+	passage := "Spaced repetition spreads reviews of material over increasing intervals to improve long term retention. " +
+		"It works because each retrieval strengthens the memory trace and slows forgetting over the following days. " +
+		"Learners who review a fact just before they would forget it need fewer sessions in total, and the gaps " +
+		"between sessions can grow from a day to weeks or months once the material is well known."
+	overlapping := passage + " and it pairs well with active recall"
+	unrelated := "photosynthesis converts light energy into chemical energy stored in glucose inside the chloroplasts of plant cells"
+
+	base := fingerprintContent(passage)
+	if got := fingerprintSimilarity(base, fingerprintContent(overlapping)); got < DefaultDuplicateThreshold {
+		t.Errorf("overlapping passage similarity = %v, want at least %v", got, DefaultDuplicateThreshold)
+	}
+	if got := fingerprintSimilarity(base, fingerprintContent(unrelated)); got > 0.2 {
+		t.Errorf("unrelated passage similarity = %v, want at most 0.2", got)
+	}
+}
+
+func TestFingerprintBandKeys(t *testing.T) {
+	a := fingerprintContent("one two three four five six seven eight nine ten")
+	b := fingerprintContent("one two three four five six seven eight nine ten eleven")
+	keysA, keysB := fingerprintBandKeys(a.MinHash), fingerprintBandKeys(b.MinHash)
+	if len(keysA) != fingerprintBands {
+		t.Fatalf("got %d band keys, want %d", len(keysA), fingerprintBands)
+	}
+	shared := 0
+	for i := range keysA {
+		if keysA[i] == keysB[i] {
+			shared++
+		}
+	}
+	if shared == 0 {
+		t.Errorf("similar notes share no band")
+	}
+
+	// Content without words matches nothing, not even other such content
+	empty := fingerprintContent("?! 🎉")
+	if empty.Hash != "" || empty.MinHash != nil || fingerprintBandKeys(empty.MinHash) != nil {
+		t.Errorf("content without words should have an empty fingerprint, got %+v", empty)
+	}
+	if got := fingerprintSimilarity(empty, fingerprintContent("...")); got != 0 {
+		t.Errorf("similarity of notes without words = %v, want 0", got)
+	}
+}
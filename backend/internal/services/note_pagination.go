@@ -0,0 +1,211 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+)
+
+const (
+	defaultNotesPageSize = 10
+	maxNotesPageSize     = 100
+)
+
+// Notes list orders
+const (
+	NoteSortCreatedAt = "created_at" // Newest first by default
+	NoteSortUpdatedAt = "updated_at" // Most recently changed first by default
+	NoteSortTitle     = "title"      // A to Z by default
+	NoteSortDomain    = "domain"     // A to Z by default
+	NoteSortPosition  = "position"   // Collection order; needs a collection filter
+)
+
+// NotePage is a page of the notes list
+type NotePage struct {
+	Notes []NoteResponse `json:"notes"`
+	Info  NotePageInfo   `json:"info"`
+}
+
+// NotePageInfo describes a page of notes. Cursors are opaque; passing one
+// back as the cursor parameter returns the next or previous page.
+type NotePageInfo struct {
+	PageSize   int    `json:"page_size"`
+	Page       int    `json:"page,omitempty"` // Set for offset pages only
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"` // Set when the total was asked for
+}
+
+// noteCursor is a decoded page cursor: the sort key and ID of the note a
+// page starts after or, going backwards, ends before
+type noteCursor struct {
+	Sort     string    `json:"s"`
+	Value    string    `json:"v"`
+	ID       uuid.UUID `json:"id"`
+	Backward bool      `json:"b,omitempty"`
+}
+
+// noteSort is an order of the notes list. Notes with the same key are
+// ordered by ID, so every note has a unique place and cursors are stable.
+type noteSort struct {
+	name  string
+	key   string // SQL expression
+	desc  bool
+	parse func(value string) (any, error) // Cursor value to query argument
+	value func(note *models.Note) string  // Note to cursor value
+}
+
+// sortByCreatedAt is the default order, newest first
+var sortByCreatedAt = noteSort{
+	name:  NoteSortCreatedAt,
+	key:   "notes.created_at",
+	desc:  true,
+	parse: parseCursorTime,
+	value: func(note *models.Note) string { return note.CreatedAt.Format(time.RFC3339Nano) },
+}
+
+var sortByUpdatedAt = noteSort{
+	name:  NoteSortUpdatedAt,
+	key:   "notes.updated_at",
+	desc:  true,
+	parse: parseCursorTime,
+	value: func(note *models.Note) string { return note.UpdatedAt.Format(time.RFC3339Nano) },
+}
+
+var sortByTitle = noteSort{
+	name:  NoteSortTitle,
+	key:   "COALESCE(notes.source_title, '')",
+	parse: parseCursorText,
+	value: func(note *models.Note) string { return note.SourceTitle },
+}
+
+var sortByDomain = noteSort{
+	name:  NoteSortDomain,
+	key:   "COALESCE(notes.domain, '')",
+	parse: parseCursorText,
+	value: func(note *models.Note) string { return note.Domain },
+}
+
+// newNoteSort returns the named order, in the given direction or its
+// default one. positions is nil unless the list is filtered to a collection,
+// whose order is then the default.
+func newNoteSort(name, order string, positions map[uuid.UUID]int) (noteSort, error) {
+	var sort noteSort
+	switch name {
+	case "":
+		sort = sortByCreatedAt
+		if positions != nil {
+			sort = sortByCollectionPosition(positions)
+		}
+	case NoteSortCreatedAt:
+		sort = sortByCreatedAt
+	case NoteSortUpdatedAt:
+		sort = sortByUpdatedAt
+	case NoteSortTitle:
+		sort = sortByTitle
+	case NoteSortDomain:
+		sort = sortByDomain
+	case NoteSortPosition:
+		if positions == nil {
+			return noteSort{}, errors.New("invalid sort")
+		}
+		sort = sortByCollectionPosition(positions)
+	default:
+		return noteSort{}, errors.New("invalid sort")
+	}
+
+	switch order {
+	case "":
+	case "asc":
+		sort.desc = false
+	case "desc":
+		sort.desc = true
+	default:
+		return noteSort{}, errors.New("invalid order")
+	}
+	return sort, nil
+}
+
+// sortByCollectionPosition orders a collection's notes as the user arranged
+// them. The positions of the notes on a page are filled in after loading it.
+func sortByCollectionPosition(positions map[uuid.UUID]int) noteSort {
+	return noteSort{
+		name: NoteSortPosition,
+		key:  "collection_notes.position",
+		parse: func(value string) (any, error) {
+			return strconv.Atoi(value)
+		},
+		value: func(note *models.Note) string { return strconv.Itoa(positions[note.ID]) },
+	}
+}
+
+// condition returns the keyset condition selecting the notes past the
+// cursor, in the direction it points
+func (o noteSort) condition(backward bool) string {
+	op := ">"
+	if o.desc != backward {
+		op = "<"
+	}
+	return fmt.Sprintf("(%s, notes.id) %s (?, ?)", o.key, op)
+}
+
+// order returns the ORDER BY clause; backward pages are read in reverse
+func (o noteSort) order(backward bool) string {
+	dir := "ASC"
+	if o.desc != backward {
+		dir = "DESC"
+	}
+	return fmt.Sprintf("%s %s, notes.id %s", o.key, dir, dir)
+}
+
+// id names the order and its direction; a cursor only fits the order it
+// was made for
+func (o noteSort) id() string {
+	if o.desc {
+		return o.name + ":desc"
+	}
+	return o.name + ":asc"
+}
+
+func (o noteSort) cursor(note *models.Note, backward bool) string {
+	return encodeNoteCursor(noteCursor{Sort: o.id(), Value: o.value(note), ID: note.ID, Backward: backward})
+}
+
+func encodeNoteCursor(cursor noteCursor) string {
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeNoteCursor(s string) (*noteCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var cursor noteCursor
+	if err := json.Unmarshal(b, &cursor); err != nil || cursor.Sort == "" || cursor.ID == uuid.Nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+func parseCursorTime(value string) (any, error) {
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+func parseCursorText(value string) (any, error) {
+	return value, nil
+}
+
+// clampNotesPageSize applies the default and maximum page sizes
+func clampNotesPageSize(pageSize int) int {
+	if pageSize < 1 {
+		return defaultNotesPageSize
+	}
+	return min(pageSize, maxNotesPageSize)
+}
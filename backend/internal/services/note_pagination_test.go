@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+)
+
+func TestNoteCursorRoundTrip(t *testing.T) {
+	note := &models.Note{ID: uuid.New(), CreatedAt: time.Date(2025, 3, 1, 12, 30, 0, 123456000, time.UTC)}
+	cursor, err := decodeNoteCursor(sortByCreatedAt.cursor(note, true))
+	if err != nil {
+		t.Fatalf("decodeNoteCursor() error = %v", err)
+	}
+	if cursor.Sort != "created_at:desc" || cursor.ID != note.ID || !cursor.Backward {
+		t.Errorf("decodeNoteCursor() = %+v, want the note's ID going backward", cursor)
+	}
+	value, err := sortByCreatedAt.parse(cursor.Value)
+	if err != nil || !value.(time.Time).Equal(note.CreatedAt) {
+		t.Errorf("parse(%q) = %v, %v, want %v", cursor.Value, value, err, note.CreatedAt)
+	}
+}
+
+func TestDecodeNoteCursorRejectsGarbage(t *testing.T) {
+	for _, s := range []string{"", "not base64!", "bm90IGpzb24", encodeNoteCursor(noteCursor{Sort: "created_at"})} {
+		if _, err := decodeNoteCursor(s); err == nil || err.Error() != "invalid cursor" {
+			t.Errorf("decodeNoteCursor(%q) error = %v, want invalid cursor", s, err)
+		}
+	}
+}
+
+func TestNoteSortKeyset(t *testing.T) {
+	tests := []struct {
+		sort      noteSort
+		backward  bool
+		condition string
+		order     string
+	}{
+		{sortByCreatedAt, false, "(notes.created_at, notes.id) < (?, ?)", "notes.created_at DESC, notes.id DESC"},
+		{sortByCreatedAt, true, "(notes.created_at, notes.id) > (?, ?)", "notes.created_at ASC, notes.id ASC"},
+		{sortByCollectionPosition(nil), false, "(collection_notes.position, notes.id) > (?, ?)", "collection_notes.position ASC, notes.id ASC"},
+		{sortByCollectionPosition(nil), true, "(collection_notes.position, notes.id) < (?, ?)", "collection_notes.position DESC, notes.id DESC"},
+	}
+	for _, tt := range tests {
+		if got := tt.sort.condition(tt.backward); got != tt.condition {
+			t.Errorf("%s condition(%v) = %q, want %q", tt.sort.name, tt.backward, got, tt.condition)
+		}
+		if got := tt.sort.order(tt.backward); got != tt.order {
+			t.Errorf("%s order(%v) = %q, want %q", tt.sort.name, tt.backward, got, tt.order)
+		}
+	}
+}
+
+func TestNewNoteSort(t *testing.T) {
+	positions := map[uuid.UUID]int{}
+	tests := []struct {
+		name, order string
+		positions   map[uuid.UUID]int
+		want, err   string
+	}{
+		{"", "", nil, "created_at:desc", ""},
+		{"", "", positions, "position:asc", ""},
+		{"created_at", "", positions, "created_at:desc", ""},
+		{"updated_at", "asc", nil, "updated_at:asc", ""},
+		{"title", "", nil, "title:asc", ""},
+		{"domain", "desc", nil, "domain:desc", ""},
+		{"position", "", nil, "", "invalid sort"},
+		{"content", "", nil, "", "invalid sort"},
+		{"title", "up", nil, "", "invalid order"},
+	}
+	for _, tt := range tests {
+		sort, err := newNoteSort(tt.name, tt.order, tt.positions)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("newNoteSort(%q, %q) error = %v, want %s", tt.name, tt.order, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || sort.id() != tt.want {
+			t.Errorf("newNoteSort(%q, %q) = %q, %v, want %q", tt.name, tt.order, sort.id(), err, tt.want)
+		}
+	}
+}
+
+func TestClampNotesPageSize(t *testing.T) {
+	for size, want := range map[int]int{-1: 10, 0: 10, 1: 1, 50: 50, 100: 100, 1000: 100} {
+		if got := clampNotesPageSize(size); got != want {
+			t.Errorf("clampNotesPageSize(%d) = %d, want %d", size, got, want)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// MaxNoteRevisionLimit is the most revisions a user can keep per note
+const MaxNoteRevisionLimit = 500
+
+// Note fields tracked by revisions
+const (
+	RevisionFieldContent     = "content"
+	RevisionFieldSourceURL   = "source_url"
+	RevisionFieldSourceTitle = "source_title"
+	RevisionFieldDomain      = "domain"
+	RevisionFieldMetadata    = "metadata"
+	RevisionFieldTags        = "tags"
+)
+
+// revisionFields lists the tracked fields in the order they are reported
+var revisionFields = []string{
+	RevisionFieldContent,
+	RevisionFieldSourceURL,
+	RevisionFieldSourceTitle,
+	RevisionFieldDomain,
+	RevisionFieldMetadata,
+	RevisionFieldTags,
+}
+
+// NoteRevisionSummary describes a revision without the note's content
+type NoteRevisionSummary struct {
+	Number        int      `json:"number"`
+	AuthorID      string   `json:"author_id"`
+	ChangedFields []string `json:"changed_fields"`
+	RevertedFrom  *int     `json:"reverted_from,omitempty"` // Revision the change went back to
+	CreatedAt     string   `json:"created_at"`
+}
+
+// NoteRevisionResponse is a revision with the note as it was after it
+type NoteRevisionResponse struct {
+	NoteRevisionSummary
+	Content     string         `json:"content"`
+	SourceURL   string         `json:"source_url,omitempty"`
+	SourceTitle string         `json:"source_title,omitempty"`
+	Domain      string         `json:"domain,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	Tags        []string       `json:"tags"`
+}
+
+// RevisionDiffResponse compares two revisions of a note. Content is diffed
+// word by word; other fields are listed with both values when they differ.
+type RevisionDiffResponse struct {
+	From    int                            `json:"from"`
+	To      int                            `json:"to"`
+	Content []DiffOp                       `json:"content"`
+	Fields  map[string]RevisionFieldChange `json:"fields"`
+}
+
+type RevisionFieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// ListRevisions returns the kept revisions of the user's note, newest first
+func (s *NotesService) ListRevisions(noteID, userID string) ([]NoteRevisionSummary, error) {
+	if err := s.checkNote(noteID, userID); err != nil {
+		return nil, err
+	}
+	var revisions []models.NoteRevision
+	err := s.db.Omit("content", "metadata").
+		Where("note_id = ?", noteID).
+		Order("number DESC").
+		Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make([]NoteRevisionSummary, len(revisions))
+	for i := range revisions {
+		result[i] = toRevisionSummary(&revisions[i])
+	}
+	return result, nil
+}
+
+// GetRevision returns one revision of the user's note
+func (s *NotesService) GetRevision(noteID, userID string, number int) (*NoteRevisionResponse, error) {
+	if err := s.checkNote(noteID, userID); err != nil {
+		return nil, err
+	}
+	revision, err := findRevision(s.db, noteID, number)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := revisionSnapshot(revision)
+	return &NoteRevisionResponse{
+		NoteRevisionSummary: toRevisionSummary(revision),
+		Content:             snapshot.content,
+		SourceURL:           snapshot.sourceURL,
+		SourceTitle:         snapshot.sourceTitle,
+		Domain:              snapshot.domain,
+		Metadata:            snapshot.field(RevisionFieldMetadata).(map[string]any),
+		Tags:                snapshot.tags,
+	}, nil
+}
+
+// DiffRevisions compares two revisions of the user's note. A zero to means
+// the latest revision and a zero from the one kept before to.
+func (s *NotesService) DiffRevisions(noteID, userID string, from, to int) (*RevisionDiffResponse, error) {
+	if err := s.checkNote(noteID, userID); err != nil {
+		return nil, err
+	}
+	if to == 0 {
+		err := s.db.Model(&models.NoteRevision{}).
+			Where("note_id = ?", noteID).
+			Select("COALESCE(MAX(number), 0)").
+			Scan(&to).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	if from == 0 {
+		err := s.db.Model(&models.NoteRevision{}).
+			Where("note_id = ? AND number < ?", noteID, to).
+			Select("COALESCE(MAX(number), 0)").
+			Scan(&from).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	fromRevision, err := findRevision(s.db, noteID, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := findRevision(s.db, noteID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	before, after := revisionSnapshot(fromRevision), revisionSnapshot(toRevision)
+	result := &RevisionDiffResponse{
+		From:    from,
+		To:      to,
+		Content: diffWords(before.content, after.content),
+		Fields:  make(map[string]RevisionFieldChange),
+	}
+	for _, field := range before.changedFields(after) {
+		if field != RevisionFieldContent {
+			result.Fields[field] = RevisionFieldChange{From: before.field(field), To: after.field(field)}
+		}
+	}
+	return result, nil
+}
+
+// RevertNote puts the user's note back as it was at a revision. The revert
+// is itself recorded as a new revision, so it can be undone the same way.
+func (s *NotesService) RevertNote(noteID, userID string, number int) (*NoteResponse, error) {
+	authorID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+	var note models.Note
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockNote(tx, noteID, userID); err != nil {
+			return err
+		}
+		revision, err := findRevision(tx, noteID, number)
+		if err != nil {
+			return err
+		}
+		if err := preloadNoteLinks(tx).Where("id = ?", noteID).First(&note).Error; err != nil {
+			return err
+		}
+		before := snapshotNote(&note)
+		target := revisionSnapshot(revision)
+		if len(before.changedFields(target)) == 0 {
+			return nil
+		}
+
+		note.Content = target.content
+		note.SourceURL = target.sourceURL
+		note.SourceTitle = target.sourceTitle
+		note.Domain = target.domain
+		note.Metadata = target.metadata
+		// Tags deleted since are created again
+		tags, err := resolveTags(tx, note.UserID, target.tags)
+		if err != nil {
+			return err
+		}
+		note.Tags = tags
+		return saveNote(tx, &note, true, &before, authorID, &number)
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := toNoteResponse(&note)
+	return &response, nil
+}
+
+// checkNote returns an error unless the note exists and belongs to the user
+func (s *NotesService) checkNote(noteID, userID string) error {
+	if _, err := uuid.Parse(noteID); err != nil {
+		return errors.New("note not found")
+	}
+	var count int64
+	if err := s.db.Model(&models.Note{}).Where("id = ? AND user_id = ?", noteID, userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("note not found")
+	}
+	return nil
+}
+
+func findRevision(db *gorm.DB, noteID string, number int) (*models.NoteRevision, error) {
+	var revision models.NoteRevision
+	if err := db.Where("note_id = ? AND number = ?", noteID, number).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("revision not found")
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// recordRevision stores the note as a new revision when it differs from
+// before, then prunes revisions past its owner's limit. A nil before means
+// the note was just created. Notes saved before revisions were kept get
+// their earlier state recorded first, so the change can be undone.
+func recordRevision(tx *gorm.DB, note *models.Note, before *noteSnapshot, authorID uuid.UUID, revertedFrom *int) error {
+	after := snapshotNote(note)
+	changed := revisionFields
+	if before != nil {
+		changed = before.changedFields(after)
+		if len(changed) == 0 {
+			return nil
+		}
+	}
+
+	var last int
+	err := tx.Model(&models.NoteRevision{}).
+		Where("note_id = ?", note.ID).
+		Select("COALESCE(MAX(number), 0)").
+		Scan(&last).Error
+	if err != nil {
+		return err
+	}
+	if last == 0 && before != nil {
+		baseline := before.revision(note.ID, note.UserID, 1, revisionFields, nil)
+		baseline.CreatedAt = before.updatedAt
+		if err := tx.Create(&baseline).Error; err != nil {
+			return err
+		}
+		last = 1
+	}
+	revision := after.revision(note.ID, authorID, last+1, changed, revertedFrom)
+	if err := tx.Create(&revision).Error; err != nil {
+		return err
+	}
+
+	var limit int
+	if err := tx.Model(&models.User{}).Where("id = ?", note.UserID).Select("note_revision_limit").Scan(&limit).Error; err != nil {
+		return err
+	}
+	if limit < 1 {
+		return nil
+	}
+	return tx.Where("note_id = ? AND number <= ?", note.ID, revision.Number-limit).Delete(&models.NoteRevision{}).Error
+}
+
+// noteSnapshot holds the tracked fields of a note at one point
+type noteSnapshot struct {
+	content     string
+	sourceURL   string
+	sourceTitle string
+	domain      string
+	metadata    datatypes.JSON
+	tags        []string // Sorted names
+	updatedAt   time.Time
+}
+
+func snapshotNote(note *models.Note) noteSnapshot {
+	tags := make([]string, len(note.Tags))
+	for i, tag := range note.Tags {
+		tags[i] = tag.Name
+	}
+	slices.SortFunc(tags, func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+	return noteSnapshot{
+		content:     note.Content,
+		sourceURL:   note.SourceURL,
+		sourceTitle: note.SourceTitle,
+		domain:      note.Domain,
+		metadata:    note.Metadata,
+		tags:        tags,
+		updatedAt:   note.UpdatedAt,
+	}
+}
+
+func revisionSnapshot(revision *models.NoteRevision) noteSnapshot {
+	tags := []string{}
+	if len(revision.Tags) > 0 {
+		_ = json.Unmarshal(revision.Tags, &tags)
+	}
+	return noteSnapshot{
+		content:     revision.Content,
+		sourceURL:   revision.SourceURL,
+		sourceTitle: revision.SourceTitle,
+		domain:      revision.Domain,
+		metadata:    revision.Metadata,
+		tags:        tags,
+		updatedAt:   revision.CreatedAt,
+	}
+}
+
+// changedFields lists the tracked fields that differ in other
+func (n noteSnapshot) changedFields(other noteSnapshot) []string {
+	var changed []string
+	for _, field := range revisionFields {
+		if !reflect.DeepEqual(n.field(field), other.field(field)) {
+			changed = append(changed, field)
+		}
+	}
+	return changed
+}
+
+// field returns a tracked field as it is shown in API responses. Metadata
+// is decoded, so formatting differences in the stored JSON do not count.
+func (n noteSnapshot) field(name string) any {
+	switch name {
+	case RevisionFieldContent:
+		return n.content
+	case RevisionFieldSourceURL:
+		return n.sourceURL
+	case RevisionFieldSourceTitle:
+		return n.sourceTitle
+	case RevisionFieldDomain:
+		return n.domain
+	case RevisionFieldMetadata:
+		var metadata map[string]any
+		if len(n.metadata) > 0 {
+			_ = json.Unmarshal(n.metadata, &metadata)
+		}
+		if len(metadata) == 0 {
+			return map[string]any(nil)
+		}
+		return metadata
+	case RevisionFieldTags:
+		return n.tags
+	}
+	return nil
+}
+
+func (n noteSnapshot) revision(noteID, authorID uuid.UUID, number int, changed []string, revertedFrom *int) models.NoteRevision {
+	changedJSON, _ := json.Marshal(changed)
+	tagsJSON, _ := json.Marshal(n.tags)
+	return models.NoteRevision{
+		NoteID:        noteID,
+		Number:        number,
+		AuthorID:      authorID,
+		ChangedFields: datatypes.JSON(changedJSON),
+		RevertedFrom:  revertedFrom,
+		Content:       n.content,
+		SourceURL:     n.sourceURL,
+		SourceTitle:   n.sourceTitle,
+		Domain:        n.domain,
+		Metadata:      n.metadata,
+		Tags:          datatypes.JSON(tagsJSON),
+	}
+}
+
+func toRevisionSummary(revision *models.NoteRevision) NoteRevisionSummary {
+	changed := []string{}
+	if len(revision.ChangedFields) > 0 {
+		_ = json.Unmarshal(revision.ChangedFields, &changed)
+	}
+	return NoteRevisionSummary{
+		Number:        revision.Number,
+		AuthorID:      revision.AuthorID.String(),
+		ChangedFields: changed,
+		RevertedFrom:  revision.RevertedFrom,
+		CreatedAt:     revision.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/datatypes"
+)
+
+func TestNoteSnapshotChangedFields(t *testing.T) {
+	note := &models.Note{
+		Content:  "first draft",
+		Domain:   "example.com",
+		Metadata: datatypes.JSON(`{"b": 1, "a": {"c": true}}`),
+		Tags:     []models.Tag{{Name: "work"}, {Name: "Go"}},
+	}
+	before := snapshotNote(note)
+	if want := []string{"Go", "work"}; !reflect.DeepEqual(before.tags, want) {
+		t.Fatalf("snapshot tags = %v, want %v", before.tags, want)
+	}
+
+	// Stored JSON is formatted differently from what was sent
+	same := *note
+	same.Metadata = datatypes.JSON(`{"a":{"c":true},"b":1}`)
+	same.Tags = []models.Tag{{Name: "Go"}, {Name: "work"}}
+	if changed := before.changedFields(snapshotNote(&same)); changed != nil {
+		t.Errorf("changedFields() = %v for an unchanged note", changed)
+	}
+
+	edited := same
+	edited.Content = "second draft"
+	edited.Metadata = nil
+	edited.Tags = []models.Tag{{Name: "Go"}}
+	want := []string{RevisionFieldContent, RevisionFieldMetadata, RevisionFieldTags}
+	if changed := before.changedFields(snapshotNote(&edited)); !reflect.DeepEqual(changed, want) {
+		t.Errorf("changedFields() = %v, want %v", changed, want)
+	}
+
+	// A revision snapshot reads back equal to the note it was taken from
+	revision := before.revision(note.ID, note.UserID, 1, revisionFields, nil)
+	if changed := before.changedFields(revisionSnapshot(&revision)); changed != nil {
+		t.Errorf("changedFields() = %v against its own revision", changed)
+	}
+}
+
+func TestNoteSnapshotEmptyMetadata(t *testing.T) {
+	none := snapshotNote(&models.Note{Content: "note"})
+	empty := snapshotNote(&models.Note{Content: "note", Metadata: datatypes.JSON(`{}`)})
+	if changed := none.changedFields(empty); changed != nil {
+		t.Errorf("changedFields() = %v between no and empty metadata", changed)
+	}
+}
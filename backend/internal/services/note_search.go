@@ -0,0 +1,205 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+)
+
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 100
+	maxSearchQueryLength  = 256
+)
+
+// headlineOptions configures ts_headline. Matches are wrapped in <mark>
+// tags; the text around them is the note as stored, not HTML-escaped.
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=\" … \""
+
+type NoteSearchResult struct {
+	NoteResponse
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type NoteSearchPage struct {
+	Results  []NoteSearchResult `json:"results"`
+	Total    int64              `json:"total"`
+	Page     int                `json:"page"`
+	PageSize int                `json:"page_size"`
+}
+
+type noteSearchRow struct {
+	ID      uuid.UUID
+	Rank    float64
+	Snippet string
+}
+
+// searchTerm is one part of a search query
+type searchTerm struct {
+	Text   string
+	Phrase bool // Quoted words that must appear in order
+	Prefix bool // Trailing *, matches words starting with Text
+	Negate bool // Leading -, excludes notes matching the term
+}
+
+// SearchNotes runs a full-text search over the user's notes, best matches
+// first. The query supports plain words, "quoted phrases", prefix* terms and
+// -excluded terms; every term must match.
+func (s *NotesService) SearchNotes(userID, query string, page, pageSize int) (*NoteSearchPage, error) {
+	if len(query) > maxSearchQueryLength {
+		return nil, errors.New("search query too long")
+	}
+	tsquery, args := buildTSQuery(parseSearchQuery(query))
+	if tsquery == "" {
+		return nil, errors.New("search query required")
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > maxSearchPageSize {
+		pageSize = defaultSearchPageSize
+	}
+
+	db := s.db.Model(&models.Note{}).
+		Joins("CROSS JOIN (SELECT "+tsquery+" AS query) AS search", args...).
+		Where("notes.user_id = ? AND notes.search_vector @@ search.query", userID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []noteSearchRow
+	err := db.Select("notes.id, ts_rank_cd(notes.search_vector, search.query) AS rank, ts_headline('english', notes.content, search.query, ?) AS snippet", headlineOptions).
+		Order("rank DESC, notes.created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	var notes []models.Note
+	if err := preloadNoteLinks(s.db).Where("id IN ?", ids).Find(&notes).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.Note, len(notes))
+	for i := range notes {
+		byID[notes[i].ID] = &notes[i]
+	}
+
+	result := &NoteSearchPage{
+		Results:  make([]NoteSearchResult, 0, len(rows)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	for _, row := range rows {
+		// A note deleted between the two queries is left out
+		note, ok := byID[row.ID]
+		if !ok {
+			continue
+		}
+		result.Results = append(result.Results, NoteSearchResult{
+			NoteResponse: toNoteResponse(note),
+			Rank:         row.Rank,
+			Snippet:      row.Snippet,
+		})
+	}
+	return result, nil
+}
+
+// parseSearchQuery splits a query into terms. Quotes group words into a
+// phrase, a trailing * makes a prefix term and a leading - negates a term.
+func parseSearchQuery(query string) []searchTerm {
+	var terms []searchTerm
+	rest := strings.TrimSpace(query)
+	for rest != "" {
+		var term searchTerm
+		if strings.HasPrefix(rest, "-") {
+			term.Negate = true
+			rest = rest[1:]
+		}
+
+		var raw string
+		if strings.HasPrefix(rest, `"`) {
+			phrase, after, found := strings.Cut(rest[1:], `"`)
+			if !found {
+				after = ""
+			}
+			raw, rest = phrase, after
+			term.Phrase = true
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 {
+				end = len(rest)
+			}
+			raw, rest = rest[:end], rest[end:]
+			if strings.HasSuffix(raw, "*") {
+				term.Prefix = true
+			}
+		}
+		rest = strings.TrimSpace(rest)
+
+		term.Text = strings.Join(searchWords(raw), " ")
+		if term.Text == "" {
+			continue
+		}
+		if term.Phrase && !strings.Contains(term.Text, " ") {
+			term.Phrase = false
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// searchWords keeps the letters and digits of s, split into words. Dropping
+// everything else keeps tsquery operators out of user input.
+func searchWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// buildTSQuery turns terms into an SQL tsquery expression and its bind
+// arguments. Terms are ANDed together; negated terms are excluded.
+func buildTSQuery(terms []searchTerm) (string, []any) {
+	var parts []string
+	var args []any
+	positive := false
+	for _, term := range terms {
+		var part string
+		switch {
+		case term.Phrase:
+			part = "phraseto_tsquery('english', ?)"
+			args = append(args, term.Text)
+		case term.Prefix:
+			// Words are letters and digits only, so they are safe to hand
+			// to to_tsquery with the prefix marker on the last one
+			part = "to_tsquery('english', ?)"
+			args = append(args, strings.Join(strings.Fields(term.Text), " & ")+":*")
+		default:
+			part = "plainto_tsquery('english', ?)"
+			args = append(args, term.Text)
+		}
+		if term.Negate {
+			part = "!!" + part
+		} else {
+			positive = true
+		}
+		parts = append(parts, part)
+	}
+	// A query of only exclusions would match nearly every note
+	if !positive {
+		return "", nil
+	}
+	return "(" + strings.Join(parts, " && ") + ")", args
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []searchTerm
+	}{
+		{"", nil},
+		{"spaced   repetition", []searchTerm{{Text: "spaced"}, {Text: "repetition"}}},
+		{`"active recall" notes`, []searchTerm{{Text: "active recall", Phrase: true}, {Text: "notes"}}},
+		{"neur* -biology", []searchTerm{{Text: "neur", Prefix: true}, {Text: "biology", Negate: true}}},
+		{`-"lecture one"`, []searchTerm{{Text: "lecture one", Phrase: true, Negate: true}}},
+		{`"single"`, []searchTerm{{Text: "single"}}},
+		{`"unterminated phrase`, []searchTerm{{Text: "unterminated phrase", Phrase: true}}},
+		{"e-mail", []searchTerm{{Text: "e mail"}}},
+		{"it's & (rm) | !x:* -", []searchTerm{{Text: "it s"}, {Text: "rm"}, {Text: "x", Prefix: true}}},
+		{"café über", []searchTerm{{Text: "café"}, {Text: "über"}}},
+	}
+
+	for _, tt := range tests {
+		if got := parseSearchQuery(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSearchQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestBuildTSQuery(t *testing.T) {
+	query, args := buildTSQuery(parseSearchQuery(`"active recall" neuro-sci* -biology`))
+	wantQuery := "(phraseto_tsquery('english', ?) && to_tsquery('english', ?) && !!plainto_tsquery('english', ?))"
+	wantArgs := []any{"active recall", "neuro & sci:*", "biology"}
+	if query != wantQuery || !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("got %q %v, want %q %v", query, args, wantQuery, wantArgs)
+	}
+
+	// Exclusions alone would match almost everything
+	if query, _ := buildTSQuery(parseSearchQuery("-biology -chemistry")); query != "" {
+		t.Errorf("query of only exclusions = %q, want none", query)
+	}
+	if query, _ := buildTSQuery(parseSearchQuery(`"" * -`)); query != "" {
+		t.Errorf("query without words = %q, want none", query)
+	}
+}
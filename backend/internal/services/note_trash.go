@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/database"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// trashPurgeBatchSize bounds how many notes one purge statement deletes
+const trashPurgeBatchSize = 500
+
+// NoteTrashService manages deleted notes until they are purged
+type NoteTrashService struct {
+	db        *gorm.DB
+	retention time.Duration
+}
+
+type TrashedNoteResponse struct {
+	NoteResponse
+	DeletedAt string `json:"deleted_at"`
+	PurgeAt   string `json:"purge_at"` // When the note is deleted for good
+}
+
+// TrashPage is a page of the trash, most recently deleted first
+type TrashPage struct {
+	Notes []TrashedNoteResponse `json:"notes"`
+	Info  NotePageInfo          `json:"info"`
+}
+
+func NewNoteTrashService(cfg *config.Config) *NoteTrashService {
+	return &NoteTrashService{
+		db:        database.DB,
+		retention: cfg.NoteTrashRetention,
+	}
+}
+
+// ListTrash returns the user's trashed notes, most recently deleted first
+func (s *NoteTrashService) ListTrash(userID string, page, pageSize int) (*TrashPage, error) {
+	page = max(page, 1)
+	pageSize = clampNotesPageSize(pageSize)
+
+	db := s.trashed().Where("user_id = ?", userID)
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	var notes []models.Note
+	err := preloadNoteLinks(db).
+		Order("deleted_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&notes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TrashPage{
+		Notes: make([]TrashedNoteResponse, len(notes)),
+		Info:  NotePageInfo{Page: page, PageSize: pageSize, Total: &total},
+	}
+	for i := range notes {
+		result.Notes[i] = s.toTrashedNoteResponse(&notes[i])
+	}
+	return result, nil
+}
+
+// RestoreNote takes a note out of the trash with its tags and collections
+func (s *NoteTrashService) RestoreNote(userID, noteID string) (*NoteResponse, error) {
+	if _, err := uuid.Parse(noteID); err != nil {
+		return nil, errors.New("note not found")
+	}
+	// UpdateColumn leaves updated_at alone; restoring is not an edit
+	result := s.trashed().
+		Where("id = ? AND user_id = ?", noteID, userID).
+		UpdateColumn("deleted_at", nil)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("note not found")
+	}
+
+	var note models.Note
+	if err := preloadNoteLinks(s.db).Where("id = ?", noteID).First(&note).Error; err != nil {
+		return nil, err
+	}
+	response := toNoteResponse(&note)
+	return &response, nil
+}
+
+// DeleteNote permanently deletes a note in the trash
+func (s *NoteTrashService) DeleteNote(userID, noteID string) error {
+	if _, err := uuid.Parse(noteID); err != nil {
+		return errors.New("note not found")
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		deleted, err := purgeNotes(tx, "id = ? AND user_id = ? AND deleted_at IS NOT NULL", noteID, userID)
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return errors.New("note not found")
+		}
+		return nil
+	})
+}
+
+// EmptyTrash permanently deletes every note in the user's trash and returns
+// how many there were
+func (s *NoteTrashService) EmptyTrash(userID string) (int64, error) {
+	var deleted int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		deleted, err = purgeNotes(tx, "user_id = ? AND deleted_at IS NOT NULL", userID)
+		return err
+	})
+	return deleted, err
+}
+
+// PurgeExpired permanently deletes notes that have been in the trash longer
+// than the retention period, in batches so no transaction grows too large.
+// It returns how many notes were deleted.
+func (s *NoteTrashService) PurgeExpired(ctx context.Context) (int64, error) {
+	cutoff := s.purgeCutoff(time.Now())
+	var purged int64
+	for {
+		var deleted int64
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var ids []uuid.UUID
+			err := tx.Unscoped().Model(&models.Note{}).
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+				Limit(trashPurgeBatchSize).
+				Pluck("id", &ids).Error
+			if err != nil || len(ids) == 0 {
+				return err
+			}
+			deleted, err = purgeNotes(tx, "id IN ?", ids)
+			return err
+		})
+		purged += deleted
+		if err != nil || deleted < trashPurgeBatchSize {
+			return purged, err
+		}
+	}
+}
+
+// trashed scopes a query to notes in the trash
+func (s *NoteTrashService) trashed() *gorm.DB {
+	return s.db.Unscoped().Model(&models.Note{}).Where("deleted_at IS NOT NULL")
+}
+
+// purgeAt is when a note deleted at deletedAt is purged
+func (s *NoteTrashService) purgeAt(deletedAt time.Time) time.Time {
+	return deletedAt.Add(s.retention)
+}
+
+// purgeCutoff is the deletion time before which notes are purged at now
+func (s *NoteTrashService) purgeCutoff(now time.Time) time.Time {
+	return now.Add(-s.retention)
+}
+
+func (s *NoteTrashService) toTrashedNoteResponse(note *models.Note) TrashedNoteResponse {
+	return TrashedNoteResponse{
+		NoteResponse: toNoteResponse(note),
+		DeletedAt:    note.DeletedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		PurgeAt:      s.purgeAt(note.DeletedAt.Time).Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestTrashPurgeTimes(t *testing.T) {
+	s := &NoteTrashService{retention: 30 * 24 * time.Hour}
+	now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+	cutoff := s.purgeCutoff(now)
+	if want := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC); !cutoff.Equal(want) {
+		t.Fatalf("purgeCutoff() = %v, want %v", cutoff, want)
+	}
+
+	// A note is purged by the first run after its purge_at
+	for _, deletedAt := range []time.Time{cutoff.Add(-time.Second), cutoff.Add(time.Second)} {
+		purged := deletedAt.Before(cutoff)
+		if due := s.purgeAt(deletedAt).Before(now); due != purged {
+			t.Errorf("note deleted at %v: purge_at %v due = %v, but purged = %v", deletedAt, s.purgeAt(deletedAt), due, purged)
+		}
+	}
+}
+
+func TestToTrashedNoteResponse(t *testing.T) {
+	s := &NoteTrashService{retention: 7 * 24 * time.Hour}
+	deletedAt := time.Date(2025, 5, 30, 8, 15, 0, 0, time.UTC)
+	note := &models.Note{ID: uuid.New(), Content: "note", DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true}}
+
+	response := s.toTrashedNoteResponse(note)
+	if response.DeletedAt != "2025-05-30T08:15:00Z" {
+		t.Errorf("deleted_at = %s, want 2025-05-30T08:15:00Z", response.DeletedAt)
+	}
+	if response.PurgeAt != "2025-06-06T08:15:00Z" {
+		t.Errorf("purge_at = %s, want 2025-06-06T08:15:00Z", response.PurgeAt)
+	}
+	if response.ID != note.ID.String() {
+		t.Errorf("id = %s, want %s", response.ID, note.ID)
+	}
+}
+
+func TestTrashOnlyTouchesTrashedNotes(t *testing.T) {
+	userID, noteID := uuid.NewString(), uuid.New()
+	note := models.Note{ID: noteID, Content: "note"}
+
+	db := newFakeDB(t, note)
+	s := &NoteTrashService{db: db.DB, retention: time.Hour}
+	if _, err := s.RestoreNote(userID, noteID.String()); err != nil {
+		t.Fatalf("RestoreNote() error = %v", err)
+	}
+	if !db.wrote(`UPDATE "notes" SET "deleted_at"=NULL WHERE deleted_at IS NOT NULL AND`, noteID.String(), userID) {
+		t.Errorf("RestoreNote() did not limit the update to trashed notes: %q", db.writes)
+	}
+
+	db = newFakeDB(t)
+	s.db = db.DB
+	if err := s.DeleteNote(userID, noteID.String()); err != nil {
+		t.Fatalf("DeleteNote() error = %v", err)
+	}
+	if !db.wrote(`DELETE FROM "notes" WHERE`, "deleted_at IS NOT NULL", noteID.String(), userID) {
+		t.Errorf("DeleteNote() did not limit the delete to trashed notes: %q", db.writes)
+	}
+	for _, table := range noteLinkTables {
+		if !db.wrote(`DELETE FROM "`+table.name+`" WHERE note_id IN (SELECT "id" FROM "notes" WHERE`, "deleted_at IS NOT NULL", noteID.String()) {
+			t.Errorf("DeleteNote() did not limit the %s delete to trashed notes: %q", table.name, db.writes)
+		}
+	}
+
+	// Nothing is deleted when the note is not in the trash
+	db = newFakeDB(t)
+	db.affected = 0
+	s.db = db.DB
+	if err := s.DeleteNote(userID, noteID.String()); err == nil || err.Error() != "note not found" {
+		t.Errorf("DeleteNote() error = %v for a note outside the trash, want note not found", err)
+	}
+}
+
+func TestPurgeExpiredDeletesSelectedNotes(t *testing.T) {
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	db := newFakeDB(t, ids)
+	s := &NoteTrashService{db: db.DB, retention: time.Hour}
+
+	purged, err := s.PurgeExpired(context.Background())
+	if err != nil {
+		t.Fatalf("PurgeExpired() error = %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("PurgeExpired() = %d, want the 1 row the delete reported", purged)
+	}
+	if !db.wrote(`DELETE FROM "notes" WHERE id IN ('` + ids[0].String() + `','` + ids[1].String() + `')`) {
+		t.Errorf("PurgeExpired() did not delete the expired notes by ID: %q", db.writes)
+	}
+}
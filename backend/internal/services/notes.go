@@ -5,17 +5,27 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/pratts/tts-study-assistant/backend/internal/database"
 	"github.com/pratts/tts-study-assistant/backend/internal/models"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tag filter modes
+const (
+	TagModeAll = "all" // Notes with every listed tag
+	TagModeAny = "any" // Notes with at least one listed tag
 )
 
 type NotesService struct {
-	db *gorm.DB
+	db          *gorm.DB
+	collections *CollectionService
 }
 
 type CreateNoteRequest struct {
@@ -24,6 +34,11 @@ type CreateNoteRequest struct {
 	SourceTitle string         `json:"source_title,omitempty"`
 	Domain      string         `json:"domain,omitempty"`
 	Metadata    map[string]any `json:"metadata,omitempty"`
+	Tags        []string       `json:"tags,omitempty"` // Tag names, created if missing
+
+	// Return the existing note instead when the user already has one that
+	// is near-identical
+	ReturnExisting bool `json:"return_existing,omitempty"`
 }
 
 type UpdateNoteRequest struct {
@@ -32,18 +47,46 @@ type UpdateNoteRequest struct {
 	SourceTitle string         `json:"source_title,omitempty"`
 	Domain      string         `json:"domain,omitempty"`
 	Metadata    map[string]any `json:"metadata,omitempty"`
+	Tags        *[]string      `json:"tags,omitempty"` // Replaces the tags when set; [] removes them all
 }
 
 type NoteResponse struct {
-	ID          string         `json:"id"`
-	Content     string         `json:"content"`
-	SourceURL   string         `json:"source_url,omitempty"`
-	SourceTitle string         `json:"source_title,omitempty"`
-	Domain      string         `json:"domain,omitempty"`   // Main domain for the note
-	Metadata    map[string]any `json:"metadata,omitempty"` // Arbitrary metadata for the note
-	CreatedAt   string         `json:"created_at"`
-	UpdatedAt   string         `json:"updated_at"`
-	Summary     string         `json:"summary,omitempty"`
+	ID          string                   `json:"id"`
+	Content     string                   `json:"content"`
+	SourceURL   string                   `json:"source_url,omitempty"`
+	SourceTitle string                   `json:"source_title,omitempty"`
+	Domain      string                   `json:"domain,omitempty"`   // Main domain for the note
+	Metadata    map[string]any           `json:"metadata,omitempty"` // Arbitrary metadata for the note
+	Tags        []NoteTagResponse        `json:"tags"`
+	Collections []NoteCollectionResponse `json:"collections"`
+	CreatedAt   string                   `json:"created_at"`
+	UpdatedAt   string                   `json:"updated_at"`
+	Summary     string                   `json:"summary,omitempty"`
+}
+
+// NoteQuery filters and pages the notes list. A Cursor from a previous
+// page takes precedence over Page, which older clients use for offset
+// paging.
+type NoteQuery struct {
+	Page         int
+	PageSize     int
+	Cursor       string
+	IncludeTotal bool
+	SourceURL    string
+	Domain       string
+	Tags         []string // Tag names
+	TagMode      string   // TagModeAll (default) or TagModeAny
+	CollectionID string   // Notes in the collection, in its order by default
+
+	CreatedSince *time.Time // Inclusive
+	CreatedUntil *time.Time // Exclusive
+	UpdatedSince *time.Time
+	UpdatedUntil *time.Time
+	HasSummary   *bool
+	Metadata     map[string]string // Dotted metadata key path to the value it must have
+
+	Sort  string // One of the NoteSort constants
+	Order string // "asc" or "desc"; each sort has a default
 }
 
 type NotesStats struct {
@@ -51,86 +94,122 @@ type NotesStats struct {
 	Count  int    `json:"count"`
 }
 
+type TagStats struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+	Count int    `json:"count"`
+}
+
+type NotesStatsResponse struct {
+	Domains []NotesStats `json:"domains"`
+	Tags    []TagStats   `json:"tags"`
+}
+
 func NewNotesService() *NotesService {
 	return &NotesService{
-		db: database.DB,
+		db:          database.DB,
+		collections: NewCollectionService(),
 	}
 }
 
-func (s *NotesService) GetNotes(userID string, page, pageSize int, sourceURL string, domain string) ([]NoteResponse, error) {
+func (s *NotesService) GetNotes(userID string, query *NoteQuery) (*NotePage, error) {
 	var notes []models.Note
-	db := s.db.Where("user_id = ?", userID)
-	if sourceURL != "" {
-		db = db.Where("source_url = ?", sourceURL)
+	db, err := s.filterNotes(s.db, userID, query)
+	if err != nil {
+		return nil, err
 	}
-	if domain != "" {
-		db = db.Where("domain = ?", domain)
+	var positions map[uuid.UUID]int
+	if query.CollectionID != "" {
+		positions = make(map[uuid.UUID]int)
 	}
-	db = db.Order("created_at DESC")
-	if page < 1 {
-		page = 1
+	sort, err := newNoteSort(query.Sort, query.Order, positions)
+	if err != nil {
+		return nil, err
 	}
-	if pageSize < 1 {
-		pageSize = 10
+
+	pageSize := clampNotesPageSize(query.PageSize)
+	page := &NotePage{Info: NotePageInfo{PageSize: pageSize}}
+	if query.IncludeTotal {
+		var total int64
+		if err := db.Count(&total).Error; err != nil {
+			return nil, err
+		}
+		page.Info.Total = &total
 	}
-	db = db.Offset((page - 1) * pageSize).Limit(pageSize)
 
-	if err := db.Find(&notes).Error; err != nil {
+	// Cursors select by key, so deep pages cost as much as the first one
+	var cursor *noteCursor
+	if query.Cursor != "" {
+		if cursor, err = decodeNoteCursor(query.Cursor); err != nil {
+			return nil, err
+		}
+		if cursor.Sort != sort.id() {
+			return nil, errors.New("invalid cursor")
+		}
+		value, err := sort.parse(cursor.Value)
+		if err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+		db = db.Where(sort.condition(cursor.Backward), value, cursor.ID)
+	} else {
+		page.Info.Page = max(query.Page, 1)
+		db = db.Offset((page.Info.Page - 1) * pageSize)
+	}
+	backward := cursor != nil && cursor.Backward
+
+	// Fetch one extra note to know whether there is another page
+	if err := preloadNoteLinks(db).Order(sort.order(backward)).Limit(pageSize + 1).Find(&notes).Error; err != nil {
 		return nil, err
 	}
+	more := len(notes) > pageSize
+	if more {
+		notes = notes[:pageSize]
+	}
+	if backward {
+		slices.Reverse(notes)
+	}
 
-	response := make([]NoteResponse, len(notes))
-	for i, note := range notes {
-		var metadata map[string]any
-		if len(note.Metadata) > 0 {
-			_ = json.Unmarshal(note.Metadata, &metadata)
+	if positions != nil && len(notes) > 0 {
+		if err := s.loadPositions(query.CollectionID, notes, positions); err != nil {
+			return nil, err
+		}
+	}
+	if len(notes) > 0 {
+		if more || backward {
+			page.Info.NextCursor = sort.cursor(&notes[len(notes)-1], false)
 		}
-		response[i] = NoteResponse{
-			ID:          note.ID.String(),
-			Content:     note.Content,
-			SourceURL:   note.SourceURL,
-			SourceTitle: note.SourceTitle,
-			Domain:      note.Domain,
-			Metadata:    metadata,
-			CreatedAt:   note.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			UpdatedAt:   note.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			Summary:     note.Summary,
+		if (more && backward) || (cursor != nil && !backward) || page.Info.Page > 1 {
+			page.Info.PrevCursor = sort.cursor(&notes[0], true)
 		}
 	}
 
-	return response, nil
+	page.Notes = make([]NoteResponse, len(notes))
+	for i := range notes {
+		page.Notes[i] = toNoteResponse(&notes[i])
+	}
+
+	return page, nil
 }
 
 func (s *NotesService) GetNoteByID(noteID, userID string) (*NoteResponse, error) {
 	var note models.Note
-	if err := s.db.Where("id = ? AND user_id = ?", noteID, userID).First(&note).Error; err != nil {
+	if err := preloadNoteLinks(s.db).Where("id = ? AND user_id = ?", noteID, userID).First(&note).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("note not found")
 		}
 		return nil, err
 	}
-	var metadata map[string]any
-	if len(note.Metadata) > 0 {
-		_ = json.Unmarshal(note.Metadata, &metadata)
-	}
-	response := &NoteResponse{
-		ID:          note.ID.String(),
-		Content:     note.Content,
-		SourceURL:   note.SourceURL,
-		SourceTitle: note.SourceTitle,
-		Domain:      note.Domain,
-		Metadata:    metadata,
-		CreatedAt:   note.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:   note.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		Summary:     note.Summary,
-	}
-	return response, nil
+	response := toNoteResponse(&note)
+	return &response, nil
 }
 
-func (s *NotesService) CreateNote(req *CreateNoteRequest, userID string) (*NoteResponse, error) {
+// CreateNote saves a new note. With ReturnExisting set, a near-identical
+// note the user already has is returned instead, and existing is true.
+func (s *NotesService) CreateNote(req *CreateNoteRequest, userID string) (*NoteResponse, bool, error) {
 	userUUID, err := uuid.Parse(userID)
 	if err != nil {
-		return nil, errors.New("invalid user ID")
+		return nil, false, errors.New("invalid user ID")
 	}
 	var metadata datatypes.JSON
 	if req.Metadata != nil {
@@ -157,71 +236,92 @@ func (s *NotesService) CreateNote(req *CreateNoteRequest, userID string) (*NoteR
 		Domain:      domain,
 		Metadata:    metadata,
 	}
-	if err := s.db.Create(&note).Error; err != nil {
-		return nil, err
-	}
-	var respMetadata map[string]any
-	if len(note.Metadata) > 0 {
-		_ = json.Unmarshal(note.Metadata, &respMetadata)
-	}
-	response := &NoteResponse{
-		ID:          note.ID.String(),
-		Content:     note.Content,
-		SourceURL:   note.SourceURL,
-		SourceTitle: note.SourceTitle,
-		Domain:      note.Domain,
-		Metadata:    respMetadata,
-		CreatedAt:   note.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:   note.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	setFingerprint(&note)
+	existing := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if req.ReturnExisting {
+			duplicateID, err := findDuplicate(tx, userUUID, &note, DefaultDuplicateThreshold)
+			if err != nil {
+				return err
+			}
+			if duplicateID != uuid.Nil {
+				existing = true
+				return preloadNoteLinks(tx).Where("id = ?", duplicateID).First(&note).Error
+			}
+		}
+		if err := tx.Create(&note).Error; err != nil {
+			return err
+		}
+		if err := saveFingerprintBands(tx, &note); err != nil {
+			return err
+		}
+		tags, err := resolveTags(tx, userUUID, req.Tags)
+		if err != nil {
+			return err
+		}
+		note.Tags = tags
+		if err := setNoteTags(tx, note.ID, tags); err != nil {
+			return err
+		}
+		return recordRevision(tx, &note, nil, userUUID, nil)
+	})
+	if err != nil {
+		return nil, false, err
 	}
-	return response, nil
+	response := toNoteResponse(&note)
+	return &response, existing, nil
 }
 
 func (s *NotesService) UpdateNote(noteID, userID string, req *UpdateNoteRequest) (*NoteResponse, error) {
+	authorID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
 	var note models.Note
-	if err := s.db.Where("id = ? AND user_id = ?", noteID, userID).First(&note).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("note not found")
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockNote(tx, noteID, userID); err != nil {
+			return err
 		}
+		if err := preloadNoteLinks(tx).Where("id = ?", noteID).First(&note).Error; err != nil {
+			return err
+		}
+		before := snapshotNote(&note)
+
+		// Update fields if provided
+		if req.Content != "" {
+			note.Content = req.Content
+		}
+		if req.SourceURL != "" {
+			note.SourceURL = req.SourceURL
+		}
+		if req.SourceTitle != "" {
+			note.SourceTitle = req.SourceTitle
+		}
+		if req.Domain != "" {
+			note.Domain = req.Domain
+		}
+		if req.Metadata != nil {
+			b, _ := json.Marshal(req.Metadata)
+			note.Metadata = datatypes.JSON(b)
+		}
+		if req.Tags != nil {
+			tags, err := resolveTags(tx, note.UserID, *req.Tags)
+			if err != nil {
+				return err
+			}
+			note.Tags = tags
+		}
+		return saveNote(tx, &note, req.Tags != nil, &before, authorID, nil)
+	})
+	if err != nil {
 		return nil, err
 	}
-	// Update fields if provided
-	if req.Content != "" {
-		note.Content = req.Content
-	}
-	if req.SourceURL != "" {
-		note.SourceURL = req.SourceURL
-	}
-	if req.SourceTitle != "" {
-		note.SourceTitle = req.SourceTitle
-	}
-	if req.Domain != "" {
-		note.Domain = req.Domain
-	}
-	if req.Metadata != nil {
-		b, _ := json.Marshal(req.Metadata)
-		note.Metadata = datatypes.JSON(b)
-	}
-	if err := s.db.Save(&note).Error; err != nil {
-		return nil, err
-	}
-	var respMetadata map[string]any
-	if len(note.Metadata) > 0 {
-		_ = json.Unmarshal(note.Metadata, &respMetadata)
-	}
-	response := &NoteResponse{
-		ID:          note.ID.String(),
-		Content:     note.Content,
-		SourceURL:   note.SourceURL,
-		SourceTitle: note.SourceTitle,
-		Domain:      note.Domain,
-		Metadata:    respMetadata,
-		CreatedAt:   note.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:   note.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	}
-	return response, nil
+	response := toNoteResponse(&note)
+	return &response, nil
 }
 
+// DeleteNote moves a note to the trash. It keeps its tags and collections
+// until it is purged, so restoring it brings them back.
 func (s *NotesService) DeleteNote(noteID, userID string) error {
 	result := s.db.Where("id = ? AND user_id = ?", noteID, userID).Delete(&models.Note{})
 	if result.Error != nil {
@@ -235,14 +335,27 @@ func (s *NotesService) DeleteNote(noteID, userID string) error {
 	return nil
 }
 
-func (s *NotesService) GetNotesStats(userID string) ([]NotesStats, error) {
-	var stats []NotesStats
+// GetNotesStats returns the user's note counts per domain and per tag
+func (s *NotesService) GetNotesStats(userID string) (*NotesStatsResponse, error) {
+	stats := &NotesStatsResponse{Domains: []NotesStats{}, Tags: []TagStats{}}
 	err := s.db.Model(&models.Note{}).
 		Select("domain, COUNT(*) as count").
 		Where("user_id = ?", userID).
 		Group("domain").
 		Order("count DESC").
-		Scan(&stats).Error
+		Scan(&stats.Domains).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Model(&models.Tag{}).
+		Select("tags.id, tags.name, tags.color, COUNT(note_tags.note_id) AS count").
+		Joins("JOIN note_tags ON note_tags.tag_id = tags.id").
+		Joins("JOIN notes ON notes.id = note_tags.note_id AND notes.deleted_at IS NULL").
+		Where("tags.user_id = ?", userID).
+		Group("tags.id").
+		Order("count DESC, LOWER(tags.name)").
+		Scan(&stats.Tags).Error
 	if err != nil {
 		return nil, err
 	}
@@ -264,8 +377,199 @@ func (s *NotesService) SummarizeNote(noteID, userID string, summarizer *Summariz
 		return "", err
 	}
 	note.Summary = summary
+	note.SummaryRequestedAt = nil
+	note.SummaryAttempts = 0
 	if err := s.db.Save(&note).Error; err != nil {
 		return "", err
 	}
 	return summary, nil
 }
+
+// filterNotes scopes a query to the user's notes matching the filters of
+// the query. Filtering by collection joins collection_notes.
+func (s *NotesService) filterNotes(db *gorm.DB, userID string, query *NoteQuery) (*gorm.DB, error) {
+	db, err := applyNoteFilters(db.Model(&models.Note{}).Where("notes.user_id = ?", userID), query)
+	if err != nil {
+		return nil, err
+	}
+	if len(query.Tags) > 0 {
+		filter, err := s.tagFilter(userID, query.Tags, query.TagMode)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("notes.id IN (?)", filter)
+	}
+	if query.CollectionID != "" {
+		if err := s.checkCollection(userID, query.CollectionID); err != nil {
+			return nil, err
+		}
+		db = db.Joins("JOIN collection_notes ON collection_notes.note_id = notes.id AND collection_notes.collection_id = ?", query.CollectionID)
+	}
+	return db, nil
+}
+
+// tagFilter returns a subquery of the IDs of the user's notes carrying all
+// (or, with TagModeAny, any) of the named tags
+func (s *NotesService) tagFilter(userID string, names []string, mode string) (*gorm.DB, error) {
+	keys := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		key := strings.ToLower(strings.Join(strings.Fields(name), " "))
+		if key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	filter := s.db.Table("note_tags").
+		Select("note_tags.note_id").
+		Joins("JOIN tags ON tags.id = note_tags.tag_id").
+		Where("tags.user_id = ? AND LOWER(tags.name) IN ?", userID, keys).
+		Group("note_tags.note_id")
+	switch mode {
+	case "", TagModeAll:
+		filter = filter.Having("COUNT(DISTINCT tags.id) = ?", len(keys))
+	case TagModeAny:
+	default:
+		return nil, errors.New("invalid tag mode")
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("invalid tag filter")
+	}
+	return filter, nil
+}
+
+// checkCollection checks that the user owns the collection
+func (s *NotesService) checkCollection(userID, collectionID string) error {
+	if _, err := uuid.Parse(collectionID); err != nil {
+		return errors.New("collection not found")
+	}
+	var count int64
+	if err := s.db.Model(&models.Collection{}).Where("id = ? AND user_id = ?", collectionID, userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("collection not found")
+	}
+	return nil
+}
+
+// loadPositions fills in the positions of notes in a collection
+func (s *NotesService) loadPositions(collectionID string, notes []models.Note, positions map[uuid.UUID]int) error {
+	ids := make([]uuid.UUID, len(notes))
+	for i := range notes {
+		ids[i] = notes[i].ID
+	}
+	var rows []models.CollectionNote
+	if err := s.db.Where("collection_id = ? AND note_id IN ?", collectionID, ids).Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		positions[row.NoteID] = row.Position
+	}
+	return nil
+}
+
+// noteLinkTables are the tables whose rows belong to a note. Their rows are
+// deleted when the note is purged.
+var noteLinkTables = []struct {
+	name  string
+	model any
+}{
+	{"note_tags", &models.NoteTag{}},
+	{"collection_notes", &models.CollectionNote{}},
+	{"note_revisions", &models.NoteRevision{}},
+	{"note_fingerprint_bands", &models.NoteFingerprintBand{}},
+}
+
+// purgeNotes permanently deletes the notes matching the conditions, trashed
+// or not, with their rows in the join tables. It returns how many notes were
+// deleted.
+func purgeNotes(tx *gorm.DB, query string, args ...any) (int64, error) {
+	ids := tx.Unscoped().Model(&models.Note{}).Select("id").Where(query, args...)
+	for _, table := range noteLinkTables {
+		if err := tx.Where("note_id IN (?)", ids).Delete(table.model).Error; err != nil {
+			return 0, err
+		}
+	}
+	result := tx.Unscoped().Where(query, args...).Delete(&models.Note{})
+	return result.RowsAffected, result.Error
+}
+
+// lockNote locks the user's note until the transaction ends, so concurrent
+// changes to it are applied and numbered one at a time
+func lockNote(tx *gorm.DB, noteID, userID string) error {
+	if _, err := uuid.Parse(noteID); err != nil {
+		return errors.New("note not found")
+	}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("id = ? AND user_id = ?", noteID, userID).
+		First(&models.Note{}).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("note not found")
+	}
+	return err
+}
+
+// saveNote writes a changed note and, when setTags is true, replaces its
+// tags with note.Tags. The change is recorded as a revision by authorID.
+func saveNote(tx *gorm.DB, note *models.Note, setTags bool, before *noteSnapshot, authorID uuid.UUID, revertedFrom *int) error {
+	fingerprint := note.FingerprintedAt == nil || note.Content != before.content
+	if fingerprint {
+		setFingerprint(note)
+	}
+	// Tags are written through the join table, not by Save
+	if err := tx.Omit(clause.Associations).Save(note).Error; err != nil {
+		return err
+	}
+	if fingerprint {
+		if err := saveFingerprintBands(tx, note); err != nil {
+			return err
+		}
+	}
+	if setTags {
+		if err := setNoteTags(tx, note.ID, note.Tags); err != nil {
+			return err
+		}
+	}
+	return recordRevision(tx, note, before, authorID, revertedFrom)
+}
+
+// preloadNoteLinks loads the tags and collections shown on notes
+func preloadNoteLinks(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tags", orderTags).Preload("Collections", orderCollections)
+}
+
+// orderTags sorts preloaded tags by name
+func orderTags(db *gorm.DB) *gorm.DB {
+	return db.Order("LOWER(tags.name)")
+}
+
+func toNoteResponse(note *models.Note) NoteResponse {
+	var metadata map[string]any
+	if len(note.Metadata) > 0 {
+		_ = json.Unmarshal(note.Metadata, &metadata)
+	}
+	tags := make([]NoteTagResponse, len(note.Tags))
+	for i, tag := range note.Tags {
+		tags[i] = NoteTagResponse{ID: tag.ID.String(), Name: tag.Name, Color: tag.Color}
+	}
+	collections := make([]NoteCollectionResponse, len(note.Collections))
+	for i, collection := range note.Collections {
+		collections[i] = NoteCollectionResponse{ID: collection.ID.String(), Name: collection.Name}
+	}
+	return NoteResponse{
+		ID:          note.ID.String(),
+		Content:     note.Content,
+		SourceURL:   note.SourceURL,
+		SourceTitle: note.SourceTitle,
+		Domain:      note.Domain,
+		Metadata:    metadata,
+		Tags:        tags,
+		Collections: collections,
+		CreatedAt:   note.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:   note.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Summary:     note.Summary,
+	}
+}
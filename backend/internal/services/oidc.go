@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/database"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"github.com/pratts/tts-study-assistant/backend/internal/oidc"
+	"gorm.io/gorm"
+)
+
+// OIDCService signs users in through the configured OpenID Connect provider
+type OIDCService struct {
+	db          *gorm.DB
+	cfg         *config.Config
+	authService *AuthService
+}
+
+const (
+	oidcStateLifetime = 10 * time.Minute
+	oidcTimeout       = 15 * time.Second
+)
+
+// The provider's discovery document is fetched on first use and shared, so
+// the server starts even while the provider is unreachable
+var (
+	oidcProviderMu sync.Mutex
+	oidcProvider   *oidc.Provider
+)
+
+type OIDCInfoResponse struct {
+	Enabled      bool   `json:"enabled"`
+	ProviderName string `json:"provider_name,omitempty"`
+}
+
+type OIDCAuthorizeRequest struct {
+	Source string `json:"source"`
+}
+
+type OIDCAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+type OIDCCallbackRequest struct {
+	Code       string `json:"code"`
+	State      string `json:"state"`
+	DeviceName string `json:"device_name,omitempty"`
+}
+
+func NewOIDCService(cfg *config.Config) *OIDCService {
+	return &OIDCService{
+		db:          database.DB,
+		cfg:         cfg,
+		authService: NewAuthService(cfg),
+	}
+}
+
+// GetInfo tells the web app whether to offer single sign-on
+func (s *OIDCService) GetInfo() *OIDCInfoResponse {
+	if s.cfg.OIDCIssuerURL == "" {
+		return &OIDCInfoResponse{Enabled: false}
+	}
+	return &OIDCInfoResponse{Enabled: true, ProviderName: s.cfg.OIDCProviderName}
+}
+
+// StartLogin creates the state for a login and returns the provider URL to
+// send the browser to. The caller must keep the state and check that the
+// callback returns the same value.
+func (s *OIDCService) StartLogin(req *OIDCAuthorizeRequest) (*OIDCAuthorizeResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oidcTimeout)
+	defer cancel()
+	provider, err := s.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := oidc.GenerateState()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oidc.GenerateState()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oidc.GenerateVerifier()
+	if err != nil {
+		return nil, err
+	}
+	source := req.Source
+	if source != "extension" {
+		source = "web"
+	}
+
+	err = s.db.Create(&models.OIDCLoginState{
+		StateHash:    hashSecret(state),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		Source:       source,
+		ExpiresAt:    time.Now().Add(oidcStateLifetime),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCAuthorizeResponse{
+		AuthorizationURL: provider.AuthCodeURL(state, nonce, oidc.S256Challenge(verifier)),
+		State:            state,
+	}, nil
+}
+
+// FinishLogin exchanges the authorization code, validates the ID token and
+// signs in the linked user. Like Login it returns a challenge instead of
+// tokens for users with 2FA enabled.
+func (s *OIDCService) FinishLogin(req *OIDCCallbackRequest, client ClientInfo) (*AuthResponse, *TwoFactorChallenge, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oidcTimeout)
+	defer cancel()
+	provider, err := s.provider(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Each state can be used once
+	var loginState models.OIDCLoginState
+	if err := s.db.Where("state_hash = ?", hashSecret(req.State)).First(&loginState).Error; err != nil {
+		return nil, nil, errors.New("invalid state")
+	}
+	result := s.db.Delete(&models.OIDCLoginState{}, "id = ?", loginState.ID)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	if result.RowsAffected == 0 || loginState.ExpiresAt.Before(time.Now()) {
+		return nil, nil, errors.New("invalid state")
+	}
+	client.Source = loginState.Source
+
+	token, err := provider.Exchange(ctx, req.Code, loginState.CodeVerifier)
+	if err != nil {
+		log.Printf("SSO login failed: %v", err)
+		return nil, nil, errors.New("sso login failed")
+	}
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, loginState.Nonce)
+	if err != nil {
+		log.Printf("SSO login failed: %v", err)
+		return nil, nil, errors.New("sso login failed")
+	}
+
+	user, err := s.linkUser(provider.Metadata().Issuer, claims, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user.DisabledAt != nil {
+		return nil, nil, errAccountDisabled
+	}
+	if user.TOTPEnabledAt != nil {
+		challenge, err := s.authService.issueTwoFactorChallenge(user, loginState.Source, req.DeviceName)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, challenge, nil
+	}
+	response, err := s.authService.startSession(user, loginState.Source, req.DeviceName, "sso", client)
+	return response, nil, err
+}
+
+// linkUser finds the user for an identity. A new identity is linked to the
+// account with the same email when both the provider and the account have
+// verified it, or to a new account when auto-provisioning is on.
+func (s *OIDCService) linkUser(issuer string, claims *oidc.IDTokenClaims, client ClientInfo) (*models.User, error) {
+	now := time.Now()
+
+	var identity models.UserIdentity
+	err := s.db.Preload("User").Where("issuer = ? AND subject = ?", issuer, claims.Subject).First(&identity).Error
+	if err == nil {
+		s.db.Model(&identity).Updates(map[string]any{"last_login_at": now, "email": claims.Email})
+		return &identity.User, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Linking by email is only safe when the provider vouches for it
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || !bool(claims.EmailVerified) {
+		return nil, errors.New("email not verified")
+	}
+
+	var user models.User
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+		switch {
+		case err == nil:
+			if err := checkIdentityLink(&user); err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if !s.cfg.OIDCAutoProvision {
+				return errors.New("account not found")
+			}
+			user, err = s.provisionUser(tx, email, claims.Name, now)
+			if err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:      user.ID,
+			Issuer:      issuer,
+			Subject:     claims.Subject,
+			Email:       email,
+			LastLoginAt: &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordAuditEvent(s.db, user.ID, AuditIdentityLinked, client, map[string]any{
+		"issuer":  issuer,
+		"subject": claims.Subject,
+	})
+	return &user, nil
+}
+
+// checkIdentityLink refuses to link a new identity to an account whose email
+// was never verified. Anyone could have registered that address with a
+// password of their own, and would share the account with the SSO user.
+func checkIdentityLink(user *models.User) error {
+	if user.EmailVerifiedAt == nil {
+		return errors.New("account email not verified")
+	}
+	return nil
+}
+
+// provisionUser creates an account for a first-time SSO user. The password
+// is random, so the account can only be used through SSO until the user
+// sets one with a password reset.
+func (s *OIDCService) provisionUser(tx *gorm.DB, email, name string, verifiedAt time.Time) (models.User, error) {
+	secret, err := generateSecret(32)
+	if err != nil {
+		return models.User{}, err
+	}
+	passwordHash, err := hashPassword(secret)
+	if err != nil {
+		return models.User{}, err
+	}
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	user := models.User{
+		Email:           email,
+		Password:        passwordHash,
+		Name:            name,
+		EmailVerifiedAt: &verifiedAt,
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+func (s *OIDCService) provider(ctx context.Context) (*oidc.Provider, error) {
+	if s.cfg.OIDCIssuerURL == "" {
+		return nil, errors.New("sso not configured")
+	}
+
+	oidcProviderMu.Lock()
+	defer oidcProviderMu.Unlock()
+	if oidcProvider != nil {
+		return oidcProvider, nil
+	}
+	provider, err := oidc.NewProvider(ctx, oidc.Config{
+		IssuerURL:    s.cfg.OIDCIssuerURL,
+		ClientID:     s.cfg.OIDCClientID,
+		ClientSecret: s.cfg.OIDCClientSecret,
+		RedirectURL:  s.cfg.OIDCRedirectURL,
+		Scopes:       s.cfg.OIDCScopes,
+	})
+	if err != nil {
+		log.Printf("SSO provider unavailable: %v", err)
+		return nil, errors.New("sso provider unavailable")
+	}
+	oidcProvider = provider
+	return provider, nil
+}
+
+// CleanupExpiredOIDCStates deletes abandoned SSO logins
+func (s *OIDCService) CleanupExpiredOIDCStates(ctx context.Context) error {
+	return s.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&models.OIDCLoginState{}).Error
+}
@@ -0,0 +1,20 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+)
+
+func TestCheckIdentityLink(t *testing.T) {
+	// Someone may have registered the address with their own password
+	if err := checkIdentityLink(&models.User{Email: "a@example.com"}); err == nil || err.Error() != "account email not verified" {
+		t.Errorf("checkIdentityLink() = %v for an unverified account, want account email not verified", err)
+	}
+
+	verifiedAt := time.Now()
+	if err := checkIdentityLink(&models.User{Email: "a@example.com", EmailVerifiedAt: &verifiedAt}); err != nil {
+		t.Errorf("checkIdentityLink() = %v for a verified account", err)
+	}
+}
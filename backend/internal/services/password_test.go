@@ -0,0 +1,64 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestHashAndVerifyPassword(t *testing.T) {
+	clientHash := "5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8"
+
+	hash, err := hashPassword(clientHash)
+	if err != nil {
+		t.Fatalf("hashPassword() error = %v", err)
+	}
+	if hash == clientHash {
+		t.Fatal("hashPassword() returned its input")
+	}
+
+	if ok, needsRehash := verifyPassword(hash, clientHash); !ok || needsRehash {
+		t.Errorf("verifyPassword() = %v, %v for the right password, want true, false", ok, needsRehash)
+	}
+	if ok, _ := verifyPassword(hash, "wrong"); ok {
+		t.Error("verifyPassword() accepted a wrong password")
+	}
+
+	// The stored hash must not work as a password
+	if ok, _ := verifyPassword(hash, hash); ok {
+		t.Error("verifyPassword() accepted the stored hash as the password")
+	}
+}
+
+func TestVerifyLegacyClientHash(t *testing.T) {
+	clientHash := "5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8"
+
+	if ok, needsRehash := verifyPassword(clientHash, clientHash); !ok || !needsRehash {
+		t.Errorf("verifyPassword() = %v, %v for a legacy row, want true, true", ok, needsRehash)
+	}
+	if ok, needsRehash := verifyPassword(clientHash, "other"); ok || needsRehash {
+		t.Errorf("verifyPassword() = %v, %v for a wrong password, want false, false", ok, needsRehash)
+	}
+	if ok, _ := verifyPassword("", ""); ok {
+		t.Error("verifyPassword() accepted an empty password for an empty row")
+	}
+}
+
+func TestVerifyPasswordFlagsOutdatedParams(t *testing.T) {
+	salt := []byte("saltsaltsaltsalt")
+	key := argon2.IDKey([]byte("password"), salt, 1, argon2Memory, argon2Threads, argon2KeyLen)
+	stored := fmt.Sprintf("$argon2id$v=%d$m=%d,t=1,p=%d$%s$%s",
+		argon2.Version, argon2Memory, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	if ok, needsRehash := verifyPassword(stored, "password"); !ok || !needsRehash {
+		t.Errorf("verifyPassword() = %v, %v with outdated parameters, want true, true", ok, needsRehash)
+	}
+	if ok, _ := verifyPassword("$argon2id$v=19$m=65536,t=1,p=2$c2FsdA$", "password"); ok {
+		t.Error("verifyPassword() accepted a hash without a key")
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/database"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// PersonalAccessTokenService manages long-lived, scoped API tokens
+type PersonalAccessTokenService struct {
+	db *gorm.DB
+}
+
+const (
+	// PersonalAccessTokenPrefix marks personal access tokens so they are easy
+	// to tell apart from JWTs and to find with secret scanners
+	PersonalAccessTokenPrefix = "tsa_pat_"
+
+	patDefaultExpiryDays = 30
+	patMaxExpiryDays     = 365
+	patLastUsedInterval  = time.Minute // how often last_used_at is written
+)
+
+type CreatePersonalAccessTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"` // Default 30, at most 365
+}
+
+type PersonalAccessTokenResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  string   `json:"expires_at"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+	Token      string   `json:"token,omitempty"` // Only set when the token is created
+}
+
+// AuthenticatedToken is the result of checking a personal access token
+type AuthenticatedToken struct {
+	TokenID string
+	UserID  string
+	Email   string
+	Role    string
+	Scopes  []string
+}
+
+func NewPersonalAccessTokenService() *PersonalAccessTokenService {
+	return &PersonalAccessTokenService{
+		db: database.DB,
+	}
+}
+
+// ListTokens returns the user's personal access tokens, newest first
+func (s *PersonalAccessTokenService) ListTokens(userID string) ([]PersonalAccessTokenResponse, error) {
+	var tokens []models.PersonalAccessToken
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	response := make([]PersonalAccessTokenResponse, len(tokens))
+	for i := range tokens {
+		response[i] = toPersonalAccessTokenResponse(&tokens[i])
+	}
+	return response, nil
+}
+
+// CreateToken issues a new token. The raw token is only returned here.
+func (s *PersonalAccessTokenService) CreateToken(userID string, req *CreatePersonalAccessTokenRequest) (*PersonalAccessTokenResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if len(req.Scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(models.Scopes, scope) {
+			return nil, errors.New("invalid scope")
+		}
+	}
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = patDefaultExpiryDays
+	}
+	if days < 1 || days > patMaxExpiryDays {
+		return nil, errors.New("invalid expiry")
+	}
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	secret, err := generateSecret(32)
+	if err != nil {
+		return nil, err
+	}
+	raw := PersonalAccessTokenPrefix + secret
+
+	scopes := slices.Clone(req.Scopes)
+	slices.Sort(scopes)
+	token := models.PersonalAccessToken{
+		UserID:    userUUID,
+		Name:      name,
+		TokenHash: hashSecret(raw),
+		Prefix:    raw[:len(PersonalAccessTokenPrefix)+4],
+		Scopes:    strings.Join(slices.Compact(scopes), " "),
+		ExpiresAt: time.Now().AddDate(0, 0, days),
+	}
+	if err := s.db.Create(&token).Error; err != nil {
+		return nil, err
+	}
+
+	response := toPersonalAccessTokenResponse(&token)
+	response.Token = raw
+	return &response, nil
+}
+
+// RevokeToken deletes one of the user's tokens
+func (s *PersonalAccessTokenService) RevokeToken(userID, tokenID string) error {
+	if _, err := uuid.Parse(tokenID); err != nil {
+		return errors.New("token not found")
+	}
+	result := s.db.Where("id = ? AND user_id = ?", tokenID, userID).Delete(&models.PersonalAccessToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("token not found")
+	}
+	return nil
+}
+
+// Authenticate looks up a raw token and returns who it belongs to and what
+// it may do
+func (s *PersonalAccessTokenService) Authenticate(raw string) (*AuthenticatedToken, error) {
+	var token models.PersonalAccessToken
+	err := s.db.Preload("User").
+		Where("token_hash = ? AND expires_at > ?", hashSecret(raw), time.Now()).
+		First(&token).Error
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+	// Tokens stay stored while the account is disabled or awaits deletion,
+	// so they work again once it is restored
+	if token.User.DeletionScheduledAt != nil || token.User.DisabledAt != nil {
+		return nil, errors.New("invalid token")
+	}
+
+	// Recording every request would mean a write per API call
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > patLastUsedInterval {
+		s.db.Model(&token).UpdateColumn("last_used_at", now)
+	}
+
+	return &AuthenticatedToken{
+		TokenID: token.ID.String(),
+		UserID:  token.UserID.String(),
+		Email:   token.User.Email,
+		Role:    token.User.Role,
+		Scopes:  strings.Fields(token.Scopes),
+	}, nil
+}
+
+// CleanupExpiredPersonalAccessTokens deletes tokens past their expiry
+func (s *PersonalAccessTokenService) CleanupExpiredPersonalAccessTokens(ctx context.Context) error {
+	return s.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&models.PersonalAccessToken{}).Error
+}
+
+func toPersonalAccessTokenResponse(token *models.PersonalAccessToken) PersonalAccessTokenResponse {
+	response := PersonalAccessTokenResponse{
+		ID:        token.ID.String(),
+		Name:      token.Name,
+		Prefix:    token.Prefix,
+		Scopes:    strings.Fields(token.Scopes),
+		ExpiresAt: token.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+		CreatedAt: token.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if token.LastUsedAt != nil {
+		response.LastUsedAt = token.LastUsedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return response
+}
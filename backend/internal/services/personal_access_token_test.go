@@ -0,0 +1,177 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+)
+
+func TestCreatePersonalAccessToken(t *testing.T) {
+	userID := uuid.NewString()
+	valid := func() CreatePersonalAccessTokenRequest {
+		return CreatePersonalAccessTokenRequest{Name: "script", Scopes: []string{models.ScopeNotesRead}}
+	}
+	tests := []struct {
+		name   string
+		modify func(*CreatePersonalAccessTokenRequest)
+		want   string
+	}{
+		{"no name", func(r *CreatePersonalAccessTokenRequest) { r.Name = "  " }, "name is required"},
+		{"no scopes", func(r *CreatePersonalAccessTokenRequest) { r.Scopes = nil }, "at least one scope is required"},
+		{"unknown scope", func(r *CreatePersonalAccessTokenRequest) { r.Scopes = []string{"admin"} }, "invalid scope"},
+		{"negative expiry", func(r *CreatePersonalAccessTokenRequest) { r.ExpiresInDays = -1 }, "invalid expiry"},
+		{"expiry too long", func(r *CreatePersonalAccessTokenRequest) { r.ExpiresInDays = patMaxExpiryDays + 1 }, "invalid expiry"},
+	}
+	for _, tt := range tests {
+		db := newFakeDB(t)
+		s := &PersonalAccessTokenService{db: db.DB}
+		req := valid()
+		tt.modify(&req)
+		if _, err := s.CreateToken(userID, &req); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: CreateToken() error = %v, want %s", tt.name, err, tt.want)
+		}
+		if len(db.writes) != 0 {
+			t.Errorf("%s: CreateToken() stored a token: %q", tt.name, db.writes)
+		}
+	}
+
+	db := newFakeDB(t)
+	s := &PersonalAccessTokenService{db: db.DB}
+	response, err := s.CreateToken(userID, &CreatePersonalAccessTokenRequest{
+		Name:   " script ",
+		Scopes: []string{models.ScopeNotesWrite, models.ScopeNotesRead, models.ScopeNotesWrite},
+	})
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	if !strings.HasPrefix(response.Token, PersonalAccessTokenPrefix) || response.Prefix != response.Token[:len(PersonalAccessTokenPrefix)+4] {
+		t.Errorf("CreateToken() token = %q with prefix %q", response.Token, response.Prefix)
+	}
+	if response.Name != "script" || strings.Join(response.Scopes, " ") != "notes:read notes:write" {
+		t.Errorf("CreateToken() = %+v, want the trimmed name and sorted unique scopes", response)
+	}
+	expiresAt, err := time.Parse(time.RFC3339, response.ExpiresAt)
+	if err != nil || expiresAt.Before(time.Now().AddDate(0, 0, patDefaultExpiryDays).Add(-time.Minute)) {
+		t.Errorf("CreateToken() expires at %s, want in %d days", response.ExpiresAt, patDefaultExpiryDays)
+	}
+	if !db.wrote(`INSERT INTO "personal_access_tokens"`, hashSecret(response.Token)) || db.wrote(response.Token) {
+		t.Errorf("CreateToken() did not store only the token hash: %q", db.writes)
+	}
+}
+
+func TestListPersonalAccessTokens(t *testing.T) {
+	userID := uuid.New()
+	lastUsedAt := time.Now().Add(-time.Hour)
+	db := newFakeDB(t, []models.PersonalAccessToken{
+		{ID: uuid.New(), UserID: userID, Name: "used", Scopes: "notes:read notes:write", LastUsedAt: &lastUsedAt},
+		{ID: uuid.New(), UserID: userID, Name: "unused", Scopes: "notes:read"},
+	})
+	s := &PersonalAccessTokenService{db: db.DB}
+
+	tokens, err := s.ListTokens(userID.String())
+	if err != nil {
+		t.Fatalf("ListTokens() error = %v", err)
+	}
+	if !db.queried("user_id = '" + userID.String() + "'") {
+		t.Errorf("ListTokens() did not filter by user: %q", db.queries)
+	}
+	if len(tokens) != 2 || tokens[0].Name != "used" || len(tokens[0].Scopes) != 2 {
+		t.Fatalf("ListTokens() = %+v", tokens)
+	}
+	if tokens[0].LastUsedAt == "" || tokens[1].LastUsedAt != "" {
+		t.Errorf("ListTokens() last used = %q, %q", tokens[0].LastUsedAt, tokens[1].LastUsedAt)
+	}
+	for _, token := range tokens {
+		if token.Token != "" {
+			t.Error("ListTokens() returned a raw token")
+		}
+	}
+}
+
+func TestRevokePersonalAccessToken(t *testing.T) {
+	userID, tokenID := uuid.NewString(), uuid.NewString()
+
+	db := newFakeDB(t)
+	s := &PersonalAccessTokenService{db: db.DB}
+	if err := s.RevokeToken(userID, "not-a-uuid"); err == nil || err.Error() != "token not found" {
+		t.Errorf("RevokeToken() error = %v for an invalid ID, want token not found", err)
+	}
+	if err := s.RevokeToken(userID, tokenID); err != nil {
+		t.Fatalf("RevokeToken() error = %v", err)
+	}
+	if !db.wrote(`DELETE FROM "personal_access_tokens"`, "id = '"+tokenID+"' AND user_id = '"+userID+"'") {
+		t.Errorf("RevokeToken() did not delete the user's token: %q", db.writes)
+	}
+
+	// Someone else's token, or one already revoked
+	db.affected = 0
+	if err := s.RevokeToken(userID, tokenID); err == nil || err.Error() != "token not found" {
+		t.Errorf("RevokeToken() error = %v, want token not found", err)
+	}
+}
+
+func TestAuthenticatePersonalAccessToken(t *testing.T) {
+	raw := PersonalAccessTokenPrefix + "secret"
+	now := time.Now()
+	recently, earlier := now.Add(-time.Second), now.Add(-time.Hour)
+	user := models.User{ID: uuid.New(), Email: "a@example.com", Role: models.RoleSupport}
+	token := models.PersonalAccessToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Scopes:    "notes:read summaries:write",
+		ExpiresAt: now.Add(time.Hour),
+		User:      user,
+	}
+
+	db := newFakeDB(t, token)
+	s := &PersonalAccessTokenService{db: db.DB}
+	got, err := s.Authenticate(raw)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if got.TokenID != token.ID.String() || got.UserID != user.ID.String() || got.Email != user.Email ||
+		got.Role != models.RoleSupport || strings.Join(got.Scopes, " ") != token.Scopes {
+		t.Errorf("Authenticate() = %+v", got)
+	}
+	// Expired tokens are not found
+	if !db.queried("token_hash = '"+hashSecret(raw)+"'", "expires_at > ") {
+		t.Errorf("Authenticate() did not look up unexpired tokens by hash: %q", db.queries)
+	}
+	if !db.wrote(`UPDATE "personal_access_tokens" SET "last_used_at"=`) {
+		t.Errorf("Authenticate() did not record the first use: %q", db.writes)
+	}
+
+	if _, err := (&PersonalAccessTokenService{db: newFakeDB(t).DB}).Authenticate(raw); err == nil {
+		t.Error("Authenticate() accepted an unknown or expired token")
+	}
+
+	tests := []struct {
+		name  string
+		user  func(*models.User)
+		used  *time.Time
+		valid bool
+		write bool
+	}{
+		{"used recently", func(*models.User) {}, &recently, true, false},
+		{"used a while ago", func(*models.User) {}, &earlier, true, true},
+		{"disabled user", func(u *models.User) { u.DisabledAt = &earlier }, nil, false, false},
+		{"user awaiting deletion", func(u *models.User) { u.DeletionScheduledAt = &earlier }, nil, false, false},
+	}
+	for _, tt := range tests {
+		token := token
+		tt.user(&token.User)
+		token.LastUsedAt = tt.used
+		db := newFakeDB(t, token)
+		s := &PersonalAccessTokenService{db: db.DB}
+
+		if _, err := s.Authenticate(raw); (err == nil) != tt.valid {
+			t.Errorf("%s: Authenticate() error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+		if db.wrote(`"last_used_at"`) != tt.write {
+			t.Errorf("%s: Authenticate() writes = %q, want last use recorded %v", tt.name, db.writes, tt.write)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash/crc32"
+	"math/big"
+	"strings"
+)
+
+const (
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	// 40 base62 characters carry about 238 bits of entropy
+	checksummedTokenLength = 40
+	tokenChecksumLength    = 6
+)
+
+// generateSecret returns n random bytes encoded as unpadded base64url
+func generateSecret(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashSecret returns the hex SHA-256 digest used to store and look up
+// high-entropy secrets such as device codes
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateChecksummedToken returns prefix followed by random base62
+// characters and a CRC32 checksum of them. The prefix lets secret scanners
+// recognise the token and the checksum lets them, and the server, reject
+// look-alikes without a database lookup.
+func generateChecksummedToken(prefix string) (string, error) {
+	var b strings.Builder
+	max := big.NewInt(int64(len(base62Alphabet)))
+	for i := 0; i < checksummedTokenLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(base62Alphabet[n.Int64()])
+	}
+	body := b.String()
+	return prefix + body + tokenChecksum(body), nil
+}
+
+// validTokenChecksum reports whether token has the prefix, the expected
+// length and a matching checksum
+func validTokenChecksum(prefix, token string) bool {
+	rest, ok := strings.CutPrefix(token, prefix)
+	if !ok || len(rest) != checksummedTokenLength+tokenChecksumLength {
+		return false
+	}
+	body, checksum := rest[:checksummedTokenLength], rest[checksummedTokenLength:]
+	return tokenChecksum(body) == checksum
+}
+
+// tokenChecksum encodes the CRC32 of body as fixed-width base62
+func tokenChecksum(body string) string {
+	n := crc32.ChecksumIEEE([]byte(body))
+	out := make([]byte, tokenChecksumLength)
+	for i := tokenChecksumLength - 1; i >= 0; i-- {
+		out[i] = base62Alphabet[n%62]
+		n /= 62
+	}
+	return string(out)
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChecksummedToken(t *testing.T) {
+	token, err := generateChecksummedToken(RefreshTokenPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, RefreshTokenPrefix) {
+		t.Fatalf("token %q is missing the prefix", token)
+	}
+	if len(token) != len(RefreshTokenPrefix)+checksummedTokenLength+tokenChecksumLength {
+		t.Fatalf("token %q has length %d", token, len(token))
+	}
+	if !validTokenChecksum(RefreshTokenPrefix, token) {
+		t.Fatalf("fresh token %q failed its checksum", token)
+	}
+
+	other, _ := generateChecksummedToken(RefreshTokenPrefix)
+	if other == token {
+		t.Fatal("two tokens were equal")
+	}
+}
+
+func TestChecksummedTokenRejectsTampering(t *testing.T) {
+	token, err := generateChecksummedToken(RefreshTokenPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Change one character of the random part
+	i := len(RefreshTokenPrefix) + 5
+	replacement := byte('a')
+	if token[i] == replacement {
+		replacement = 'b'
+	}
+	tampered := token[:i] + string(replacement) + token[i+1:]
+
+	for _, bad := range []string{
+		tampered,
+		token[:len(token)-1],
+		"tsa_xx_" + strings.TrimPrefix(token, RefreshTokenPrefix),
+		"",
+		"3f1c6a0e-8d0b-4c57-9a53-52d9f1c2a7b4",
+	} {
+		if validTokenChecksum(RefreshTokenPrefix, bad) {
+			t.Errorf("validTokenChecksum(%q) = true", bad)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/database"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const maxSessionLabelLength = 100
+
+// A session is a refresh token family. Its ID is the family ID, which stays
+// the same across rotations and is carried in the sid claim of access tokens.
+type SessionService struct {
+	db *gorm.DB
+}
+
+type SessionResponse struct {
+	ID         string `json:"id"`
+	Source     string `json:"source"`
+	DeviceInfo string `json:"device_info,omitempty"`
+	Label      string `json:"label,omitempty"`
+	IPAddress  string `json:"ip_address,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at"`
+	ExpiresAt  string `json:"expires_at"`
+	Current    bool   `json:"current"`
+}
+
+type UpdateSessionRequest struct {
+	Label string `json:"label"`
+}
+
+func NewSessionService() *SessionService {
+	return &SessionService{
+		db: database.DB,
+	}
+}
+
+// ListSessions returns the user's active sessions, most recently used first
+func (s *SessionService) ListSessions(userID, currentSessionID string) ([]SessionResponse, error) {
+	var tokens []models.RefreshToken
+	err := s.db.Where("user_id = ? AND rotated_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// The active token is the newest in its family; the oldest one tells
+	// when the session started.
+	var starts []struct {
+		FamilyID  uuid.UUID
+		StartedAt time.Time
+	}
+	err = s.db.Model(&models.RefreshToken{}).
+		Select("family_id, MIN(created_at) as started_at").
+		Where("user_id = ?", userID).
+		Group("family_id").
+		Scan(&starts).Error
+	if err != nil {
+		return nil, err
+	}
+	startedAt := make(map[uuid.UUID]time.Time, len(starts))
+	for _, start := range starts {
+		startedAt[start.FamilyID] = start.StartedAt
+	}
+
+	response := make([]SessionResponse, len(tokens))
+	for i, token := range tokens {
+		sessionID := token.FamilyID
+		createdAt, ok := startedAt[sessionID]
+		if !ok {
+			createdAt = token.CreatedAt
+		}
+		// Tokens issued before last_used_at was tracked were last used when
+		// they were issued
+		lastUsedAt := token.CreatedAt
+		if token.LastUsedAt != nil {
+			lastUsedAt = *token.LastUsedAt
+		}
+		response[i] = SessionResponse{
+			ID:         sessionID.String(),
+			Source:     token.Source,
+			DeviceInfo: token.DeviceInfo,
+			Label:      token.Label,
+			IPAddress:  token.IPAddress,
+			UserAgent:  token.UserAgent,
+			CreatedAt:  createdAt.Format("2006-01-02T15:04:05Z07:00"),
+			LastUsedAt: lastUsedAt.Format("2006-01-02T15:04:05Z07:00"),
+			ExpiresAt:  token.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+			Current:    sessionID.String() == currentSessionID,
+		}
+	}
+
+	return response, nil
+}
+
+// UpdateSession sets a user-chosen label on a session
+func (s *SessionService) UpdateSession(userID, sessionID string, req *UpdateSessionRequest) error {
+	label := strings.TrimSpace(req.Label)
+	if utf8.RuneCountInString(label) > maxSessionLabelLength {
+		return errors.New("label too long")
+	}
+	result := s.sessionScope(userID, sessionID).
+		Update("label", label)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+// RevokeSession deletes every refresh token of a session. Its access tokens
+// stop working at once, as AuthMiddleware requires the session to exist.
+func (s *SessionService) RevokeSession(userID, sessionID string) error {
+	result := s.sessionScope(userID, sessionID).Delete(&models.RefreshToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+// RevokeSessions deletes all of the user's sessions except exceptSessionID,
+// when set. It returns the number of sessions revoked.
+func (s *SessionService) RevokeSessions(userID, exceptSessionID string) (int64, error) {
+	var count int64
+	db := s.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND rotated_at IS NULL AND expires_at > ?", userID, time.Now())
+	if exceptSessionID != "" {
+		db = db.Where("family_id <> ?", exceptSessionID)
+	}
+	if err := db.Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	db = s.db.Where("user_id = ?", userID)
+	if exceptSessionID != "" {
+		db = db.Where("family_id <> ?", exceptSessionID)
+	}
+	if err := db.Delete(&models.RefreshToken{}).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *SessionService) sessionScope(userID, sessionID string) *gorm.DB {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		sessionID = uuid.Nil.String()
+	}
+	return s.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND family_id = ?", userID, sessionID)
+}
+
+// accessTokenState is what an access token is checked against on each request
+type accessTokenState struct {
+	TokenVersion int
+	SessionLive  bool
+}
+
+// AccessTokenRevoked reports whether an access token was revoked: issued
+// before the user's token version was bumped (for example by a password
+// change), or for a session that was revoked or logged out since
+func AccessTokenRevoked(userID, sessionID string, version int) bool {
+	return accessTokenRevoked(database.DB, userID, sessionID, version)
+}
+
+func accessTokenRevoked(db *gorm.DB, userID, sessionID string, version int) bool {
+	query := db.Model(&models.User{}).Where("id = ?", userID)
+	if sessionID != "" {
+		query = query.Select("token_version, EXISTS (SELECT 1 FROM refresh_tokens "+
+			"WHERE refresh_tokens.family_id = ? AND refresh_tokens.user_id = users.id "+
+			"AND refresh_tokens.expires_at > ?) AS session_live", sessionID, time.Now())
+	} else {
+		query = query.Select("token_version, TRUE AS session_live")
+	}
+	var state accessTokenState
+	if err := query.Take(&state).Error; err != nil {
+		return true
+	}
+	return state.TokenVersion != version || !state.SessionLive
+}
+
+// revokeUserSessions bumps the user's token version, which invalidates every
+// live access token, and deletes all refresh tokens except those of
+// keepSessionID when it is non-empty.
+func revokeUserSessions(tx *gorm.DB, userID, keepSessionID string) error {
+	err := tx.Model(&models.User{}).
+		Where("id = ?", userID).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error
+	if err != nil {
+		return err
+	}
+	db := tx.Where("user_id = ?", userID)
+	if keepSessionID != "" {
+		db = db.Where("family_id <> ?", keepSessionID)
+	}
+	return db.Delete(&models.RefreshToken{}).Error
+}
+
+// describeUserAgent turns a User-Agent header into a short label such as
+// "Chrome on macOS". It only needs to be good enough to tell devices apart.
+func describeUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return ""
+	}
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	case strings.HasPrefix(userAgent, "curl/"):
+		browser = "curl"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(userAgent, "Android"):
+		os = "Android"
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		os = "iOS"
+	case strings.Contains(userAgent, "Windows"):
+		os = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"), strings.Contains(userAgent, "Macintosh"):
+		os = "macOS"
+	case strings.Contains(userAgent, "CrOS"):
+		os = "ChromeOS"
+	case strings.Contains(userAgent, "Linux"):
+		os = "Linux"
+	}
+
+	if os == "" {
+		return browser
+	}
+	return browser + " on " + os
+}
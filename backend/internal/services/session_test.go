@@ -0,0 +1,46 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+)
+
+func TestUpdateSessionLabelLength(t *testing.T) {
+	userID, sessionID := uuid.NewString(), uuid.NewString()
+
+	db := newFakeDB(t)
+	s := &SessionService{db: db.DB}
+	long := strings.Repeat("é", maxSessionLabelLength+1)
+	if err := s.UpdateSession(userID, sessionID, &UpdateSessionRequest{Label: long}); err == nil || err.Error() != "label too long" {
+		t.Errorf("UpdateSession() error = %v, want label too long", err)
+	}
+	if len(db.writes) != 0 {
+		t.Errorf("UpdateSession() wrote a label that is too long: %q", db.writes)
+	}
+
+	label := strings.Repeat("é", maxSessionLabelLength)
+	if err := s.UpdateSession(userID, sessionID, &UpdateSessionRequest{Label: "  " + label + "  "}); err != nil {
+		t.Fatalf("UpdateSession() error = %v", err)
+	}
+	if !db.wrote(`UPDATE "refresh_tokens" SET "label"='`+label+`'`, sessionID) {
+		t.Errorf("UpdateSession() did not set the trimmed label: %q", db.writes)
+	}
+}
+
+func TestReissueAccessTokenTracksLastUse(t *testing.T) {
+	useTestKeys(t, "secret")
+	user := models.User{ID: uuid.New(), Email: "a@example.com"}
+	session := models.RefreshToken{ID: uuid.New(), UserID: user.ID, FamilyID: uuid.New(), Source: "web"}
+	db := newFakeDB(t, user, session)
+	s := &AuthService{db: db.DB}
+
+	if _, err := s.ReissueAccessToken(user.ID.String(), session.FamilyID.String()); err != nil {
+		t.Fatalf("ReissueAccessToken() error = %v", err)
+	}
+	if !db.wrote(`UPDATE "refresh_tokens" SET "last_used_at"=`, session.ID.String()) {
+		t.Errorf("ReissueAccessToken() did not record the session as used: %q", db.writes)
+	}
+}
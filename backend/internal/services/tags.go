@@ -0,0 +1,377 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/database"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxTagNameLength = 50
+	maxTagsPerNote   = 20
+)
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type TagService struct {
+	db *gorm.DB
+}
+
+type CreateTagRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+// UpdateTagRequest renames or recolors a tag. Nil fields are left as they
+// are; an empty color resets it to the default.
+type UpdateTagRequest struct {
+	Name  *string `json:"name,omitempty"`
+	Color *string `json:"color,omitempty"`
+}
+
+// MergeTagsRequest moves the notes of the source tags onto the target tag
+// and deletes the sources
+type MergeTagsRequest struct {
+	SourceIDs []string `json:"source_ids"`
+	TargetID  string   `json:"target_id"`
+}
+
+type TagResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Color     string `json:"color,omitempty"`
+	NoteCount int64  `json:"note_count"`
+	CreatedAt string `json:"created_at"`
+}
+
+// NoteTagResponse is a tag as shown on a note
+type NoteTagResponse struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+type tagWithCount struct {
+	models.Tag
+	NoteCount int64
+}
+
+func NewTagService() *TagService {
+	return &TagService{
+		db: database.DB,
+	}
+}
+
+// ListTags returns the user's tags with the number of notes on each, by name
+func (s *TagService) ListTags(userID string) ([]TagResponse, error) {
+	var tags []tagWithCount
+	err := s.db.Model(&models.Tag{}).
+		Select("tags.*, COUNT(notes.id) AS note_count").
+		Joins("LEFT JOIN note_tags ON note_tags.tag_id = tags.id").
+		Joins("LEFT JOIN notes ON notes.id = note_tags.note_id AND notes.deleted_at IS NULL").
+		Where("tags.user_id = ?", userID).
+		Group("tags.id").
+		Order("LOWER(tags.name)").
+		Scan(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]TagResponse, len(tags))
+	for i := range tags {
+		response[i] = toTagResponse(&tags[i].Tag, tags[i].NoteCount)
+	}
+	return response, nil
+}
+
+// CreateTag creates a tag. Names are unique per user, ignoring case.
+func (s *TagService) CreateTag(userID string, req *CreateTagRequest) (*TagResponse, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+	name, err := normalizeTagName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	color, err := normalizeTagColor(req.Color)
+	if err != nil {
+		return nil, err
+	}
+	if taken, err := s.nameTaken(userID, name, ""); err != nil {
+		return nil, err
+	} else if taken {
+		return nil, errors.New("tag already exists")
+	}
+
+	tag := models.Tag{UserID: userUUID, Name: name, Color: color}
+	if err := s.db.Create(&tag).Error; err != nil {
+		return nil, err
+	}
+	response := toTagResponse(&tag, 0)
+	return &response, nil
+}
+
+// UpdateTag renames or recolors a tag. Renaming onto the name of another
+// tag fails; merge the tags instead.
+func (s *TagService) UpdateTag(userID, tagID string, req *UpdateTagRequest) (*TagResponse, error) {
+	tag, err := s.getTag(s.db, userID, tagID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name, err := normalizeTagName(*req.Name)
+		if err != nil {
+			return nil, err
+		}
+		if taken, err := s.nameTaken(userID, name, tagID); err != nil {
+			return nil, err
+		} else if taken {
+			return nil, errors.New("tag already exists")
+		}
+		tag.Name = name
+	}
+	if req.Color != nil {
+		color, err := normalizeTagColor(*req.Color)
+		if err != nil {
+			return nil, err
+		}
+		tag.Color = color
+	}
+	if err := s.db.Model(tag).Select("name", "color").Updates(tag).Error; err != nil {
+		return nil, err
+	}
+
+	noteCount, err := s.countNotes(tag.ID)
+	if err != nil {
+		return nil, err
+	}
+	response := toTagResponse(tag, noteCount)
+	return &response, nil
+}
+
+// DeleteTag deletes a tag and removes it from every note
+func (s *TagService) DeleteTag(userID, tagID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		tag, err := s.getTag(tx, userID, tagID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("tag_id = ?", tag.ID).Delete(&models.NoteTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	})
+}
+
+// MergeTags puts the target tag on every note that has one of the source
+// tags, then deletes the sources
+func (s *TagService) MergeTags(userID string, req *MergeTagsRequest) (*TagResponse, error) {
+	var sourceIDs []uuid.UUID
+	for _, id := range req.SourceIDs {
+		if id == req.TargetID {
+			continue
+		}
+		sourceID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, errors.New("tag not found")
+		}
+		sourceIDs = append(sourceIDs, sourceID)
+	}
+	if len(sourceIDs) == 0 {
+		return nil, errors.New("nothing to merge")
+	}
+
+	var target *models.Tag
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if target, err = s.getTag(tx, userID, req.TargetID); err != nil {
+			return err
+		}
+		var owned int64
+		err = tx.Model(&models.Tag{}).Where("id IN ? AND user_id = ?", sourceIDs, userID).Count(&owned).Error
+		if err != nil {
+			return err
+		}
+		if owned != int64(len(sourceIDs)) {
+			return errors.New("tag not found")
+		}
+
+		err = tx.Exec(`INSERT INTO note_tags (note_id, tag_id, created_at)
+			SELECT DISTINCT note_id, ?, NOW() FROM note_tags WHERE tag_id IN ?
+			ON CONFLICT DO NOTHING`, target.ID, sourceIDs).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("tag_id IN ?", sourceIDs).Delete(&models.NoteTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", sourceIDs).Delete(&models.Tag{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	noteCount, err := s.countNotes(target.ID)
+	if err != nil {
+		return nil, err
+	}
+	response := toTagResponse(target, noteCount)
+	return &response, nil
+}
+
+func (s *TagService) getTag(db *gorm.DB, userID, tagID string) (*models.Tag, error) {
+	if _, err := uuid.Parse(tagID); err != nil {
+		return nil, errors.New("tag not found")
+	}
+	var tag models.Tag
+	if err := db.Where("id = ? AND user_id = ?", tagID, userID).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("tag not found")
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// countNotes returns the number of notes with the tag, leaving out the trash
+func (s *TagService) countNotes(tagID uuid.UUID) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.Note{}).
+		Joins("JOIN note_tags ON note_tags.note_id = notes.id").
+		Where("note_tags.tag_id = ?", tagID).
+		Count(&count).Error
+	return count, err
+}
+
+// nameTaken reports whether another of the user's tags has the name
+func (s *TagService) nameTaken(userID, name, exceptID string) (bool, error) {
+	db := s.db.Model(&models.Tag{}).Where("user_id = ? AND LOWER(name) = LOWER(?)", userID, name)
+	if exceptID != "" {
+		db = db.Where("id <> ?", exceptID)
+	}
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// resolveTags returns the user's tags with the given names, creating the
+// ones that do not exist yet. Names are matched ignoring case.
+func resolveTags(tx *gorm.DB, userID uuid.UUID, names []string) ([]models.Tag, error) {
+	seen := make(map[string]bool, len(names))
+	var unique, keys []string
+	for _, raw := range names {
+		name, err := normalizeTagName(raw)
+		if err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, name)
+		keys = append(keys, key)
+	}
+	if len(unique) > maxTagsPerNote {
+		return nil, errors.New("too many tags")
+	}
+	if len(unique) == 0 {
+		return []models.Tag{}, nil
+	}
+
+	var existing []models.Tag
+	if err := tx.Where("user_id = ? AND LOWER(name) IN ?", userID, keys).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]models.Tag, len(existing))
+	for _, tag := range existing {
+		byKey[strings.ToLower(tag.Name)] = tag
+	}
+
+	var missing []models.Tag
+	for _, name := range unique {
+		if _, ok := byKey[strings.ToLower(name)]; !ok {
+			missing = append(missing, models.Tag{UserID: userID, Name: name})
+		}
+	}
+	if len(missing) > 0 {
+		// Another request may create the same tag meanwhile; keep theirs
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&missing).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("user_id = ? AND LOWER(name) IN ?", userID, keys).Find(&existing).Error; err != nil {
+			return nil, err
+		}
+		for _, tag := range existing {
+			byKey[strings.ToLower(tag.Name)] = tag
+		}
+	}
+
+	tags := make([]models.Tag, 0, len(unique))
+	for _, name := range unique {
+		tags = append(tags, byKey[strings.ToLower(name)])
+	}
+	return tags, nil
+}
+
+// setNoteTags replaces the tags on a note
+func setNoteTags(tx *gorm.DB, noteID uuid.UUID, tags []models.Tag) error {
+	if err := tx.Where("note_id = ?", noteID).Delete(&models.NoteTag{}).Error; err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	rows := make([]models.NoteTag, len(tags))
+	for i, tag := range tags {
+		rows[i] = models.NoteTag{NoteID: noteID, TagID: tag.ID}
+	}
+	return tx.Create(&rows).Error
+}
+
+// normalizeTagName trims a tag name and collapses runs of whitespace.
+// Commas are not allowed since tag filters are comma-separated.
+func normalizeTagName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	switch {
+	case name == "":
+		return "", errors.New("tag name is required")
+	case utf8.RuneCountInString(name) > maxTagNameLength:
+		return "", errors.New("tag name too long")
+	case strings.Contains(name, ","):
+		return "", errors.New("invalid tag name")
+	}
+	return name, nil
+}
+
+func normalizeTagColor(color string) (string, error) {
+	color = strings.TrimSpace(color)
+	if color == "" {
+		return "", nil
+	}
+	if !tagColorPattern.MatchString(color) {
+		return "", errors.New("invalid color")
+	}
+	return strings.ToLower(color), nil
+}
+
+func toTagResponse(tag *models.Tag, noteCount int64) TagResponse {
+	return TagResponse{
+		ID:        tag.ID.String(),
+		Name:      tag.Name,
+		Color:     tag.Color,
+		NoteCount: noteCount,
+		CreatedAt: tag.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
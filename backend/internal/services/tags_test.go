@@ -0,0 +1,49 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTagName(t *testing.T) {
+	tests := []struct {
+		name, want, err string
+	}{
+		{"  machine   learning ", "machine learning", ""},
+		{"Go", "Go", ""},
+		{"", "", "tag name is required"},
+		{" \t ", "", "tag name is required"},
+		{"a,b", "", "invalid tag name"},
+		{strings.Repeat("é", 50), strings.Repeat("é", 50), ""},
+		{strings.Repeat("x", 51), "", "tag name too long"},
+	}
+	for _, tt := range tests {
+		got, err := normalizeTagName(tt.name)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("normalizeTagName(%q) error = %v, want %s", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeTagName(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTagColor(t *testing.T) {
+	for color, want := range map[string]string{
+		"":          "",
+		"#3182CE":   "#3182ce",
+		" #00ff00 ": "#00ff00",
+	} {
+		if got, err := normalizeTagColor(color); err != nil || got != want {
+			t.Errorf("normalizeTagColor(%q) = %q, %v, want %q", color, got, err, want)
+		}
+	}
+	for _, color := range []string{"red", "#fff", "3182ce", "#3182cg", "#3182ce00"} {
+		if _, err := normalizeTagColor(color); err == nil {
+			t.Errorf("normalizeTagColor(%q) succeeded, want an error", color)
+		}
+	}
+}
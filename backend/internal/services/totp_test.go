@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 appendix B (SHA1), truncated to six digits
+func TestTOTPCodeRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := totpEncoding.DecodeString(secret)
+	now := time.Unix(1700000000, 0)
+	step := now.Unix() / totpPeriod
+
+	if got, ok := validateTOTP(secret, totpCode(key, step), now); !ok || got != step {
+		t.Errorf("current code rejected: step %d ok %v", got, ok)
+	}
+	if got, ok := validateTOTP(secret, totpCode(key, step-1), now); !ok || got != step-1 {
+		t.Errorf("previous step code rejected: step %d ok %v", got, ok)
+	}
+	if _, ok := validateTOTP(secret, totpCode(key, step-2), now); ok {
+		t.Error("code two steps old accepted")
+	}
+	if _, ok := validateTOTP(secret, "12345", now); ok {
+		t.Error("short code accepted")
+	}
+}
+
+func TestTOTPSecretEncryption(t *testing.T) {
+	encrypted, err := encryptTOTPSecret("key", "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := decryptTOTPSecret("key", encrypted)
+	if err != nil || secret != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("decrypt = %q, %v", secret, err)
+	}
+	if _, err := decryptTOTPSecret("other key", encrypted); err == nil {
+		t.Error("decrypt with the wrong key succeeded")
+	}
+}
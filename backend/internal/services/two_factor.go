@@ -0,0 +1,288 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+	"github.com/pratts/tts-study-assistant/backend/internal/database"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// TwoFactorService manages TOTP enrollment and recovery codes
+type TwoFactorService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+const recoveryCodeCount = 10
+
+type TwoFactorStatusResponse struct {
+	Enabled                bool   `json:"enabled"`
+	EnabledAt              string `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int64  `json:"recovery_codes_remaining"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"` // Pre-hashed password from UI
+	Code     string `json:"code"`     // TOTP or recovery code
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func NewTwoFactorService(cfg *config.Config) *TwoFactorService {
+	return &TwoFactorService{
+		db:  database.DB,
+		cfg: cfg,
+	}
+}
+
+// GetStatus reports whether 2FA is on and how many recovery codes are left
+func (s *TwoFactorService) GetStatus(userID string) (*TwoFactorStatusResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	response := &TwoFactorStatusResponse{Enabled: user.TOTPEnabledAt != nil}
+	if user.TOTPEnabledAt != nil {
+		response.EnabledAt = user.TOTPEnabledAt.Format("2006-01-02T15:04:05Z07:00")
+		err = s.db.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Count(&response.RecoveryCodesRemaining).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// BeginSetup generates a new TOTP secret for the user. It does not take
+// effect until ConfirmSetup is called with a code from the authenticator.
+func (s *TwoFactorService) BeginSetup(userID string) (*TwoFactorSetupResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, errors.New("two-factor already enabled")
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := encryptTOTPSecret(s.cfg.TOTPEncryptionKey, secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(user).Update("totp_secret", encrypted).Error; err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: totpURI(s.cfg.JWTIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmSetup enables 2FA once the user proves their authenticator works,
+// and returns the recovery codes. They are only shown this once.
+func (s *TwoFactorService) ConfirmSetup(userID, code string, client ClientInfo) (*RecoveryCodesResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, errors.New("two-factor already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("two-factor setup not started")
+	}
+
+	var codes []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.verifyTOTP(tx, user, code); err != nil {
+			return err
+		}
+		if err := tx.Model(user).Update("totp_enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordAuditEvent(s.db, user.ID, AuditTwoFactorEnabled, client, nil)
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable turns 2FA off. It needs both the password and a current code so a
+// stolen session alone cannot remove the second factor.
+func (s *TwoFactorService) Disable(userID string, req *DisableTwoFactorRequest, client ClientInfo) error {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabledAt == nil {
+		return errors.New("two-factor not enabled")
+	}
+	if ok, _ := verifyPassword(user.Password, req.Password); !ok {
+		return errors.New("invalid password")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.verifySecondFactor(tx, user, req.Code, client); err != nil {
+			return err
+		}
+		err := tx.Model(user).Updates(map[string]any{
+			"totp_secret":     "",
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	recordAuditEvent(s.db, user.ID, AuditTwoFactorDisabled, client, nil)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a
+// current TOTP code
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID, code string, client ClientInfo) (*RecoveryCodesResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt == nil {
+		return nil, errors.New("two-factor not enabled")
+	}
+
+	var codes []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.verifyTOTP(tx, user, code); err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordAuditEvent(s.db, user.ID, AuditRecoveryCodesRegenerated, client, nil)
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code
+func (s *TwoFactorService) verifySecondFactor(tx *gorm.DB, user *models.User, code string, client ClientInfo) error {
+	if err := s.verifyTOTP(tx, user, code); err == nil {
+		return nil
+	} else if err.Error() != "invalid two-factor code" {
+		return err
+	}
+
+	result := tx.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashSecret(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invalid two-factor code")
+	}
+	recordAuditEvent(tx, user.ID, AuditRecoveryCodeUsed, client, nil)
+	return nil
+}
+
+// verifyTOTP checks a TOTP code and records its time step. The conditional
+// update rejects a code whose step was already used, even concurrently.
+func (s *TwoFactorService) verifyTOTP(tx *gorm.DB, user *models.User, code string) error {
+	secret, err := decryptTOTPSecret(s.cfg.TOTPEncryptionKey, user.TOTPSecret)
+	if err != nil {
+		// Most likely TOTP_ENCRYPTION_KEY changed. Recovery codes still work.
+		log.Printf("Failed to decrypt TOTP secret for user %s: %v", user.ID, err)
+		return errors.New("invalid two-factor code")
+	}
+	step, ok := validateTOTP(secret, code, time.Now())
+	if !ok {
+		return errors.New("invalid two-factor code")
+	}
+	result := tx.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invalid two-factor code")
+	}
+	return nil
+}
+
+func (s *TwoFactorService) getUser(userID string) (*models.User, error) {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	return &user, nil
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and returns a new
+// set. Codes look like "3f9a2-c41e7".
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(b)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: hashSecret(raw)}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode accepts recovery codes with any case, spaces or dashes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') {
+			return r
+		}
+		return -1
+	}, code)
+}
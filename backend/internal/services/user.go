@@ -3,30 +3,47 @@ package services
 import (
 	"errors"
 
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
 	"github.com/pratts/tts-study-assistant/backend/internal/database"
 	"github.com/pratts/tts-study-assistant/backend/internal/models"
 	"gorm.io/gorm"
 )
 
 type UserService struct {
-	db *gorm.DB
+	db             *gorm.DB
+	accountService *AccountService
 }
 
 type UpdateProfileRequest struct {
 	Name     string `json:"name,omitempty"`
 	Email    string `json:"email,omitempty"`
 	Password string `json:"password,omitempty"` // Pre-hashed password from UI
+
+	// When the password changes every session is revoked; set this to keep
+	// the session making the request
+	KeepCurrentSession bool `json:"keep_current_session,omitempty"`
+
+	// How many revisions to keep per note, 1 to MaxNoteRevisionLimit
+	NoteRevisionLimit *int `json:"note_revision_limit,omitempty"`
 }
 
 type UserProfileResponse struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
-	Name  string `json:"name"`
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	EmailVerified bool   `json:"email_verified"`
+	PendingEmail  string `json:"pending_email,omitempty"` // New address awaiting confirmation
+
+	NoteRevisionLimit int `json:"note_revision_limit"`
+
+	// Set when a password change revoked the caller's access token
+	AccessToken string `json:"access_token,omitempty"`
 }
 
-func NewUserService() *UserService {
+func NewUserService(cfg *config.Config) *UserService {
 	return &UserService{
-		db: database.DB,
+		db:             database.DB,
+		accountService: NewAccountService(cfg),
 	}
 }
 
@@ -40,15 +57,21 @@ func (s *UserService) GetProfile(userID string) (*UserProfileResponse, error) {
 	}
 
 	response := &UserProfileResponse{
-		ID:    user.ID.String(),
-		Email: user.Email,
-		Name:  user.Name,
+		ID:            user.ID.String(),
+		Email:         user.Email,
+		Name:          user.Name,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		PendingEmail:  s.accountService.PendingEmail(userID),
+
+		NoteRevisionLimit: user.NoteRevisionLimit,
 	}
 
 	return response, nil
 }
 
-func (s *UserService) UpdateProfile(userID string, req *UpdateProfileRequest) (*UserProfileResponse, error) {
+// UpdateProfile updates the user's profile. If the password changes, every
+// session except keepSessionID (when non-empty) is revoked.
+func (s *UserService) UpdateProfile(userID, keepSessionID string, req *UpdateProfileRequest, client ClientInfo) (*UserProfileResponse, error) {
 	var user models.User
 	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -61,42 +84,92 @@ func (s *UserService) UpdateProfile(userID string, req *UpdateProfileRequest) (*
 	if req.Name != "" {
 		user.Name = req.Name
 	}
+	if req.NoteRevisionLimit != nil {
+		if *req.NoteRevisionLimit < 1 || *req.NoteRevisionLimit > MaxNoteRevisionLimit {
+			return nil, errors.New("invalid revision limit")
+		}
+		user.NoteRevisionLimit = *req.NoteRevisionLimit
+	}
 
-	if req.Email != "" {
+	// A new email is only applied once confirmed from the new inbox
+	changeEmail := req.Email != "" && req.Email != user.Email
+	if changeEmail {
 		// Check if email is already taken by another user
 		var existingUser models.User
 		if err := s.db.Where("email = ? AND id != ?", req.Email, userID).First(&existingUser).Error; err == nil {
 			return nil, errors.New("email already taken")
 		}
-		user.Email = req.Email
 	}
 
 	if req.Password != "" {
-		// Store the pre-hashed password directly
-		user.Password = req.Password
+		passwordHash, err := hashPassword(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = passwordHash
 	}
 
-	if err := s.db.Save(&user).Error; err != nil {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		if req.Password != "" {
+			return revokeUserSessions(tx, userID, keepSessionID)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	if req.Password != "" {
+		recordAuditEvent(s.db, user.ID, AuditPasswordChanged, client, nil)
+	}
+
+	if changeEmail {
+		if err := s.accountService.RequestEmailChange(&user, req.Email, client); err != nil {
+			return nil, err
+		}
+	}
 
 	response := &UserProfileResponse{
-		ID:    user.ID.String(),
-		Email: user.Email,
-		Name:  user.Name,
+		ID:            user.ID.String(),
+		Email:         user.Email,
+		Name:          user.Name,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		PendingEmail:  s.accountService.PendingEmail(userID),
+
+		NoteRevisionLimit: user.NoteRevisionLimit,
 	}
 
 	return response, nil
 }
 
-func (s *UserService) UpdatePassword(userID, oldPassword, newPassword string) error {
+// UpdatePassword changes the user's password and revokes every session
+// except keepSessionID, when non-empty
+func (s *UserService) UpdatePassword(userID, keepSessionID, oldPassword, newPassword string, client ClientInfo) error {
 	var user models.User
 	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
 		return err
 	}
-	if user.Password != oldPassword {
+	if ok, _ := verifyPassword(user.Password, oldPassword); !ok {
+		recordAuditFailure(s.db, user.ID, AuditPasswordChanged, client, map[string]any{
+			"reason": "incorrect_password",
+		})
 		return errors.New("incorrect password")
 	}
-	user.Password = newPassword
-	return s.db.Save(&user).Error
+	passwordHash, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password", passwordHash).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, userID, keepSessionID)
+	})
+	if err != nil {
+		return err
+	}
+	recordAuditEvent(s.db, user.ID, AuditPasswordChanged, client, nil)
+	return nil
 }
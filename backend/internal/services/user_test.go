@@ -0,0 +1,94 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pratts/tts-study-assistant/backend/internal/models"
+)
+
+func TestUpdatePasswordRevokesOtherSessions(t *testing.T) {
+	useTestKeys(t, "secret")
+	passwordHash, err := hashPassword("old-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{ID: uuid.New(), Email: "a@example.com", Password: passwordHash, TokenVersion: 4}
+	current, other := uuid.New(), uuid.New()
+
+	auth := &AuthService{}
+	issued := map[uuid.UUID]string{}
+	for _, session := range []uuid.UUID{current, other} {
+		token, err := auth.generateAccessTokenWithSource(&user, "web", session.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		issued[session] = token
+	}
+
+	db := newFakeDB(t, user)
+	users := &UserService{db: db.DB}
+	if err := users.UpdatePassword(user.ID.String(), current.String(), "old-password", "new-password", ClientInfo{}); err != nil {
+		t.Fatalf("UpdatePassword() error = %v", err)
+	}
+	if !db.wrote(`UPDATE "users" SET "token_version"=token_version + 1`, user.ID.String()) {
+		t.Errorf("UpdatePassword() did not bump the token version: %q", db.writes)
+	}
+	if !db.wrote(`DELETE FROM "refresh_tokens"`, "family_id <> '"+current.String()+"'") {
+		t.Errorf("UpdatePassword() did not keep the current session: %q", db.writes)
+	}
+
+	// The stored version is now one higher and the current session is left
+	user.TokenVersion++
+	db = newFakeDB(t, user,
+		models.RefreshToken{UserID: user.ID, FamilyID: current, Source: "web"},
+		accessTokenState{TokenVersion: user.TokenVersion, SessionLive: true},
+	)
+	for session, token := range issued {
+		claims, err := auth.ParseToken(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !accessTokenRevoked(db.DB, claims.UserID, claims.SessionID, claims.Version) {
+			t.Errorf("access token of session %s issued before the change is still accepted", session)
+		}
+	}
+
+	auth.db = db.DB
+	token, err := auth.ReissueAccessToken(user.ID.String(), current.String())
+	if err != nil {
+		t.Fatalf("ReissueAccessToken() error = %v", err)
+	}
+	claims, err := auth.ParseToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.SessionID != current.String() || accessTokenRevoked(db.DB, claims.UserID, claims.SessionID, claims.Version) {
+		t.Errorf("reissued access token for session %s (version %d) is not accepted", claims.SessionID, claims.Version)
+	}
+}
+
+func TestAccessTokenRevoked(t *testing.T) {
+	userID, sessionID := uuid.NewString(), uuid.NewString()
+	tests := []struct {
+		name    string
+		state   accessTokenState
+		version int
+		want    bool
+	}{
+		{"current", accessTokenState{TokenVersion: 2, SessionLive: true}, 2, false},
+		{"version bumped", accessTokenState{TokenVersion: 3, SessionLive: true}, 2, true},
+		{"session revoked", accessTokenState{TokenVersion: 2}, 2, true},
+	}
+	for _, tt := range tests {
+		db := newFakeDB(t, tt.state)
+		if got := accessTokenRevoked(db.DB, userID, sessionID, tt.version); got != tt.want {
+			t.Errorf("%s: accessTokenRevoked() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Unknown users have no live tokens
+	if !accessTokenRevoked(newFakeDB(t).DB, userID, sessionID, 0) {
+		t.Error("accessTokenRevoked() = false for an unknown user")
+	}
+}
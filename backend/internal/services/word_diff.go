@@ -0,0 +1,177 @@
+package services
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Diff operations
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// maxDiffEdits bounds the work of a diff. Texts that differ in more words
+// than this are shown as replaced outright.
+const maxDiffEdits = 2000
+
+var diffTokenPattern = regexp.MustCompile(`\s+|\S+`)
+
+// DiffOp is a run of text that is in both texts, or only in the new or the
+// old one. Joining the equal and delete runs gives the old text back; the
+// equal and insert runs give the new one.
+type DiffOp struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type diffEdit struct {
+	op    string
+	token string
+}
+
+// diffWords compares two texts word by word. Whitespace is kept, so the
+// result can be rendered in place.
+func diffWords(from, to string) []DiffOp {
+	a := diffTokenPattern.FindAllString(from, -1)
+	b := diffTokenPattern.FindAllString(to, -1)
+
+	// The common ends need no search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var builder diffBuilder
+	for _, token := range a[:prefix] {
+		builder.add(DiffEqual, token)
+	}
+	middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if edits, ok := myersDiff(middleA, middleB); ok {
+		for _, edit := range edits {
+			builder.add(edit.op, edit.token)
+		}
+	} else {
+		builder.add(DiffDelete, strings.Join(middleA, ""))
+		builder.add(DiffInsert, strings.Join(middleB, ""))
+	}
+	for _, token := range a[len(a)-suffix:] {
+		builder.add(DiffEqual, token)
+	}
+	return builder.ops()
+}
+
+// myersDiff finds a shortest edit script from a to b with Myers' algorithm.
+// It gives up, returning false, past maxDiffEdits edits.
+func myersDiff(a, b []string) ([]diffEdit, bool) {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+	// trace[d] holds v for diagonals -d-1..d+1 as it was before step d
+	var trace [][]int
+	for d := 0; d <= min(maxD, maxDiffEdits); d++ {
+		trace = append(trace, slices.Clone(v[offset-d-1:offset+d+2]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// backtrackDiff walks the trace back from the end of both texts
+func backtrackDiff(a, b []string, trace [][]int) []diffEdit {
+	x, y := len(a), len(b)
+	var edits []diffEdit
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			edits = append(edits, diffEdit{DiffEqual, a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, diffEdit{DiffInsert, b[y-1]})
+			} else {
+				edits = append(edits, diffEdit{DiffDelete, a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	slices.Reverse(edits)
+	return edits
+}
+
+// diffBuilder joins edits into runs. Between two equal runs, deleted text
+// comes before inserted text.
+type diffBuilder struct {
+	result            []DiffOp
+	deleted, inserted strings.Builder
+}
+
+func (b *diffBuilder) add(op, text string) {
+	switch op {
+	case DiffDelete:
+		b.deleted.WriteString(text)
+	case DiffInsert:
+		b.inserted.WriteString(text)
+	default:
+		b.flush()
+		b.push(DiffEqual, text)
+	}
+}
+
+func (b *diffBuilder) flush() {
+	b.push(DiffDelete, b.deleted.String())
+	b.push(DiffInsert, b.inserted.String())
+	b.deleted.Reset()
+	b.inserted.Reset()
+}
+
+func (b *diffBuilder) push(op, text string) {
+	if text == "" {
+		return
+	}
+	if last := len(b.result) - 1; last >= 0 && b.result[last].Op == op {
+		b.result[last].Text += text
+		return
+	}
+	b.result = append(b.result, DiffOp{Op: op, Text: text})
+}
+
+func (b *diffBuilder) ops() []DiffOp {
+	b.flush()
+	if b.result == nil {
+		return []DiffOp{}
+	}
+	return b.result
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffWords(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     []DiffOp
+	}{
+		{"", "", []DiffOp{}},
+		{"same text", "same text", []DiffOp{{DiffEqual, "same text"}}},
+		{"", "new note", []DiffOp{{DiffInsert, "new note"}}},
+		{"old note", "", []DiffOp{{DiffDelete, "old note"}}},
+		{
+			"the quick brown fox", "the slow brown dog",
+			[]DiffOp{{DiffEqual, "the "}, {DiffDelete, "quick"}, {DiffInsert, "slow"}, {DiffEqual, " brown "}, {DiffDelete, "fox"}, {DiffInsert, "dog"}},
+		},
+		{
+			"spaced  out", "spaced out",
+			[]DiffOp{{DiffEqual, "spaced"}, {DiffDelete, "  "}, {DiffInsert, " "}, {DiffEqual, "out"}},
+		},
+		{
+			"a b c", "a x b c",
+			[]DiffOp{{DiffEqual, "a "}, {DiffInsert, "x "}, {DiffEqual, "b c"}},
+		},
+	}
+	for _, tt := range tests {
+		if got := diffWords(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("diffWords(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+// rebuild joins the runs that make up one side of a diff
+func rebuild(ops []DiffOp, skip string) string {
+	var b strings.Builder
+	for _, op := range ops {
+		if op.Op != skip {
+			b.WriteString(op.Text)
+		}
+	}
+	return b.String()
+}
+
+func TestDiffWordsRebuildsBothTexts(t *testing.T) {
+	words := []string{"alpha", "beta", "gamma", "delta", " ", " ", "\n"}
+	random := rand.New(rand.NewSource(1))
+	text := func() string {
+		var b strings.Builder
+		for range random.Intn(40) {
+			b.WriteString(words[random.Intn(len(words))])
+		}
+		return b.String()
+	}
+	for range 500 {
+		from, to := text(), text()
+		ops := diffWords(from, to)
+		if got := rebuild(ops, DiffInsert); got != from {
+			t.Fatalf("diffWords(%q, %q) old side = %q", from, to, got)
+		}
+		if got := rebuild(ops, DiffDelete); got != to {
+			t.Fatalf("diffWords(%q, %q) new side = %q", from, to, got)
+		}
+	}
+}
+
+func TestDiffWordsFallsBackOnLargeChanges(t *testing.T) {
+	from := strings.Repeat("a ", maxDiffEdits)
+	to := strings.Repeat("b ", maxDiffEdits)
+	ops := diffWords(from, to)
+	if rebuild(ops, DiffInsert) != from || rebuild(ops, DiffDelete) != to {
+		t.Fatalf("diffWords() fallback does not rebuild both texts")
+	}
+}
@@ -0,0 +1,226 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+)
+
+// Keys holds the key set used to sign and verify JWTs. It is set by Load.
+var Keys *KeySet
+
+// KeySet signs tokens with one active Ed25519 key and verifies tokens signed
+// with any key it knows, looked up by the kid header. Rotating keys means
+// adding a new key, making it active, and removing the old one once every
+// token it signed has expired.
+//
+// Without a key directory the set falls back to HS256 with the shared JWT
+// secret, which keeps local development working but cannot be published as a
+// JWKS.
+type KeySet struct {
+	issuer    string
+	activeKID string
+	keys      map[string]*key
+	secret    []byte
+}
+
+type key struct {
+	id      string
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Load builds the key set from the configuration and stores it in Keys.
+//
+// cfg.JWTKeysDir holds one PEM file per key, named after its key ID:
+// <kid>.pem for PKCS#8 private keys and <kid>.pub.pem for public keys of
+// retired signing keys that should still verify tokens.
+func Load(cfg *config.Config) error {
+	ks, err := NewKeySet(cfg)
+	if err != nil {
+		return err
+	}
+	Keys = ks
+	return nil
+}
+
+// NewKeySet builds a key set from the configuration
+func NewKeySet(cfg *config.Config) (*KeySet, error) {
+	ks := &KeySet{
+		issuer: cfg.JWTIssuer,
+		keys:   make(map[string]*key),
+	}
+
+	if cfg.JWTKeysDir == "" {
+		if cfg.JWTSecret == "" {
+			return nil, errors.New("either JWT_KEYS_DIR or JWT_SECRET must be set")
+		}
+		log.Println("JWT_KEYS_DIR not set, signing tokens with HS256; use signing keys in production")
+		ks.secret = []byte(cfg.JWTSecret)
+		return ks, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(cfg.JWTKeysDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		k, err := readKey(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if existing, ok := ks.keys[k.id]; ok && existing.private != nil {
+			continue
+		}
+		ks.keys[k.id] = k
+	}
+
+	ks.activeKID = cfg.JWTActiveKeyID
+	if ks.activeKID == "" {
+		// Default to the last private key by name, e.g. the newest of
+		// date-named keys such as 2025-06.pem
+		var ids []string
+		for id, k := range ks.keys {
+			if k.private != nil {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		if len(ids) > 0 {
+			ks.activeKID = ids[len(ids)-1]
+		}
+	}
+	active, ok := ks.keys[ks.activeKID]
+	if !ok || active.private == nil {
+		return nil, fmt.Errorf("no private key found for active key ID %q in %s", ks.activeKID, cfg.JWTKeysDir)
+	}
+
+	log.Printf("Signing tokens with EdDSA key %s (%d verification keys)", ks.activeKID, len(ks.keys))
+	return ks, nil
+}
+
+func readKey(file string) (*key, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	name := strings.TrimSuffix(filepath.Base(file), ".pem")
+	if strings.HasSuffix(name, ".pub") {
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		public, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("not an Ed25519 public key")
+		}
+		return &key{id: strings.TrimSuffix(name, ".pub"), public: public}, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an Ed25519 private key")
+	}
+	return &key{id: name, private: private, public: private.Public().(ed25519.PublicKey)}, nil
+}
+
+// Sign signs the claims with the active key. The issuer claim is added when
+// the claims are a jwt.MapClaims without one.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if mapClaims, ok := claims.(jwt.MapClaims); ok && ks.issuer != "" {
+		if _, ok := mapClaims["iss"]; !ok {
+			mapClaims["iss"] = ks.issuer
+		}
+	}
+
+	if ks.secret != nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.secret)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = ks.activeKID
+	return token.SignedString(ks.keys[ks.activeKID].private)
+}
+
+// Parse verifies a token and fills claims. Only the algorithm the key set
+// signs with is accepted, and EdDSA tokens must name a known key.
+func (ks *KeySet) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	options := []jwt.ParserOption{}
+	if ks.issuer != "" {
+		options = append(options, jwt.WithIssuer(ks.issuer))
+	}
+
+	if ks.secret != nil {
+		options = append(options, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+		return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			return ks.secret, nil
+		}, options...)
+	}
+
+	options = append(options, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}))
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		k, ok := ks.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return k.public, nil
+	}, options...)
+}
+
+// JWKS returns the public verification keys. It is empty in HS256 mode.
+func (ks *KeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(ks.keys))
+	for id := range ks.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(ids))}
+	for _, id := range ids {
+		jwks.Keys = append(jwks.Keys, JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(ks.keys[id].public),
+			KeyID:     id,
+			Use:       "sig",
+			Algorithm: jwt.SigningMethodEdDSA.Alg(),
+		})
+	}
+	return jwks
+}
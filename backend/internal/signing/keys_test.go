@@ -0,0 +1,226 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pratts/tts-study-assistant/backend/internal/config"
+)
+
+// writeKey writes a private key as <kid>.pem, or only its public key as
+// <kid>.pub.pem for a retired key
+func writeKey(t *testing.T, dir, kid string, retired bool) ed25519.PrivateKey {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := &pem.Block{Type: "PRIVATE KEY"}
+	name := kid + ".pem"
+	if retired {
+		block.Type, name = "PUBLIC KEY", kid+".pub.pem"
+		block.Bytes, err = x509.MarshalPKIXPublicKey(public)
+	} else {
+		block.Bytes, err = x509.MarshalPKCS8PrivateKey(private)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return private
+}
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "user", "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+func TestNewKeySetLoadsKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "2025-01", true)
+	writeKey(t, dir, "2025-06", false)
+	writeKey(t, dir, "2025-03", false)
+
+	ks, err := NewKeySet(&config.Config{JWTKeysDir: dir, JWTIssuer: "test"})
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	if len(ks.keys) != 3 || ks.secret != nil {
+		t.Errorf("NewKeySet() loaded %d keys (HS256 %v), want 3 EdDSA keys", len(ks.keys), ks.secret != nil)
+	}
+	// The last private key by name, not the retired one
+	if ks.activeKID != "2025-06" {
+		t.Errorf("active key = %q, want 2025-06", ks.activeKID)
+	}
+
+	ks, err = NewKeySet(&config.Config{JWTKeysDir: dir, JWTActiveKeyID: "2025-03"})
+	if err != nil || ks.activeKID != "2025-03" {
+		t.Errorf("NewKeySet() active key = %v (%v), want 2025-03", ks, err)
+	}
+	token, err := ks.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil || parsed.Header["kid"] != "2025-03" || parsed.Method.Alg() != "EdDSA" {
+		t.Errorf("Sign() header = %v (%v), want EdDSA with kid 2025-03", parsed.Header, err)
+	}
+
+	for _, kid := range []string{"2025-01", "2024-12"} {
+		if _, err := NewKeySet(&config.Config{JWTKeysDir: dir, JWTActiveKeyID: kid}); err == nil {
+			t.Errorf("NewKeySet() accepted active key %s without a private key", kid)
+		}
+	}
+	if _, err := NewKeySet(&config.Config{JWTKeysDir: t.TempDir()}); err == nil {
+		t.Error("NewKeySet() accepted an empty key directory")
+	}
+	if _, err := NewKeySet(&config.Config{}); err == nil {
+		t.Error("NewKeySet() accepted neither a key directory nor a secret")
+	}
+}
+
+func TestParseVerifiesKnownKeys(t *testing.T) {
+	dir := t.TempDir()
+	retired := writeKey(t, dir, "2025-01", true)
+	writeKey(t, dir, "2025-06", false)
+	ks, err := NewKeySet(&config.Config{JWTKeysDir: dir, JWTIssuer: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := ks.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Parse(token, jwt.MapClaims{}); err != nil {
+		t.Errorf("Parse() error = %v for a token signed with the active key", err)
+	}
+
+	signWith := func(kid string, key ed25519.PrivateKey) string {
+		t.Helper()
+		c := claims()
+		c["iss"] = "test"
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, c)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	// Tokens signed before a rotation still verify with the public key
+	if _, err := ks.Parse(signWith("2025-01", retired), jwt.MapClaims{}); err != nil {
+		t.Errorf("Parse() error = %v for a token signed with a retired key", err)
+	}
+
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Parse(signWith("2024-12", other), jwt.MapClaims{}); err == nil {
+		t.Error("Parse() accepted a token with an unknown kid")
+	}
+	if _, err := ks.Parse(signWith("2025-06", other), jwt.MapClaims{}); err == nil {
+		t.Error("Parse() accepted a token signed with another key under a known kid")
+	}
+
+	// An HS256 token, for example signed with a public key as the secret,
+	// must not verify when EdDSA keys are loaded
+	hs256 := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": "test", "exp": time.Now().Add(time.Minute).Unix()})
+	hs256.Header["kid"] = "2025-06"
+	signed, err := hs256.SignedString([]byte(ks.keys["2025-06"].public))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// It is turned away by the algorithm, before any key is looked up
+	if _, err := ks.Parse(signed, jwt.MapClaims{}); err == nil || !strings.Contains(err.Error(), "signing method HS256 is invalid") {
+		t.Errorf("Parse() error = %v for an HS256 token with EdDSA keys loaded, want an invalid signing method", err)
+	}
+
+	c := claims()
+	c["iss"] = "someone-else"
+	wrongIssuer := jwt.NewWithClaims(jwt.SigningMethodEdDSA, c)
+	wrongIssuer.Header["kid"] = ks.activeKID
+	signed, err = wrongIssuer.SignedString(ks.keys[ks.activeKID].private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Parse(signed, jwt.MapClaims{}); err == nil {
+		t.Error("Parse() accepted a token from another issuer")
+	}
+}
+
+func TestHS256KeySet(t *testing.T) {
+	ks, err := NewKeySet(&config.Config{JWTSecret: "secret", JWTIssuer: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := ks.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Parse(token, jwt.MapClaims{}); err != nil {
+		t.Errorf("Parse() error = %v for an HS256 token", err)
+	}
+
+	// Neither other secrets nor other algorithms are accepted
+	other, err := NewKeySet(&config.Config{JWTSecret: "other", JWTIssuer: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Parse(token, jwt.MapClaims{}); err == nil {
+		t.Error("Parse() accepted a token signed with another secret")
+	}
+	dir := t.TempDir()
+	writeKey(t, dir, "2025-06", false)
+	eddsa, err := NewKeySet(&config.Config{JWTKeysDir: dir, JWTIssuer: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err = eddsa.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Parse(token, jwt.MapClaims{}); err == nil {
+		t.Error("Parse() accepted an EdDSA token in HS256 mode")
+	}
+
+	if jwks := ks.JWKS(); len(jwks.Keys) != 0 {
+		t.Errorf("JWKS() = %+v in HS256 mode, want no keys", jwks)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "2025-06", false)
+	writeKey(t, dir, "2025-01", true)
+	ks, err := NewKeySet(&config.Config{JWTKeysDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := ks.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != "2025-01" || jwks.Keys[1].KeyID != "2025-06" {
+		t.Fatalf("JWKS() = %+v, want both keys sorted by kid", jwks)
+	}
+	for _, jwk := range jwks.Keys {
+		if jwk.KeyType != "OKP" || jwk.Curve != "Ed25519" || jwk.Use != "sig" || jwk.Algorithm != "EdDSA" {
+			t.Errorf("JWKS() key = %+v", jwk)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || !ks.keys[jwk.KeyID].public.Equal(ed25519.PublicKey(x)) {
+			t.Errorf("JWKS() key %s does not hold its public key", jwk.KeyID)
+		}
+	}
+}
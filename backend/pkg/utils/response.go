@@ -12,6 +12,7 @@ type SuccessResponse struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
+	Meta    interface{} `json:"meta,omitempty"` // Details about Data, such as pagination
 }
 
 func SendError(c *fiber.Ctx, status int, message string, code ...string) error {
@@ -40,6 +41,17 @@ func SendSuccess(c *fiber.Ctx, message string, data ...interface{}) error {
 	return c.Status(fiber.StatusOK).JSON(response)
 }
 
+// SendSuccessWithMeta sends data along with details about it, such as the
+// cursors of a page, without changing the shape of data
+func SendSuccessWithMeta(c *fiber.Ctx, message string, data, meta interface{}) error {
+	return c.Status(fiber.StatusOK).JSON(SuccessResponse{
+		Success: true,
+		Message: message,
+		Data:    data,
+		Meta:    meta,
+	})
+}
+
 // Add SendErrorWithCode for compatibility
 func SendErrorWithCode(c *fiber.Ctx, status int, message string, code string) error {
 	return SendError(c, status, message, code)
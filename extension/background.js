@@ -116,11 +116,27 @@ chrome.runtime.onMessage.addListener((request, sender, sendResponse) => {
         case 'triggerSummarize':
             handleMessageSummarize(request);
             break;
+
+        case 'startDevicePairing':
+            handleStartDevicePairing(sendResponse);
+            return true; // Will respond asynchronously
         default:
             sendResponse({ status: 'unknown action' });
     }
 });
 
+// Starts device pairing and keeps polling here, since the popup closes when
+// the user switches to the web app to approve the code
+async function handleStartDevicePairing(sendResponse) {
+    const pairing = await authManager.startDevicePairing();
+    sendResponse(pairing);
+    if (!pairing.success) return;
+
+    chrome.tabs.create({ url: pairing.verification_uri_complete });
+    const result = await authManager.pollDevicePairing(pairing.device_code, pairing.interval, pairing.expires_in);
+    chrome.runtime.sendMessage({ action: 'devicePairingComplete', ...result }).catch(() => { });
+}
+
 async function handleMessageSummarize(request) {
     try {
         const isAuthenticated = await ApiClient.isAuthenticated();
@@ -60,11 +60,12 @@ export class ApiClient {
         return data.data || [];
     }
 
+    // Saving the same passage twice returns the note saved the first time
     async createNote(noteData) {
         const data = await this._fetchWithAuth(`${this.API_URL}/notes`, {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify(noteData)
+            body: JSON.stringify({ return_existing: true, ...noteData })
         });
         return data.data;
     }
@@ -26,11 +26,67 @@ export class AuthManager {
         });
         const data = await resp.json();
         if (!resp.ok || !data.data) return { success: false, message: data.message || 'Login failed' };
+        if (data.data.two_factor_required) {
+            return { success: false, twoFactorRequired: true, challengeToken: data.data.challenge_token };
+        }
+        await this._storeTokens(data.data);
+        this.setupTokenRefreshAlarm();
+        return { success: true, user: data.data.user };
+    }
+
+    // Second login step for accounts with two-factor authentication
+    async loginTwoFactor(challengeToken, code) {
+        const resp = await fetch(`${this.API_URL}/auth/login/2fa`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ challenge_token: challengeToken, code })
+        });
+        const data = await resp.json();
+        if (!resp.ok || !data.data) return { success: false, message: data.message || 'Invalid code' };
         await this._storeTokens(data.data);
         this.setupTokenRefreshAlarm();
         return { success: true, user: data.data.user };
     }
 
+    // Device pairing: the extension shows a short code that the user approves
+    // in the signed-in web app, so no password is typed into the extension.
+    async startDevicePairing() {
+        const resp = await fetch(`${this.API_URL}/auth/device/code`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ device_name: 'Study Assistant extension' })
+        });
+        const data = await resp.json().catch(() => ({}));
+        if (!resp.ok || !data.data) return { success: false, message: data.message || 'Could not start pairing' };
+        return { success: true, ...data.data };
+    }
+
+    async pollDevicePairing(deviceCode, interval, expiresIn) {
+        let wait = interval * 1000;
+        const deadline = Date.now() + expiresIn * 1000;
+        while (Date.now() < deadline) {
+            await new Promise(resolve => setTimeout(resolve, wait));
+            const resp = await fetch(`${this.API_URL}/auth/device/token`, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ device_code: deviceCode })
+            });
+            const data = await resp.json().catch(() => ({}));
+            if (resp.ok && data.data) {
+                await this._storeTokens(data.data);
+                this.setupTokenRefreshAlarm();
+                return { success: true, user: data.data.user };
+            }
+            if (data.code === 'AUTHORIZATION_PENDING') continue;
+            if (data.code === 'SLOW_DOWN' || resp.status === 429) {
+                wait += 5000;
+                continue;
+            }
+            return { success: false, message: data.message || 'Pairing failed' };
+        }
+        return { success: false, message: 'Pairing code expired' };
+    }
+
     async checkSession() {
         const tokens = await this._getTokens();
         if (!tokens.access_token) return { loggedIn: false };
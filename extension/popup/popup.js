@@ -443,9 +443,22 @@ function setupAuthListeners() {
                 body: JSON.stringify({ email, password: hashed, source: 'extension' })
             });
 
-            const data = await resp.json();
+            let data = await resp.json();
             if (!resp.ok) throw new Error(data.message);
 
+            // Accounts with two-factor authentication need a code to finish
+            if (data.data.two_factor_required) {
+                const code = prompt('Enter the code from your authenticator app, or a recovery code');
+                if (!code) return;
+                const codeResp = await fetch(`${API_BASE}/auth/login/2fa`, {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ challenge_token: data.data.challenge_token, code: code.trim() })
+                });
+                data = await codeResp.json();
+                if (!codeResp.ok) throw new Error(data.message);
+            }
+
             await chrome.storage.local.set({
                 access_token: data.data.access_token,
                 refresh_token: data.data.refresh_token,
@@ -463,6 +476,33 @@ function setupAuthListeners() {
         }
     };
 
+    // Device pairing with the web app
+    document.getElementById('pair-btn').addEventListener('click', async () => {
+        const status = document.getElementById('pair-status');
+        status.textContent = 'Requesting a code...';
+        const pairing = await chrome.runtime.sendMessage({ action: 'startDevicePairing' });
+        if (!pairing || !pairing.success) {
+            status.textContent = (pairing && pairing.message) || 'Could not start pairing';
+            return;
+        }
+        status.textContent = `Enter code ${pairing.user_code} in the web app to connect.`;
+    });
+
+    chrome.runtime.onMessage.addListener(async (request) => {
+        if (request.action !== 'devicePairingComplete') return;
+        const status = document.getElementById('pair-status');
+        if (!request.success) {
+            status.textContent = request.message || 'Pairing failed';
+            return;
+        }
+        status.textContent = '';
+        authState.loggedIn = true;
+        authState.user = request.user;
+        hideModal('login-modal-overlay');
+        updateAuthHeader();
+        await loadSiteNotes();
+    });
+
     // Signup form
     document.getElementById('signup-form').onsubmit = async (e) => {
         e.preventDefault();
@@ -8,6 +8,10 @@ import Notes from './pages/Notes';
 import Profile from './pages/Profile';
 import MainLayout from './components/Layout/MainLayout';
 import PrivacyPolicy from './pages/PrivacyPolicy';
+import Device from './pages/Device';
+import EmailLink from './pages/EmailLink';
+import ResetPassword from './pages/ResetPassword';
+import SSOCallback from './pages/SSOCallback';
 
 function PrivateRoute({ children }: { children: JSX.Element }) {
   const { isAuthenticated, loading } = useAuth();
@@ -54,7 +58,19 @@ export default function App() {
             </PrivateRoute>
           }
         />
+        <Route
+          path="/device"
+          element={
+            <PrivateRoute>
+              <MainLayout><Device /></MainLayout>
+            </PrivateRoute>
+          }
+        />
         <Route path="/privacy-policy" element={<PrivacyPolicy />} />
+        <Route path="/verify-email" element={<EmailLink action="verify" />} />
+        <Route path="/confirm-email" element={<EmailLink action="change" />} />
+        <Route path="/reset-password" element={<ResetPassword />} />
+        <Route path="/sso/callback" element={<SSOCallback />} />
         <Route path="*" element={<Navigate to="/" />} />
       </Routes>
     </AuthProvider>
@@ -54,6 +54,9 @@ export async function loginApi(email: string, password: string) {
         body: JSON.stringify({ email, password })
     });
     const data = await resp.json();
+    if (resp.ok && data.data && data.data.two_factor_required) {
+        return { success: false, two_factor_required: true, challenge_token: data.data.challenge_token };
+    }
     if (resp.ok && data.data) {
         return {
             success: true,
@@ -65,6 +68,25 @@ export async function loginApi(email: string, password: string) {
     return { success: false, message: data.message };
 }
 
+// Second login step for accounts with two-factor authentication
+export async function loginTwoFactorApi(challengeToken: string, code: string) {
+    const resp = await fetch(`${API_URL}/auth/login/2fa`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ challenge_token: challengeToken, code })
+    });
+    const data = await resp.json();
+    if (resp.ok && data.data) {
+        return {
+            success: true,
+            access_token: data.data.access_token,
+            refresh_token: data.data.refresh_token,
+            user: data.data.user
+        };
+    }
+    return { success: false, code: data.code, message: data.message };
+}
+
 export async function refreshTokenApi(refreshToken: string) {
     const resp = await fetch(`${API_URL}/auth/refresh`, {
         method: 'POST',
@@ -102,34 +124,268 @@ export async function logoutApi() {
 }
 
 // Dashboard stats: GET /notes/stats
+// Note counts per domain and per tag.
 export async function getNotesStats() {
     const data = await fetchWithAuth(`${API_URL}/notes/stats`);
-    return data.data || [];
+    return data.data || { domains: [], tags: [] };
 }
 
 // Notes list: GET /notes
-export async function getNotes(params: { domain?: string, page?: number, page_size?: number } = {}) {
+// tags filters by tag name; tag_mode 'all' (default) or 'any'.
+// collection_id lists a collection's notes in their collection order.
+export async function getNotes(params: NotesParams = {}) {
+    return (await getNotesPage(params)).notes;
+}
+
+type NotesParams = {
+    domain?: string, tags?: string[], tag_mode?: 'all' | 'any', collection_id?: string,
+    created_since?: string, created_until?: string, updated_since?: string, updated_until?: string,
+    has_summary?: boolean, metadata?: Record<string, string>,
+    sort?: 'created_at' | 'updated_at' | 'title' | 'domain' | 'position', order?: 'asc' | 'desc',
+    page?: number, page_size?: number, cursor?: string, include_total?: boolean
+};
+
+// Notes with paging details: next_cursor, prev_cursor and, with include_total, total.
+// Pass a cursor from meta to move between pages.
+export async function getNotesPage(params: NotesParams = {}) {
     const search = new URLSearchParams();
     if (params.domain) search.set('domain', params.domain);
+    if (params.tags?.length) search.set('tags', params.tags.join(','));
+    if (params.tag_mode) search.set('tag_mode', params.tag_mode);
+    if (params.collection_id) search.set('collection_id', params.collection_id);
     if (params.page) search.set('page', params.page.toString());
     if (params.page_size) search.set('page_size', params.page_size.toString());
+    for (const key of ['created_since', 'created_until', 'updated_since', 'updated_until', 'sort', 'order'] as const) {
+        if (params[key]) search.set(key, params[key] as string);
+    }
+    if (params.has_summary !== undefined) search.set('has_summary', String(params.has_summary));
+    for (const [key, value] of Object.entries(params.metadata || {})) {
+        search.set(`metadata.${key}`, value);
+    }
+    if (params.cursor) search.set('cursor', params.cursor);
+    if (params.include_total) search.set('include_total', 'true');
     const data = await fetchWithAuth(`${API_URL}/notes?${search.toString()}`);
+    return { notes: data.data || [], meta: data.meta || {} };
+}
+
+// Full-text search: GET /notes/search
+// Supports "quoted phrases", prefix* and -excluded terms.
+export async function searchNotes(params: { q: string, page?: number, page_size?: number }) {
+    const search = new URLSearchParams({ q: params.q });
+    if (params.page) search.set('page', params.page.toString());
+    if (params.page_size) search.set('page_size', params.page_size.toString());
+    const data = await fetchWithAuth(`${API_URL}/notes/search?${search.toString()}`);
+    return data.data || { results: [], total: 0 };
+}
+
+// Tags: GET/POST /tags, PUT/DELETE /tags/:id, POST /tags/merge
+export async function getTags() {
+    const data = await fetchWithAuth(`${API_URL}/tags`);
     return data.data || [];
 }
 
+export async function createTag(name: string, color?: string) {
+    const data = await fetchWithAuth(`${API_URL}/tags`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ name, color })
+    });
+    return data.data;
+}
+
+export async function updateTag(id: string, changes: { name?: string, color?: string }) {
+    const data = await fetchWithAuth(`${API_URL}/tags/${id}`, {
+        method: 'PUT',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(changes)
+    });
+    return data.data;
+}
+
+export async function deleteTag(id: string) {
+    await fetchWithAuth(`${API_URL}/tags/${id}`, { method: 'DELETE' });
+    return true;
+}
+
+export async function mergeTags(sourceIds: string[], targetId: string) {
+    const data = await fetchWithAuth(`${API_URL}/tags/merge`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ source_ids: sourceIds, target_id: targetId })
+    });
+    return data.data;
+}
+
+// Collections: GET/POST /collections, GET/PUT/DELETE /collections/:id,
+// POST /collections/:id/move and the /collections/:id/notes routes
+export async function getCollections() {
+    const data = await fetchWithAuth(`${API_URL}/collections`);
+    return data.data || [];
+}
+
+export async function createCollection(name: string, parentId?: string, description?: string) {
+    const data = await fetchWithAuth(`${API_URL}/collections`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ name, parent_id: parentId, description })
+    });
+    return data.data;
+}
+
+export async function updateCollection(id: string, changes: { name?: string, description?: string }) {
+    const data = await fetchWithAuth(`${API_URL}/collections/${id}`, {
+        method: 'PUT',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(changes)
+    });
+    return data.data;
+}
+
+// parentId null moves the collection to the top level; position defaults to last
+export async function moveCollection(id: string, parentId: string | null, position?: number) {
+    const data = await fetchWithAuth(`${API_URL}/collections/${id}/move`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ parent_id: parentId, position })
+    });
+    return data.data;
+}
+
+export async function deleteCollection(id: string, mode: 'keep_children' | 'cascade' | 'cascade_notes' = 'keep_children') {
+    const data = await fetchWithAuth(`${API_URL}/collections/${id}?mode=${mode}`, { method: 'DELETE' });
+    return data.data;
+}
+
+export async function addNotesToCollection(id: string, noteIds: string[]) {
+    await fetchWithAuth(`${API_URL}/collections/${id}/notes`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ note_ids: noteIds })
+    });
+    return true;
+}
+
+export async function removeNoteFromCollection(id: string, noteId: string) {
+    await fetchWithAuth(`${API_URL}/collections/${id}/notes/${noteId}`, { method: 'DELETE' });
+    return true;
+}
+
+export async function reorderCollectionNotes(id: string, noteIds: string[]) {
+    await fetchWithAuth(`${API_URL}/collections/${id}/notes/order`, {
+        method: 'PUT',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ note_ids: noteIds })
+    });
+    return true;
+}
+
 // Delete note: DELETE /notes/:id
+// The note goes to the trash and can be restored until it is purged.
 export async function deleteNote(id: string) {
     await fetchWithAuth(`${API_URL}/notes/${id}`, { method: 'DELETE' });
     return true;
 }
 
+// Trash: GET/DELETE /notes/trash, DELETE /notes/trash/:id, POST /notes/trash/:id/restore
+export async function getTrash(params: { page?: number, page_size?: number } = {}) {
+    const search = new URLSearchParams();
+    if (params.page) search.set('page', params.page.toString());
+    if (params.page_size) search.set('page_size', params.page_size.toString());
+    const data = await fetchWithAuth(`${API_URL}/notes/trash?${search.toString()}`);
+    return { notes: data.data || [], meta: data.meta || {} };
+}
+
+export async function restoreNote(id: string) {
+    const data = await fetchWithAuth(`${API_URL}/notes/trash/${id}/restore`, { method: 'POST' });
+    return data.data;
+}
+
+export async function deleteNotePermanently(id: string) {
+    await fetchWithAuth(`${API_URL}/notes/trash/${id}`, { method: 'DELETE' });
+    return true;
+}
+
+export async function emptyTrash() {
+    const data = await fetchWithAuth(`${API_URL}/notes/trash`, { method: 'DELETE' });
+    return data.data;
+}
+
+// Revisions: GET /notes/:id/revisions, GET /notes/:id/revisions/:number,
+// GET /notes/:id/revisions/diff, POST /notes/:id/revisions/:number/revert
+export async function getRevisions(noteId: string) {
+    const data = await fetchWithAuth(`${API_URL}/notes/${noteId}/revisions`);
+    return data.data || [];
+}
+
+export async function getRevision(noteId: string, number: number) {
+    const data = await fetchWithAuth(`${API_URL}/notes/${noteId}/revisions/${number}`);
+    return data.data;
+}
+
+// Without from and to, the latest revision is compared with the one before it
+export async function diffRevisions(noteId: string, from?: number, to?: number) {
+    const search = new URLSearchParams();
+    if (from) search.set('from', from.toString());
+    if (to) search.set('to', to.toString());
+    const data = await fetchWithAuth(`${API_URL}/notes/${noteId}/revisions/diff?${search.toString()}`);
+    return data.data;
+}
+
+export async function revertNote(noteId: string, number: number) {
+    const data = await fetchWithAuth(`${API_URL}/notes/${noteId}/revisions/${number}/revert`, { method: 'POST' });
+    return data.data;
+}
+
+// Duplicates: GET /notes/duplicates, POST /notes/merge
+export async function getDuplicates(params: { threshold?: number, limit?: number } = {}) {
+    const search = new URLSearchParams();
+    if (params.threshold) search.set('threshold', params.threshold.toString());
+    if (params.limit) search.set('limit', params.limit.toString());
+    const data = await fetchWithAuth(`${API_URL}/notes/duplicates?${search.toString()}`);
+    return data.data || [];
+}
+
+// The target keeps its content; the merged notes go to the trash
+export async function mergeNotes(targetId: string, noteIds: string[]) {
+    const data = await fetchWithAuth(`${API_URL}/notes/merge`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ target_id: targetId, note_ids: noteIds })
+    });
+    return data.data;
+}
+
+// Bulk actions: POST /notes/bulk
+// Pass ids or a filter. With dry_run the result shows what would change.
+export async function bulkNotes(request: {
+    ids?: string[],
+    filter?: Record<string, any>,
+    action: 'delete' | 'set_domain' | 'merge_metadata' | 'add_tags' | 'remove_tags' | 'move_to_collection' | 'summarize',
+    dry_run?: boolean,
+    domain?: string,
+    metadata?: Record<string, any>,
+    tags?: string[],
+    collection_id?: string
+}) {
+    const data = await fetchWithAuth(`${API_URL}/notes/bulk`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(request)
+    });
+    return data.data;
+}
+
 // Update password: PUT /user/password
+// Other sessions are signed out; this one keeps going with a new access token.
 export async function updatePassword(oldPassword: string, newPassword: string) {
-    await fetchWithAuth(`${API_URL}/user/password`, {
+    const data = await fetchWithAuth(`${API_URL}/user/password`, {
         method: 'PUT',
         headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({ old_password: oldPassword, new_password: newPassword })
+        body: JSON.stringify({ old_password: oldPassword, new_password: newPassword, keep_current_session: true })
     });
+    if (data.data?.access_token) {
+        localStorage.setItem('access_token', data.data.access_token);
+    }
     return true;
 }
 
@@ -151,4 +407,199 @@ export async function generateSummary(id: string) {
         method: 'POST'
     });
     return data.data || data;
-} 
\ No newline at end of file
+} 
+
+// Active sessions: GET /user/sessions
+export async function getSessions() {
+    const data = await fetchWithAuth(`${API_URL}/user/sessions`);
+    return data.data || [];
+}
+
+// Revoke a session: DELETE /user/sessions/:id
+export async function revokeSession(id: string) {
+    await fetchWithAuth(`${API_URL}/user/sessions/${id}`, { method: 'DELETE' });
+    return true;
+}
+
+// Revoke every other session: DELETE /user/sessions?except=current
+export async function revokeOtherSessions() {
+    const data = await fetchWithAuth(`${API_URL}/user/sessions?except=current`, { method: 'DELETE' });
+    return data.data || data;
+}
+
+// Pending extension pairing: GET /device?user_code=
+export async function getPendingDevice(userCode: string) {
+    const data = await fetchWithAuth(`${API_URL}/device?user_code=${encodeURIComponent(userCode)}`);
+    return data.data || data;
+}
+
+// Approve or deny extension pairing: POST /device/approve
+export async function approveDevice(userCode: string, approve: boolean) {
+    await fetchWithAuth(`${API_URL}/device/approve`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ user_code: userCode, approve })
+    });
+    return true;
+}
+
+async function postPublic(path: string, body: object) {
+    const resp = await fetch(`${API_URL}${path}`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(body)
+    });
+    const data = await resp.json().catch(() => ({}));
+    if (!resp.ok) throw new Error(data.message || 'API error');
+    return data;
+}
+
+async function getPublic(path: string) {
+    const resp = await fetch(`${API_URL}${path}`);
+    const data = await resp.json().catch(() => ({}));
+    if (!resp.ok) throw new Error(data.message || 'API error');
+    return data;
+}
+
+// Email verification link: POST /auth/verify-email
+export async function verifyEmail(token: string) {
+    await postPublic('/auth/verify-email', { token });
+    return true;
+}
+
+// Email change confirmation link: POST /auth/confirm-email-change
+export async function confirmEmailChange(token: string) {
+    await postPublic('/auth/confirm-email-change', { token });
+    return true;
+}
+
+// Request a password reset email: POST /auth/forgot-password
+export async function forgotPassword(email: string) {
+    await postPublic('/auth/forgot-password', { email });
+    return true;
+}
+
+// Set a new password from the emailed link: POST /auth/reset-password
+export async function resetPassword(token: string, password: string) {
+    await postPublic('/auth/reset-password', { token, password });
+    return true;
+}
+
+// Two-factor status: GET /user/2fa
+export async function getTwoFactorStatus() {
+    const data = await fetchWithAuth(`${API_URL}/user/2fa`);
+    return data.data || data;
+}
+
+// Start TOTP enrollment: POST /user/2fa/setup
+export async function setupTwoFactor() {
+    const data = await fetchWithAuth(`${API_URL}/user/2fa/setup`, { method: 'POST' });
+    return data.data || data;
+}
+
+// Confirm TOTP enrollment: POST /user/2fa/confirm
+export async function confirmTwoFactor(code: string) {
+    const data = await fetchWithAuth(`${API_URL}/user/2fa/confirm`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ code })
+    });
+    return data.data || data;
+}
+
+// Disable two-factor authentication: POST /user/2fa/disable
+export async function disableTwoFactor(password: string, code: string) {
+    await fetchWithAuth(`${API_URL}/user/2fa/disable`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ password, code })
+    });
+    return true;
+}
+
+// Replace recovery codes: POST /user/2fa/recovery-codes
+export async function regenerateRecoveryCodes(code: string) {
+    const data = await fetchWithAuth(`${API_URL}/user/2fa/recovery-codes`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ code })
+    });
+    return data.data || data;
+}
+
+// Personal access tokens: GET /user/tokens
+export async function getPersonalAccessTokens() {
+    const data = await fetchWithAuth(`${API_URL}/user/tokens`);
+    return data.data || [];
+}
+
+// Create a personal access token: POST /user/tokens
+export async function createPersonalAccessToken(name: string, scopes: string[], expiresInDays: number) {
+    const data = await fetchWithAuth(`${API_URL}/user/tokens`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ name, scopes, expires_in_days: expiresInDays })
+    });
+    return data.data || data;
+}
+
+// Revoke a personal access token: DELETE /user/tokens/:id
+export async function revokePersonalAccessToken(id: string) {
+    await fetchWithAuth(`${API_URL}/user/tokens/${id}`, { method: 'DELETE' });
+    return true;
+}
+
+// Schedule account deletion: DELETE /user
+export async function deleteAccount(password: string, code: string) {
+    const data = await fetchWithAuth(`${API_URL}/user`, {
+        method: 'DELETE',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ password, code: code || undefined })
+    });
+    return data.data || data;
+}
+
+// Security events: GET /user/security-events
+export async function getSecurityEvents(cursor?: string) {
+    const query = cursor ? `?cursor=${encodeURIComponent(cursor)}` : '';
+    const data = await fetchWithAuth(`${API_URL}/user/security-events${query}`);
+    return data.data || { events: [] };
+}
+
+// Single sign-on availability: GET /auth/oidc
+export async function getSSOInfo() {
+    try {
+        const data = await getPublic('/auth/oidc');
+        return data.data || { enabled: false };
+    } catch {
+        return { enabled: false };
+    }
+}
+
+// Start single sign-on: POST /auth/oidc/authorize
+export async function startSSOLogin() {
+    const data = await postPublic('/auth/oidc/authorize', { source: 'web' });
+    return data.data;
+}
+
+// Finish single sign-on: POST /auth/oidc/callback
+export async function ssoCallbackApi(code: string, state: string) {
+    const resp = await fetch(`${API_URL}/auth/oidc/callback`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ code, state })
+    });
+    const data = await resp.json();
+    if (resp.ok && data.data && data.data.two_factor_required) {
+        return { success: false, two_factor_required: true, challenge_token: data.data.challenge_token };
+    }
+    if (resp.ok && data.data) {
+        return {
+            success: true,
+            access_token: data.data.access_token,
+            refresh_token: data.data.refresh_token,
+            user: data.data.user
+        };
+    }
+    return { success: false, message: data.message };
+}
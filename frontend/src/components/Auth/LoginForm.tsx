@@ -1,28 +1,88 @@
-import React, { useState } from 'react';
-import { Box, Button, Input, VStack, Text } from '@chakra-ui/react';
+import React, { useState, useEffect } from 'react';
+import { Box, Button, Input, VStack, Text, Link } from '@chakra-ui/react';
 import { useAuth } from '../../context/AuthContext';
 import { sha256 } from '../../utils/hash';
-import { useNavigate } from 'react-router-dom';
+import { getSSOInfo, startSSOLogin } from '../../api/apiClient';
+import { Link as RouterLink, useNavigate } from 'react-router-dom';
 
 export default function LoginForm() {
-  const { login } = useAuth();
+  const { login, completeTwoFactorLogin } = useAuth();
   const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
+  const [challengeToken, setChallengeToken] = useState('');
+  const [code, setCode] = useState('');
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
+  const [ssoName, setSSOName] = useState('');
   const navigate = useNavigate();
 
+  useEffect(() => {
+    getSSOInfo().then((info: { enabled: boolean; provider_name?: string }) => {
+      if (info.enabled) setSSOName(info.provider_name || 'SSO');
+    });
+  }, []);
+
+  const handleSSO = async () => {
+    setError('');
+    setLoading(true);
+    try {
+      const { authorization_url, state } = await startSSOLogin();
+      // Checked on return so another site cannot complete a login for us
+      sessionStorage.setItem('sso_state', state);
+      window.location.href = authorization_url;
+    } catch (e: any) {
+      setError(e.message || 'Single sign-on is unavailable');
+      setLoading(false);
+    }
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     setLoading(true);
     setError('');
     const hashed = await sha256(password);
-    const success = await login(email, hashed);
-    if (!success) setError('Invalid credentials');
-    else navigate('/dashboard');
+    const result = await login(email, hashed);
+    if (result.success) navigate('/dashboard');
+    else if (result.challengeToken) setChallengeToken(result.challengeToken);
+    else setError(result.message || 'Invalid credentials');
+    setLoading(false);
+  };
+
+  const handleCode = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setLoading(true);
+    setError('');
+    const result = await completeTwoFactorLogin(challengeToken, code.trim());
+    if (result.success) navigate('/dashboard');
+    else setError(result.message || 'Invalid code');
     setLoading(false);
   };
 
+  if (challengeToken) {
+    return (
+      <Box as="form" onSubmit={handleCode}>
+        <VStack spacing={4} align="stretch">
+          <Text>Enter the code from your authenticator app, or one of your recovery codes.</Text>
+          <Input
+            placeholder="123456"
+            value={code}
+            onChange={e => setCode(e.target.value)}
+            autoComplete="one-time-code"
+            autoFocus
+            required
+          />
+          {error && <Text color="red.500">{error}</Text>}
+          <Button type="submit" colorScheme="blue" isLoading={loading} w="full">
+            Verify
+          </Button>
+          <Button variant="link" size="sm" onClick={() => { setChallengeToken(''); setCode(''); setError(''); }}>
+            Back
+          </Button>
+        </VStack>
+      </Box>
+    );
+  }
+
   return (
     <Box as="form" onSubmit={handleSubmit}>
       <VStack spacing={4} align="stretch">
@@ -44,7 +104,15 @@ export default function LoginForm() {
         <Button type="submit" colorScheme="blue" isLoading={loading} w="full">
           Login
         </Button>
+        {ssoName && (
+          <Button variant="outline" onClick={handleSSO} isDisabled={loading} w="full">
+            Sign in with {ssoName}
+          </Button>
+        )}
+        <Link as={RouterLink} to="/reset-password" fontSize="sm" color="blue.500" textAlign="center">
+          Forgot password?
+        </Link>
       </VStack>
     </Box>
   );
-} 
\ No newline at end of file
+}
@@ -0,0 +1,46 @@
+import React, { useState } from 'react';
+import { Box, Button, Input, VStack, Text, Alert, AlertIcon } from '@chakra-ui/react';
+import { deleteAccount } from '../api/apiClient';
+import { sha256 } from '../utils/hash';
+import { useAuth } from '../context/AuthContext';
+
+export default function DeleteAccount() {
+  const { logout } = useAuth();
+  const [password, setPassword] = useState('');
+  const [code, setCode] = useState('');
+  const [error, setError] = useState('');
+  const [loading, setLoading] = useState(false);
+
+  const handleDelete = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!window.confirm('Delete your account and all of your notes? You can cancel by logging in again during the grace period.')) return;
+    setError('');
+    setLoading(true);
+    try {
+      const result = await deleteAccount(await sha256(password), code.trim());
+      alert(`Your account will be deleted on ${new Date(result.deletion_scheduled_at).toLocaleString()}. Log in before then to cancel.`);
+      // Every session was signed out, including this one
+      await logout();
+    } catch (e: any) {
+      setError(e.message || 'Failed to delete account');
+      setLoading(false);
+    }
+  };
+
+  return (
+    <Box bg="white" borderRadius="md" boxShadow="sm" p={6} maxW="400px" mt={6} borderColor="red.200" borderWidth="1px">
+      <Text fontWeight="bold" mb={2}>Delete Account</Text>
+      <Text fontSize="sm" color="gray.600" mb={4}>
+        Your account, notes and summaries are permanently deleted after a grace period. Logging in before then cancels the deletion.
+      </Text>
+      <form onSubmit={handleDelete}>
+        <VStack spacing={3} align="stretch">
+          <Input placeholder="Password" type="password" value={password} onChange={e => setPassword(e.target.value)} required />
+          <Input placeholder="Two-factor code (if enabled)" value={code} onChange={e => setCode(e.target.value)} />
+          {error && <Alert status="error"><AlertIcon />{error}</Alert>}
+          <Button type="submit" colorScheme="red" isLoading={loading}>Delete my account</Button>
+        </VStack>
+      </form>
+    </Box>
+  );
+}
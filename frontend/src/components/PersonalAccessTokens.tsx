@@ -0,0 +1,111 @@
+import React, { useState, useEffect } from 'react';
+import { Box, Button, Input, VStack, HStack, Text, Alert, AlertIcon, Badge, Code, Checkbox, Select } from '@chakra-ui/react';
+import { getPersonalAccessTokens, createPersonalAccessToken, revokePersonalAccessToken } from '../api/apiClient';
+
+interface PersonalAccessToken {
+  id: string;
+  name: string;
+  prefix: string;
+  scopes: string[];
+  expires_at: string;
+  last_used_at?: string;
+}
+
+const SCOPES = ['notes:read', 'notes:write', 'summaries:write'];
+
+export default function PersonalAccessTokens() {
+  const [tokens, setTokens] = useState<PersonalAccessToken[]>([]);
+  const [name, setName] = useState('');
+  const [scopes, setScopes] = useState<string[]>(['notes:read']);
+  const [expiresInDays, setExpiresInDays] = useState(30);
+  const [newToken, setNewToken] = useState('');
+  const [error, setError] = useState('');
+  const [loading, setLoading] = useState(false);
+
+  const loadTokens = () => {
+    getPersonalAccessTokens()
+      .then((data: PersonalAccessToken[]) => setTokens(data))
+      .catch((e: Error) => setError(e.message || 'Failed to load tokens'));
+  };
+
+  useEffect(loadTokens, []);
+
+  const toggleScope = (scope: string) => {
+    setScopes(scopes.includes(scope) ? scopes.filter(s => s !== scope) : [...scopes, scope]);
+  };
+
+  const handleCreate = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError('');
+    setLoading(true);
+    try {
+      const created = await createPersonalAccessToken(name, scopes, expiresInDays);
+      setNewToken(created.token);
+      setName('');
+      loadTokens();
+    } catch (e: any) {
+      setError(e.message || 'Failed to create token');
+    }
+    setLoading(false);
+  };
+
+  const handleRevoke = async (id: string) => {
+    setError('');
+    try {
+      await revokePersonalAccessToken(id);
+      loadTokens();
+    } catch (e: any) {
+      setError(e.message || 'Failed to revoke token');
+    }
+  };
+
+  return (
+    <Box bg="white" borderRadius="md" boxShadow="sm" p={6} maxW="600px" mt={6}>
+      <Text fontWeight="bold" mb={4}>Personal Access Tokens</Text>
+      <VStack spacing={3} align="stretch">
+        {newToken && (
+          <Alert status="warning" flexDirection="column" alignItems="start">
+            <Text mb={2}>Copy this token now. It will not be shown again.</Text>
+            <Code p={2} wordBreak="break-all">{newToken}</Code>
+          </Alert>
+        )}
+        <form onSubmit={handleCreate}>
+          <VStack spacing={3} align="stretch">
+            <Input placeholder="Token name, e.g. export script" value={name} onChange={e => setName(e.target.value)} required />
+            <HStack>
+              {SCOPES.map(scope => (
+                <Checkbox key={scope} isChecked={scopes.includes(scope)} onChange={() => toggleScope(scope)}>{scope}</Checkbox>
+              ))}
+            </HStack>
+            <Select value={expiresInDays} onChange={e => setExpiresInDays(Number(e.target.value))}>
+              <option value={7}>Expires in 7 days</option>
+              <option value={30}>Expires in 30 days</option>
+              <option value={90}>Expires in 90 days</option>
+              <option value={365}>Expires in 1 year</option>
+            </Select>
+            <Button type="submit" colorScheme="blue" isLoading={loading} isDisabled={scopes.length === 0}>Create token</Button>
+          </VStack>
+        </form>
+        {tokens.map(token => (
+          <HStack key={token.id} justify="space-between" borderWidth="1px" borderRadius="md" p={3}>
+            <Box>
+              <HStack>
+                <Text fontWeight="medium">{token.name}</Text>
+                <Code fontSize="xs">{token.prefix}…</Code>
+              </HStack>
+              <HStack mt={1}>
+                {token.scopes.map(scope => <Badge key={scope}>{scope}</Badge>)}
+              </HStack>
+              <Text fontSize="sm" color="gray.500">
+                Expires {new Date(token.expires_at).toLocaleDateString()}
+                {token.last_used_at ? ` · Last used ${new Date(token.last_used_at).toLocaleString()}` : ' · Never used'}
+              </Text>
+            </Box>
+            <Button size="sm" colorScheme="red" variant="ghost" onClick={() => handleRevoke(token.id)}>Revoke</Button>
+          </HStack>
+        ))}
+        {error && <Alert status="error"><AlertIcon />{error}</Alert>}
+      </VStack>
+    </Box>
+  );
+}
@@ -0,0 +1,83 @@
+import { useState, useEffect } from 'react';
+import { Box, Button, VStack, HStack, Text, Alert, AlertIcon, Badge } from '@chakra-ui/react';
+import { getSecurityEvents } from '../api/apiClient';
+
+interface SecurityEvent {
+  id: string;
+  event: string;
+  outcome: 'success' | 'failure';
+  source?: string;
+  ip_address?: string;
+  user_agent?: string;
+  created_at: string;
+}
+
+const EVENT_LABELS: Record<string, string> = {
+  register: 'Account created',
+  login: 'Sign-in',
+  token_refresh: 'Session refreshed',
+  logout: 'Sign-out',
+  password_changed: 'Password changed',
+  password_reset: 'Password reset',
+  email_change_requested: 'Email change requested',
+  email_changed: 'Email changed',
+  refresh_token_reuse: 'Session token reused',
+  two_factor_enabled: 'Two-factor enabled',
+  two_factor_disabled: 'Two-factor disabled',
+  recovery_code_used: 'Recovery code used',
+  recovery_codes_regenerated: 'Recovery codes regenerated',
+  identity_linked: 'Single sign-on linked',
+  account_locked: 'Sign-in locked',
+  account_deletion_requested: 'Account deletion requested',
+  account_deletion_cancelled: 'Account deletion cancelled',
+  role_changed: 'Role changed by an admin',
+  account_disabled: 'Account disabled by an admin',
+  account_enabled: 'Account enabled by an admin',
+  sessions_revoked: 'Signed out by an admin',
+};
+
+export default function SecurityActivity() {
+  const [events, setEvents] = useState<SecurityEvent[]>([]);
+  const [cursor, setCursor] = useState<string | undefined>();
+  const [error, setError] = useState('');
+  const [loading, setLoading] = useState(false);
+
+  const loadEvents = (next?: string) => {
+    setLoading(true);
+    getSecurityEvents(next)
+      .then((page: { events: SecurityEvent[], next_cursor?: string }) => {
+        setEvents(next ? [...events, ...page.events] : page.events);
+        setCursor(page.next_cursor);
+      })
+      .catch((e: Error) => setError(e.message || 'Failed to load security activity'))
+      .finally(() => setLoading(false));
+  };
+
+  useEffect(() => loadEvents(), []);
+
+  return (
+    <Box bg="white" borderRadius="md" boxShadow="sm" p={6} maxW="600px" mt={6}>
+      <Text fontWeight="bold" mb={4}>Security Activity</Text>
+      <VStack spacing={2} align="stretch">
+        {events.map(event => (
+          <HStack key={event.id} justify="space-between" borderWidth="1px" borderRadius="md" p={3}>
+            <Box>
+              <HStack>
+                <Text fontWeight="medium">{EVENT_LABELS[event.event] || event.event}</Text>
+                {event.outcome === 'failure' && <Badge colorScheme="red">Failed</Badge>}
+                {event.source && <Badge>{event.source}</Badge>}
+              </HStack>
+              <Text fontSize="sm" color="gray.500">
+                {new Date(event.created_at).toLocaleString()}
+                {event.ip_address ? ` · ${event.ip_address}` : ''}
+              </Text>
+            </Box>
+          </HStack>
+        ))}
+        {!loading && events.length === 0 && !error && <Text color="gray.500">No activity yet.</Text>}
+        {cursor && <Button size="sm" variant="ghost" isLoading={loading} onClick={() => loadEvents(cursor)}>Load more</Button>}
+        {error && <Alert status="error"><AlertIcon />{error}</Alert>}
+      </VStack>
+    </Box>
+  );
+}
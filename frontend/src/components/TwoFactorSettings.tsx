@@ -0,0 +1,122 @@
+import React, { useState, useEffect } from 'react';
+import { Box, Button, Input, VStack, HStack, Text, Alert, AlertIcon, Badge, Code, SimpleGrid } from '@chakra-ui/react';
+import { getTwoFactorStatus, setupTwoFactor, confirmTwoFactor, disableTwoFactor, regenerateRecoveryCodes } from '../api/apiClient';
+import { sha256 } from '../utils/hash';
+
+interface TwoFactorStatus {
+  enabled: boolean;
+  recovery_codes_remaining: number;
+}
+
+interface TwoFactorSetup {
+  secret: string;
+  otpauth_uri: string;
+}
+
+export default function TwoFactorSettings() {
+  const [status, setStatus] = useState<TwoFactorStatus | null>(null);
+  const [setup, setSetup] = useState<TwoFactorSetup | null>(null);
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([]);
+  const [code, setCode] = useState('');
+  const [password, setPassword] = useState('');
+  const [error, setError] = useState('');
+  const [loading, setLoading] = useState(false);
+
+  const loadStatus = () => {
+    getTwoFactorStatus()
+      .then((data: TwoFactorStatus) => setStatus(data))
+      .catch((e: Error) => setError(e.message || 'Failed to load two-factor status'));
+  };
+
+  useEffect(loadStatus, []);
+
+  const run = async (action: () => Promise<void>) => {
+    setError('');
+    setLoading(true);
+    try {
+      await action();
+    } catch (e: any) {
+      setError(e.message || 'Request failed');
+    }
+    setLoading(false);
+  };
+
+  const handleSetup = () => run(async () => {
+    setSetup(await setupTwoFactor());
+    setRecoveryCodes([]);
+  });
+
+  const handleConfirm = (e: React.FormEvent) => {
+    e.preventDefault();
+    run(async () => {
+      const data = await confirmTwoFactor(code.trim());
+      setRecoveryCodes(data.recovery_codes);
+      setSetup(null);
+      setCode('');
+      loadStatus();
+    });
+  };
+
+  const handleRegenerate = (e: React.FormEvent) => {
+    e.preventDefault();
+    run(async () => {
+      const data = await regenerateRecoveryCodes(code.trim());
+      setRecoveryCodes(data.recovery_codes);
+      setCode('');
+      loadStatus();
+    });
+  };
+
+  const handleDisable = () => run(async () => {
+    await disableTwoFactor(await sha256(password), code.trim());
+    setRecoveryCodes([]);
+    setPassword('');
+    setCode('');
+    loadStatus();
+  });
+
+  return (
+    <Box bg="white" borderRadius="md" boxShadow="sm" p={6} maxW="600px" mt={6}>
+      <HStack mb={4}>
+        <Text fontWeight="bold">Two-Factor Authentication</Text>
+        {status && <Badge colorScheme={status.enabled ? 'green' : 'gray'}>{status.enabled ? 'On' : 'Off'}</Badge>}
+      </HStack>
+      <VStack spacing={3} align="stretch">
+        {recoveryCodes.length > 0 && (
+          <Alert status="warning" flexDirection="column" alignItems="start">
+            <Text mb={2}>Save these recovery codes somewhere safe. Each works once and they will not be shown again.</Text>
+            <SimpleGrid columns={2} spacing={1}>
+              {recoveryCodes.map(c => <Code key={c}>{c}</Code>)}
+            </SimpleGrid>
+          </Alert>
+        )}
+        {status && !status.enabled && !setup && (
+          <Button colorScheme="blue" onClick={handleSetup} isLoading={loading}>Set up authenticator app</Button>
+        )}
+        {setup && (
+          <form onSubmit={handleConfirm}>
+            <VStack spacing={3} align="stretch">
+              <Text>Add this key to your authenticator app, then enter the code it shows.</Text>
+              <Code p={2} wordBreak="break-all">{setup.secret}</Code>
+              <Text fontSize="sm" color="gray.500" wordBreak="break-all">{setup.otpauth_uri}</Text>
+              <Input placeholder="123456" value={code} onChange={e => setCode(e.target.value)} autoComplete="one-time-code" />
+              <Button type="submit" colorScheme="blue" isLoading={loading}>Enable</Button>
+            </VStack>
+          </form>
+        )}
+        {status && status.enabled && (
+          <>
+            <Text fontSize="sm" color="gray.500">{status.recovery_codes_remaining} recovery codes left</Text>
+            <Input placeholder="Authenticator code" value={code} onChange={e => setCode(e.target.value)} autoComplete="one-time-code" />
+            <form onSubmit={handleRegenerate}>
+              <Button type="submit" variant="outline" w="full" isLoading={loading}>New recovery codes</Button>
+            </form>
+            <Input placeholder="Password" type="password" value={password} onChange={e => setPassword(e.target.value)} />
+            <Button colorScheme="red" variant="outline" onClick={handleDisable} isLoading={loading}>Turn off</Button>
+          </>
+        )}
+        {error && <Alert status="error"><AlertIcon />{error}</Alert>}
+      </VStack>
+    </Box>
+  );
+}
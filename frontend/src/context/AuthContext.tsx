@@ -1,12 +1,20 @@
 import React, { createContext, useContext, useState, useEffect } from 'react';
 import { useNavigate } from 'react-router-dom';
-import { loginApi, refreshTokenApi, logoutApi, getUserProfile } from '../api/apiClient';
+import { loginApi, loginTwoFactorApi, ssoCallbackApi, refreshTokenApi, logoutApi, getUserProfile } from '../api/apiClient';
+
+export interface LoginResult {
+  success: boolean;
+  challengeToken?: string; // Set when a two-factor code is needed to finish
+  message?: string;
+}
 
 interface AuthContextType {
   isAuthenticated: boolean;
   loading: boolean;
   user: any;
-  login: (email: string, password: string) => Promise<boolean>;
+  login: (email: string, password: string) => Promise<LoginResult>;
+  completeTwoFactorLogin: (challengeToken: string, code: string) => Promise<LoginResult>;
+  completeSSOLogin: (code: string, state: string) => Promise<LoginResult>;
   logout: () => Promise<void>;
 }
 
@@ -14,7 +22,9 @@ const AuthContext = createContext<AuthContextType>({
   isAuthenticated: false,
   loading: true,
   user: null,
-  login: async () => false,
+  login: async () => ({ success: false }),
+  completeTwoFactorLogin: async () => ({ success: false }),
+  completeSSOLogin: async () => ({ success: false }),
   logout: async () => {},
 });
 
@@ -50,16 +60,44 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     checkAuth();
   }, []);
 
-  const login = async (email: string, password: string) => {
+  const storeSession = (result: { access_token: string; refresh_token: string; user: any }) => {
+    localStorage.setItem('access_token', result.access_token);
+    localStorage.setItem('refresh_token', result.refresh_token);
+    setIsAuthenticated(true);
+    setUser(result.user);
+  };
+
+  const login = async (email: string, password: string): Promise<LoginResult> => {
     const result = await loginApi(email, password);
     if (result.success) {
-      localStorage.setItem('access_token', result.access_token);
-      localStorage.setItem('refresh_token', result.refresh_token);
-      setIsAuthenticated(true);
-      setUser(result.user);
-      return true;
+      storeSession(result);
+      return { success: true };
+    }
+    if (result.two_factor_required) {
+      return { success: false, challengeToken: result.challenge_token };
+    }
+    return { success: false, message: result.message };
+  };
+
+  const completeSSOLogin = async (code: string, state: string): Promise<LoginResult> => {
+    const result = await ssoCallbackApi(code, state);
+    if (result.success) {
+      storeSession(result);
+      return { success: true };
+    }
+    if (result.two_factor_required) {
+      return { success: false, challengeToken: result.challenge_token };
+    }
+    return { success: false, message: result.message };
+  };
+
+  const completeTwoFactorLogin = async (challengeToken: string, code: string): Promise<LoginResult> => {
+    const result = await loginTwoFactorApi(challengeToken, code);
+    if (result.success) {
+      storeSession(result);
+      return { success: true };
     }
-    return false;
+    return { success: false, message: result.message };
   };
 
   const logout = async () => {
@@ -78,7 +116,7 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
   };
 
   return (
-    <AuthContext.Provider value={{ isAuthenticated, loading, user, login, logout }}>
+    <AuthContext.Provider value={{ isAuthenticated, loading, user, login, completeTwoFactorLogin, completeSSOLogin, logout }}>
       {children}
     </AuthContext.Provider>
   );
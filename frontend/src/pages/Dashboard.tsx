@@ -1,10 +1,11 @@
 import React, { useEffect, useState } from 'react';
-import { Box, Heading, SimpleGrid, Stat, StatLabel, StatNumber, Table, Thead, Tbody, Tr, Th, Td, IconButton, Text, Spinner, Alert, AlertIcon } from '@chakra-ui/react';
+import { Box, Heading, SimpleGrid, Stat, StatLabel, StatNumber, Table, Thead, Tbody, Tr, Th, Td, IconButton, Text, Spinner, Alert, AlertIcon, Tag, Wrap, WrapItem } from '@chakra-ui/react';
 import { FaVolumeUp, FaPause } from 'react-icons/fa';
 import { getNotesStats, getNotes } from '../api/apiClient';
 
 export default function Dashboard() {
   const [stats, setStats] = useState<{ domain: string; count: number }[]>([]);
+  const [tagStats, setTagStats] = useState<{ id: string; name: string; color?: string; count: number }[]>([]);
   const [mostRecent, setMostRecent] = useState<any>(null);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState('');
@@ -15,7 +16,8 @@ export default function Dashboard() {
       setError('');
       try {
         const statsData = await getNotesStats();
-        setStats(statsData);
+        setStats(statsData.domains);
+        setTagStats(statsData.tags);
         const notes = await getNotes({ page: 1, page_size: 1 });
         setMostRecent(notes[0] || null);
       } catch (e: any) {
@@ -90,6 +92,20 @@ export default function Dashboard() {
             </Table>
             {stats.length === 0 && <Text mt={4}>No notes found.</Text>}
           </Box>
+          {tagStats.length > 0 && (
+            <Box bg="white" borderRadius="md" boxShadow="sm" p={6} mt={8}>
+              <Text fontWeight="bold" mb={4}>Tags</Text>
+              <Wrap>
+                {tagStats.map(tag => (
+                  <WrapItem key={tag.id}>
+                    <Tag bg={tag.color || undefined} color={tag.color ? 'white' : undefined}>
+                      {tag.name} · {tag.count}
+                    </Tag>
+                  </WrapItem>
+                ))}
+              </Wrap>
+            </Box>
+          )}
         </>
       )}
     </Box>
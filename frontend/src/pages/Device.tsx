@@ -0,0 +1,99 @@
+import React, { useState, useEffect } from 'react';
+import { useSearchParams } from 'react-router-dom';
+import { Box, Heading, Input, Button, VStack, HStack, Text, Alert, AlertIcon } from '@chakra-ui/react';
+import { getPendingDevice, approveDevice } from '../api/apiClient';
+
+interface PendingDevice {
+  user_code: string;
+  device_info?: string;
+  ip_address?: string;
+  created_at: string;
+}
+
+export default function Device() {
+  const [searchParams] = useSearchParams();
+  const [code, setCode] = useState(searchParams.get('code') || '');
+  const [device, setDevice] = useState<PendingDevice | null>(null);
+  const [message, setMessage] = useState('');
+  const [error, setError] = useState('');
+  const [loading, setLoading] = useState(false);
+
+  const lookup = async (userCode: string) => {
+    setMessage('');
+    setError('');
+    setDevice(null);
+    setLoading(true);
+    try {
+      setDevice(await getPendingDevice(userCode));
+    } catch (e: any) {
+      setError(e.message || 'Code not found or expired');
+    }
+    setLoading(false);
+  };
+
+  useEffect(() => {
+    if (code) lookup(code);
+    // Only look up the code from the link once
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, []);
+
+  const handleLookup = (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    if (code.trim()) lookup(code.trim());
+  };
+
+  const handleDecision = async (approve: boolean) => {
+    if (!device) return;
+    setError('');
+    setLoading(true);
+    try {
+      await approveDevice(device.user_code, approve);
+      setMessage(approve
+        ? 'Extension connected. You can return to the extension.'
+        : 'Request denied. The extension was not signed in.');
+      setDevice(null);
+      setCode('');
+    } catch (e: any) {
+      setError(e.message || 'Failed to update request');
+    }
+    setLoading(false);
+  };
+
+  return (
+    <Box>
+      <Heading size="lg" mb={6}>Connect Extension</Heading>
+      <Box bg="white" borderRadius="md" boxShadow="sm" p={6} maxW="400px">
+        <VStack spacing={4} align="stretch">
+          <form onSubmit={handleLookup}>
+            <VStack spacing={3} align="stretch">
+              <Text fontWeight="bold">Enter the code shown in the extension</Text>
+              <Input
+                placeholder="XXXX-XXXX"
+                value={code}
+                onChange={e => setCode(e.target.value.toUpperCase())}
+                textTransform="uppercase"
+                letterSpacing="widest"
+              />
+              <Button type="submit" colorScheme="blue" isLoading={loading && !device}>Continue</Button>
+            </VStack>
+          </form>
+          {device && (
+            <VStack spacing={3} align="stretch" borderWidth="1px" borderRadius="md" p={4}>
+              <Text>
+                Sign in <b>{device.device_info || 'the extension'}</b>
+                {device.ip_address ? ` (${device.ip_address})` : ''} to your account?
+              </Text>
+              <Text fontSize="sm" color="gray.500">Only approve if you just requested this code.</Text>
+              <HStack>
+                <Button colorScheme="green" onClick={() => handleDecision(true)} isLoading={loading}>Approve</Button>
+                <Button variant="outline" colorScheme="red" onClick={() => handleDecision(false)} isDisabled={loading}>Deny</Button>
+              </HStack>
+            </VStack>
+          )}
+          {message && <Alert status="success"><AlertIcon />{message}</Alert>}
+          {error && <Alert status="error"><AlertIcon />{error}</Alert>}
+        </VStack>
+      </Box>
+    </Box>
+  );
+}
@@ -0,0 +1,43 @@
+import React, { useEffect, useState } from 'react';
+import { Link as RouterLink, useSearchParams } from 'react-router-dom';
+import { Box, Heading, Text, Spinner, Alert, AlertIcon, Link } from '@chakra-ui/react';
+import { verifyEmail, confirmEmailChange } from '../api/apiClient';
+
+interface EmailLinkProps {
+  action: 'verify' | 'change';
+}
+
+// Handles the links sent for email verification and email change confirmation
+export default function EmailLink({ action }: EmailLinkProps) {
+  const [searchParams] = useSearchParams();
+  const [status, setStatus] = useState<'loading' | 'success' | 'error'>('loading');
+  const [error, setError] = useState('');
+
+  useEffect(() => {
+    const token = searchParams.get('token') || '';
+    const confirm = action === 'verify' ? verifyEmail : confirmEmailChange;
+    confirm(token)
+      .then(() => setStatus('success'))
+      .catch((e: any) => {
+        setError(e.message || 'Invalid or expired link');
+        setStatus('error');
+      });
+    // Tokens are single use, so only submit once
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, []);
+
+  return (
+    <Box maxW="400px" mx="auto" mt={16} p={8} bg="white" borderRadius="md" boxShadow="sm">
+      <Heading size="lg" mb={4}>{action === 'verify' ? 'Verify Email' : 'Confirm Email'}</Heading>
+      {status === 'loading' && <Spinner />}
+      {status === 'success' && (
+        <Alert status="success">
+          <AlertIcon />
+          {action === 'verify' ? 'Your email address is verified.' : 'Your email address has been changed.'}
+        </Alert>
+      )}
+      {status === 'error' && <Alert status="error"><AlertIcon />{error}</Alert>}
+      <Text mt={4}><Link as={RouterLink} to="/" color="blue.500">Back to sign in</Link></Text>
+    </Box>
+  );
+}
@@ -16,10 +16,11 @@ import {
   Button,
   HStack,
   Badge,
+  Input,
   useDisclosure
 } from '@chakra-ui/react';
 import { FaEye, FaTrash, FaFileAlt, FaCopy } from 'react-icons/fa';
-import { getNotes, deleteNote, generateSummary } from '../api/apiClient';
+import { getNotesPage, searchNotes, deleteNote, generateSummary } from '../api/apiClient';
 import { Select } from '@chakra-ui/react';
 import NoteModal from '../components/NoteModal';
 
@@ -30,6 +31,12 @@ export default function Notes() {
   const [page, setPage] = useState(1);
   const [pageSize, setPageSize] = useState(10);
   const [hasMore, setHasMore] = useState(false);
+  const [total, setTotal] = useState(0);
+  const [cursor, setCursor] = useState<string | undefined>();
+  const [cursors, setCursors] = useState<{ next?: string, prev?: string }>({});
+  const [searchInput, setSearchInput] = useState('');
+  const [query, setQuery] = useState('');
+  const [tagFilter, setTagFilter] = useState('');
   const [generatingSummaries, setGeneratingSummaries] = useState<Set<string>>(new Set());
   const [selectedNoteId, setSelectedNoteId] = useState<string>('');
   const { isOpen, onOpen, onClose } = useDisclosure();
@@ -39,19 +46,51 @@ export default function Notes() {
       setLoading(true);
       setError('');
       try {
-        const data = await getNotes({ page, page_size: pageSize });
-        setNotes(data);
-        setHasMore(data.length === pageSize);
+        if (query) {
+          const data = await searchNotes({ q: query, page, page_size: pageSize });
+          setNotes(data.results);
+          setTotal(data.total);
+          setHasMore(page * pageSize < data.total);
+        } else {
+          const { notes, meta } = await getNotesPage({ cursor, page_size: pageSize, include_total: true, tags: tagFilter ? [tagFilter] : undefined });
+          setNotes(notes);
+          setTotal(meta.total ?? 0);
+          setCursors({ next: meta.next_cursor, prev: meta.prev_cursor });
+          setHasMore(!!meta.next_cursor);
+        }
       } catch (e: any) {
         setError(e.message || 'Failed to load notes');
       }
       setLoading(false);
     }
     fetchNotes();
-  }, [page, pageSize]);
+  }, [page, pageSize, query, tagFilter, cursor]);
+
+  // Search pages by number; the notes list follows the cursors it was given
+  const goToPage = (next: boolean) => {
+    if (!query) setCursor(next ? cursors.next : cursors.prev);
+    setPage(next ? page + 1 : page - 1);
+  };
+
+  const resetPaging = () => {
+    setCursor(undefined);
+    setPage(1);
+  };
+
+  const handleSearch = (e: React.FormEvent) => {
+    e.preventDefault();
+    setQuery(searchInput.trim());
+    resetPaging();
+  };
+
+  const clearSearch = () => {
+    setSearchInput('');
+    setQuery('');
+    resetPaging();
+  };
 
   const handleDelete = async (id: string) => {
-    if (!window.confirm('Delete this note?')) return;
+    if (!window.confirm('Move this note to the trash?')) return;
     try {
       await deleteNote(id);
       setNotes(notes.filter(n => n.id !== id));
@@ -107,9 +146,37 @@ export default function Notes() {
     return text.substring(0, maxLength) + '...';
   };
 
+  // Search snippets wrap matches in <mark>; everything else is note text and
+  // is rendered as text, never as HTML
+  const renderSnippet = (snippet: string) =>
+    snippet.split(/(<mark>.*?<\/mark>)/).map((part, i) =>
+      part.startsWith('<mark>') && part.endsWith('</mark>')
+        ? <Text as="mark" key={i}>{part.slice(6, -7)}</Text>
+        : part
+    );
+
   return (
     <Box>
       <Heading size="lg" mb={6}>Notes</Heading>
+      <form onSubmit={handleSearch}>
+        <HStack mb={4}>
+          <Input
+            bg="white"
+            placeholder='Search notes, e.g. "active recall" neuro* -draft'
+            value={searchInput}
+            onChange={e => setSearchInput(e.target.value)}
+          />
+          <Button type="submit" colorScheme="blue">Search</Button>
+          {query && <Button onClick={clearSearch}>Clear</Button>}
+        </HStack>
+      </form>
+      {tagFilter && !query && (
+        <HStack mb={4}>
+          <Text>Tagged</Text>
+          <Badge colorScheme="purple">{tagFilter}</Badge>
+          <Button size="xs" onClick={() => { setTagFilter(''); resetPaging(); }}>Show all</Button>
+        </HStack>
+      )}
       {loading && <Spinner size="lg" />}
       {error && <Alert status="error" mb={4}><AlertIcon />{error}</Alert>}
       {!loading && !error && (
@@ -132,8 +199,24 @@ export default function Notes() {
                   </Td>
                   <Td>
                     <Text fontSize="sm">
-                      {truncateText(note.content, 50)}
+                      {note.snippet ? renderSnippet(note.snippet) : truncateText(note.content, 50)}
                     </Text>
+                    {note.tags?.length > 0 && (
+                      <HStack spacing={1} mt={1} wrap="wrap">
+                        {note.tags.map((tag: any) => (
+                          <Badge
+                            key={tag.id}
+                            cursor="pointer"
+                            bg={tag.color || undefined}
+                            color={tag.color ? 'white' : undefined}
+                            colorScheme={tag.color ? undefined : 'purple'}
+                            onClick={() => { setQuery(''); setSearchInput(''); setTagFilter(tag.name); resetPaging(); }}
+                          >
+                            {tag.name}
+                          </Badge>
+                        ))}
+                      </HStack>
+                    )}
                   </Td>
                   <Td>
                     <HStack spacing={1}>
@@ -173,16 +256,16 @@ export default function Notes() {
               ))}
             </Tbody>
           </Table>
-          {notes.length === 0 && <Text mt={4}>No notes found.</Text>}
+          {notes.length === 0 && <Text mt={4}>{query ? 'No notes match your search.' : 'No notes found.'}</Text>}
           
           {/* Pagination controls */}
           <HStack mt={4} justify="space-between">
             <HStack>
-              <Button onClick={() => setPage(page - 1)} isDisabled={page === 1}>Previous</Button>
-              <Text>Page {page}</Text>
-              <Button onClick={() => setPage(page + 1)} isDisabled={!hasMore}>Next</Button>
+              <Button onClick={() => goToPage(false)} isDisabled={page === 1}>Previous</Button>
+              <Text>Page {page} of {Math.max(1, Math.ceil(total / pageSize))}</Text>
+              <Button onClick={() => goToPage(true)} isDisabled={!hasMore}>Next</Button>
             </HStack>
-            <Select value={pageSize} onChange={e => { setPageSize(Number(e.target.value)); setPage(1); }} width="auto">
+            <Select value={pageSize} onChange={e => { setPageSize(Number(e.target.value)); resetPaging(); }} width="auto">
               <option value={10}>10</option>
               <option value={25}>25</option>
               <option value={50}>50</option>
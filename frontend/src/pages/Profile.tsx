@@ -1,8 +1,22 @@
 import React, { useState, useEffect } from 'react';
-import { Box, Heading, Input, Button, VStack, Text, Alert, AlertIcon, Spinner } from '@chakra-ui/react';
-import { updatePassword, getUserProfile } from '../api/apiClient';
+import { Box, Heading, Input, Button, VStack, HStack, Text, Alert, AlertIcon, Spinner, Badge } from '@chakra-ui/react';
+import { updatePassword, getUserProfile, getSessions, revokeSession, revokeOtherSessions } from '../api/apiClient';
 import { sha256 } from '../utils/hash';
 import { useAuth } from '../context/AuthContext';
+import TwoFactorSettings from '../components/TwoFactorSettings';
+import PersonalAccessTokens from '../components/PersonalAccessTokens';
+import SecurityActivity from '../components/SecurityActivity';
+import DeleteAccount from '../components/DeleteAccount';
+
+interface Session {
+  id: string;
+  source: string;
+  device_info?: string;
+  label?: string;
+  ip_address?: string;
+  last_used_at: string;
+  current: boolean;
+}
 
 export default function Profile() {
   const { user } = useAuth();
@@ -15,6 +29,8 @@ export default function Profile() {
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
   const [profileLoading, setProfileLoading] = useState(!user);
+  const [sessions, setSessions] = useState<Session[]>([]);
+  const [sessionsError, setSessionsError] = useState('');
 
   useEffect(() => {
     if (!user) {
@@ -30,6 +46,34 @@ export default function Profile() {
     }
   }, [user]);
 
+  const loadSessions = () => {
+    getSessions()
+      .then((data: Session[]) => setSessions(data))
+      .catch((e: Error) => setSessionsError(e.message || 'Failed to load sessions'));
+  };
+
+  useEffect(loadSessions, []);
+
+  const handleRevoke = async (id: string) => {
+    setSessionsError('');
+    try {
+      await revokeSession(id);
+      loadSessions();
+    } catch (e: any) {
+      setSessionsError(e.message || 'Failed to revoke session');
+    }
+  };
+
+  const handleRevokeOthers = async () => {
+    setSessionsError('');
+    try {
+      await revokeOtherSessions();
+      loadSessions();
+    } catch (e: any) {
+      setSessionsError(e.message || 'Failed to revoke sessions');
+    }
+  };
+
   const handlePasswordUpdate = async (e: React.FormEvent<HTMLFormElement>) => {
     e.preventDefault();
     setMessage('');
@@ -43,10 +87,11 @@ export default function Profile() {
       const oldHash = await sha256(oldPassword);
       const newHash = await sha256(newPassword);
       await updatePassword(oldHash, newHash);
-      setMessage('Password updated!');
+      setMessage('Password updated! Other sessions have been signed out.');
       setOldPassword('');
       setNewPassword('');
       setConfirmPassword('');
+      loadSessions();
     } catch (e: any) {
       setError(e.message || 'Failed to update password');
     }
@@ -92,6 +137,36 @@ export default function Profile() {
           </form>
         </VStack>
       </Box>
+      <Box bg="white" borderRadius="md" boxShadow="sm" p={6} maxW="600px" mt={6}>
+        <HStack justify="space-between" mb={4}>
+          <Text fontWeight="bold">Active Sessions</Text>
+          <Button size="sm" variant="outline" onClick={handleRevokeOthers}>Sign out other sessions</Button>
+        </HStack>
+        {sessionsError && <Alert status="error" mb={3}><AlertIcon />{sessionsError}</Alert>}
+        <VStack spacing={3} align="stretch">
+          {sessions.map(session => (
+            <HStack key={session.id} justify="space-between" borderWidth="1px" borderRadius="md" p={3}>
+              <Box>
+                <HStack>
+                  <Text fontWeight="medium">{session.label || session.device_info || 'Unknown device'}</Text>
+                  <Badge colorScheme={session.source === 'extension' ? 'purple' : 'blue'}>{session.source}</Badge>
+                  {session.current && <Badge colorScheme="green">This device</Badge>}
+                </HStack>
+                <Text fontSize="sm" color="gray.500">
+                  {session.ip_address ? `${session.ip_address} · ` : ''}Last active {new Date(session.last_used_at).toLocaleString()}
+                </Text>
+              </Box>
+              {!session.current && (
+                <Button size="sm" colorScheme="red" variant="ghost" onClick={() => handleRevoke(session.id)}>Revoke</Button>
+              )}
+            </HStack>
+          ))}
+        </VStack>
+      </Box>
+      <TwoFactorSettings />
+      <PersonalAccessTokens />
+      <SecurityActivity />
+      <DeleteAccount />
     </Box>
   );
 } 
\ No newline at end of file
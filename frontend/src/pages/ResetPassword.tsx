@@ -0,0 +1,74 @@
+import React, { useState } from 'react';
+import { Link as RouterLink, useSearchParams } from 'react-router-dom';
+import { Box, Heading, Input, Button, VStack, Text, Alert, AlertIcon, Link } from '@chakra-ui/react';
+import { forgotPassword, resetPassword } from '../api/apiClient';
+import { sha256 } from '../utils/hash';
+
+// Without a token this asks for an email to send a reset link to; with the
+// token from that link it sets the new password.
+export default function ResetPassword() {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token');
+  const [email, setEmail] = useState('');
+  const [password, setPassword] = useState('');
+  const [confirmPassword, setConfirmPassword] = useState('');
+  const [message, setMessage] = useState('');
+  const [error, setError] = useState('');
+  const [loading, setLoading] = useState(false);
+
+  const handleRequest = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError('');
+    setLoading(true);
+    try {
+      await forgotPassword(email);
+      setMessage('If an account exists for that email, a reset link is on its way.');
+    } catch (e: any) {
+      setError(e.message || 'Failed to send reset email');
+    }
+    setLoading(false);
+  };
+
+  const handleReset = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError('');
+    if (password !== confirmPassword) {
+      setError('Passwords do not match');
+      return;
+    }
+    setLoading(true);
+    try {
+      await resetPassword(token || '', await sha256(password));
+      setMessage('Password reset. Sign in with your new password.');
+    } catch (e: any) {
+      setError(e.message || 'Failed to reset password');
+    }
+    setLoading(false);
+  };
+
+  return (
+    <Box maxW="400px" mx="auto" mt={16} p={8} bg="white" borderRadius="md" boxShadow="sm">
+      <Heading size="lg" mb={4}>Reset Password</Heading>
+      {!message && !token && (
+        <Box as="form" onSubmit={handleRequest}>
+          <VStack spacing={4} align="stretch">
+            <Input placeholder="Email" type="email" value={email} onChange={e => setEmail(e.target.value)} required />
+            <Button type="submit" colorScheme="blue" isLoading={loading}>Send reset link</Button>
+          </VStack>
+        </Box>
+      )}
+      {!message && token && (
+        <Box as="form" onSubmit={handleReset}>
+          <VStack spacing={4} align="stretch">
+            <Input placeholder="New password" type="password" value={password} onChange={e => setPassword(e.target.value)} required />
+            <Input placeholder="Confirm new password" type="password" value={confirmPassword} onChange={e => setConfirmPassword(e.target.value)} required />
+            <Button type="submit" colorScheme="blue" isLoading={loading}>Reset password</Button>
+          </VStack>
+        </Box>
+      )}
+      {message && <Alert status="success"><AlertIcon />{message}</Alert>}
+      {error && <Alert status="error" mt={4}><AlertIcon />{error}</Alert>}
+      <Text mt={4}><Link as={RouterLink} to="/" color="blue.500">Back to sign in</Link></Text>
+    </Box>
+  );
+}
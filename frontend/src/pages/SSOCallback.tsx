@@ -0,0 +1,65 @@
+import React, { useEffect, useState } from 'react';
+import { Link as RouterLink, useNavigate, useSearchParams } from 'react-router-dom';
+import { Box, Heading, Text, Spinner, Alert, AlertIcon, Link, Input, Button, VStack } from '@chakra-ui/react';
+import { useAuth } from '../context/AuthContext';
+
+// Landing page for the identity provider's redirect after single sign-on
+export default function SSOCallback() {
+  const [searchParams] = useSearchParams();
+  const { completeSSOLogin, completeTwoFactorLogin } = useAuth();
+  const navigate = useNavigate();
+  const [error, setError] = useState('');
+  const [challengeToken, setChallengeToken] = useState('');
+  const [code, setCode] = useState('');
+  const [loading, setLoading] = useState(false);
+
+  useEffect(() => {
+    const state = searchParams.get('state') || '';
+    const expectedState = sessionStorage.getItem('sso_state');
+    sessionStorage.removeItem('sso_state');
+
+    if (searchParams.get('error')) {
+      setError(searchParams.get('error_description') || 'Sign-in was cancelled');
+      return;
+    }
+    if (!state || state !== expectedState) {
+      setError('Sign-in could not be verified. Please try again.');
+      return;
+    }
+    completeSSOLogin(searchParams.get('code') || '', state).then(result => {
+      if (result.success) navigate('/dashboard');
+      else if (result.challengeToken) setChallengeToken(result.challengeToken);
+      else setError(result.message || 'Single sign-on failed');
+    });
+    // The authorization code can only be used once
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, []);
+
+  const handleCode = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setLoading(true);
+    setError('');
+    const result = await completeTwoFactorLogin(challengeToken, code.trim());
+    if (result.success) navigate('/dashboard');
+    else setError(result.message || 'Invalid code');
+    setLoading(false);
+  };
+
+  return (
+    <Box maxW="400px" mx="auto" mt={16} p={8} bg="white" borderRadius="md" boxShadow="sm">
+      <Heading size="lg" mb={4}>Signing In</Heading>
+      {!error && !challengeToken && <Spinner />}
+      {challengeToken && (
+        <form onSubmit={handleCode}>
+          <VStack spacing={3} align="stretch">
+            <Text>Enter the code from your authenticator app, or one of your recovery codes.</Text>
+            <Input placeholder="123456" value={code} onChange={e => setCode(e.target.value)} autoComplete="one-time-code" autoFocus />
+            <Button type="submit" colorScheme="blue" isLoading={loading}>Verify</Button>
+          </VStack>
+        </form>
+      )}
+      {error && <Alert status="error" mt={4}><AlertIcon />{error}</Alert>}
+      {error && <Text mt={4}><Link as={RouterLink} to="/" color="blue.500">Back to sign in</Link></Text>}
+    </Box>
+  );
+}